@@ -0,0 +1,352 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+/*
+The approval package holds high-value transfers until an operator approves them.
+
+Thresholds are configured per resource ID for the writer's chain. A fungible transfer above the threshold of its
+resource is not voted on, but added to the store as pending. Operators approve or reject pending transfers with the
+chainbridge CLI. The writer votes on approved transfers, and acknowledges rejected ones without voting.
+
+The store is an append-only log of JSON records, so that the CLI can update it while the relayer is running. The
+writer reads it again whenever it checks for approved transfers.
+*/
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Status is the state of a transfer in the store
+type Status string
+
+const (
+	NotRequired Status = "" // Below the threshold
+	Pending     Status = "pending"
+	Approved    Status = "approved"
+	Rejected    Status = "rejected"
+)
+
+const (
+	opPending = "pending"
+	opApprove = "approve"
+	opReject  = "reject"
+	opDone    = "done"
+)
+
+// LoadThresholds reads the thresholds file at path, a JSON object mapping hex resource IDs to the amount, in the
+// token's base unit, above which transfers require approval.
+func LoadThresholds(path string) (map[msg.ResourceId]*big.Int, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	thresholds, err := parseThresholds(bz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval thresholds %s: %w", path, err)
+	}
+	return thresholds, nil
+}
+
+func parseThresholds(bz []byte) (map[msg.ResourceId]*big.Int, error) {
+	var raw map[string]string
+	err := json.Unmarshal(bz, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	thresholds := make(map[msg.ResourceId]*big.Int)
+	for id, amount := range raw {
+		rId, err := hexutil.Decode(id)
+		if err != nil || len(rId) != 32 {
+			return nil, fmt.Errorf("invalid resource ID %s", id)
+		}
+		threshold, ok := new(big.Int).SetString(amount, 10)
+		if !ok || threshold.Sign() < 0 {
+			return nil, fmt.Errorf("resource %s: invalid threshold %q", id, amount)
+		}
+		thresholds[msg.ResourceIdFromSlice(rId)] = threshold
+	}
+	return thresholds, nil
+}
+
+// record is a single entry in the log, Msg and Amount are only set when a transfer is added
+type record struct {
+	msglog.Record
+	Amount *big.Int  `json:"amount,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Request is a transfer in the store
+type Request struct {
+	Message   msg.Message `json:"-"`
+	Source    msg.ChainId `json:"src"`
+	Dest      msg.ChainId `json:"dst"`
+	Nonce     msg.Nonce   `json:"nonce"`
+	Resource  string      `json:"resourceId"`
+	Amount    *big.Int    `json:"amount"`
+	Status    Status      `json:"status"`
+	Requested time.Time   `json:"requested"`
+	Decided   *time.Time  `json:"decided,omitempty"`
+	Resolved  bool        `json:"resolved"` // Whether the writer has acted on the decision
+}
+
+// Store holds the transfers awaiting or given a decision
+type Store struct {
+	path       string
+	thresholds map[msg.ResourceId]*big.Int
+	requests   map[outbox.Key]*Request
+	lock       sync.Mutex
+}
+
+// FileName returns the name of the approval log for the chain
+func FileName(chain msg.ChainId) string {
+	return fmt.Sprintf("approvals-%d.log", chain)
+}
+
+// Open loads the store of transfers to chain in dir. An empty dir uses the default blockstore location in the home
+// directory. Transfers are only added if thresholds are set.
+func Open(dir string, chain msg.ChainId, thresholds map[msg.ResourceId]*big.Int) (*Store, error) {
+	dir, err := msglog.DataDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{path: filepath.Join(dir, FileName(chain)), thresholds: thresholds}
+	err = s.load()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load replays the log into the set of requests, the lock must be held or the store not yet shared
+func (s *Store) load() error {
+	requests := make(map[outbox.Key]*Request)
+
+	err := msglog.Replay(s.path, func(line []byte) error {
+		var rec record
+		err := json.Unmarshal(line, &rec)
+		if err != nil {
+			return err
+		}
+
+		switch rec.Op {
+		case opPending:
+			requests[rec.Key] = newRequest(rec.Message(), rec.Amount, rec.Time)
+		case opApprove, opReject:
+			if r, ok := requests[rec.Key]; ok && r.Status == Pending {
+				r.decide(rec.Op, rec.Time)
+			}
+		case opDone:
+			if r, ok := requests[rec.Key]; ok {
+				r.Resolved = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.requests = requests
+	return nil
+}
+
+func newRequest(m msg.Message, amount *big.Int, t time.Time) *Request {
+	return &Request{
+		Message:   m,
+		Source:    m.Source,
+		Dest:      m.Destination,
+		Nonce:     m.DepositNonce,
+		Resource:  m.ResourceId.Hex(),
+		Amount:    amount,
+		Status:    Pending,
+		Requested: t,
+	}
+}
+
+func (r *Request) decide(op string, t time.Time) {
+	if op == opApprove {
+		r.Status = Approved
+	} else {
+		r.Status = Rejected
+	}
+	r.Decided = &t
+}
+
+// Check returns the status of the message. A transfer above the threshold of its resource that is not in the
+// store yet is added as pending. The log is read again while the transfer is pending, as it may have been decided by
+// the CLI. Approved and rejected transfers are marked as resolved, as the writer is about to act on the decision.
+// They remain in the store, so a replayed message is not added again.
+func (s *Store) Check(m msg.Message) (Status, error) {
+	key := outbox.KeyOf(m)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if r, ok := s.requests[key]; ok && r.Status == Pending {
+		err := s.load()
+		if err != nil {
+			return Pending, err
+		}
+	}
+
+	if r, ok := s.requests[key]; ok {
+		if r.Status != Pending && !r.Resolved {
+			return r.Status, s.resolve(r)
+		}
+		return r.Status, nil
+	}
+
+	threshold, ok := s.thresholds[m.ResourceId]
+	amount := msglog.AmountOf(m)
+	if !ok || amount == nil || amount.Cmp(threshold) <= 0 {
+		return NotRequired, nil
+	}
+
+	r, err := msglog.NewRecord(opPending, m)
+	if err != nil {
+		return Pending, err
+	}
+	rec := record{Record: r, Amount: amount, Time: time.Now()}
+	err = s.write(rec)
+	if err != nil {
+		return Pending, err
+	}
+	s.requests[key] = newRequest(m, amount, rec.Time)
+	return Pending, nil
+}
+
+// Approve approves the pending transfer with the key
+func (s *Store) Approve(key outbox.Key) error {
+	return s.decide(key, opApprove)
+}
+
+// Reject rejects the pending transfer with the key, it will not be voted on
+func (s *Store) Reject(key outbox.Key) error {
+	return s.decide(key, opReject)
+}
+
+func (s *Store) decide(key outbox.Key, op string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	r, ok := s.requests[key]
+	if !ok {
+		return fmt.Errorf("no transfer %s awaiting approval", key)
+	} else if r.Status != Pending {
+		return fmt.Errorf("transfer %s is already %s", key, r.Status)
+	}
+
+	rec := record{Record: msglog.Record{Op: op, Key: key}, Time: time.Now()}
+	err := s.write(rec)
+	if err != nil {
+		return err
+	}
+	r.decide(op, rec.Time)
+	return nil
+}
+
+// Decided reads the log again and returns the approved and rejected transfers the writer has not acted on
+func (s *Store) Decided() ([]msg.Message, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var decided []msg.Message
+	for _, r := range s.sorted() {
+		if r.Status != Pending && !r.Resolved {
+			decided = append(decided, r.Message)
+		}
+	}
+	return decided, nil
+}
+
+// List reads the log again and returns all requests, ordered by source, destination and nonce
+func (s *Store) List() ([]Request, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	requests := []Request{}
+	for _, r := range s.sorted() {
+		requests = append(requests, *r)
+	}
+	return requests, nil
+}
+
+// resolve records that the writer acted on the decision, the lock must be held
+func (s *Store) resolve(r *Request) error {
+	err := s.write(record{Record: msglog.Record{Op: opDone, Key: outbox.KeyOf(r.Message)}, Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	r.Resolved = true
+	return nil
+}
+
+// sorted returns the requests ordered by key, the lock must be held
+func (s *Store) sorted() []*Request {
+	keys := make([]outbox.Key, 0, len(s.requests))
+	for k := range s.requests {
+		keys = append(keys, k)
+	}
+	msglog.SortKeys(keys)
+
+	requests := make([]*Request, len(keys))
+	for i, k := range keys {
+		requests[i] = s.requests[k]
+	}
+	return requests
+}
+
+// write appends the record to the log, the lock must be held
+func (s *Store) write(rec record) error {
+	return msglog.Append(s.path, rec)
+}
+
+// Handler serves the requests of the stores as JSON, optionally filtered by the status query parameter
+func Handler(stores []*Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := Status(r.URL.Query().Get("status"))
+
+		requests := []Request{}
+		for _, s := range stores {
+			list, err := s.List()
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			for _, req := range list {
+				if status == NotRequired || req.Status == status {
+					requests = append(requests, req)
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(requests)
+	}
+}
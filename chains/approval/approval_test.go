@@ -0,0 +1,172 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package approval
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+func newTestStore(t *testing.T, thresholds map[msg.ResourceId]*big.Int) (*Store, string) {
+	dir, err := ioutil.TempDir(os.TempDir(), "approval")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	s, err := Open(dir, 1, thresholds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, dir
+}
+
+func checkStatus(t *testing.T, s *Store, m msg.Message, expected Status) {
+	status, err := s.Check(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != expected {
+		t.Fatalf("nonce %d: expected status %q, got %q", m.DepositNonce, expected, status)
+	}
+}
+
+func TestStore_ApproveAndReject(t *testing.T) {
+	rId := msg.ResourceId{1}
+	thresholds := map[msg.ResourceId]*big.Int{rId: big.NewInt(100)}
+	s, dir := newTestStore(t, thresholds)
+
+	small := msg.NewFungibleTransfer(0, 1, 1, big.NewInt(100), rId, []byte{0xab})
+	large := msg.NewFungibleTransfer(0, 1, 2, big.NewInt(101), rId, []byte{0xab})
+	rejected := msg.NewFungibleTransfer(0, 1, 3, big.NewInt(500), rId, []byte{0xab})
+	other := msg.NewFungibleTransfer(0, 1, 4, big.NewInt(500), msg.ResourceId{2}, []byte{0xab})
+
+	checkStatus(t, s, small, NotRequired)
+	checkStatus(t, s, other, NotRequired)
+	checkStatus(t, s, large, Pending)
+	checkStatus(t, s, rejected, Pending)
+	checkStatus(t, s, large, Pending)
+
+	// Decisions are taken by the CLI in another process
+	cli, err := Open(dir, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cli.Approve(outbox.KeyOf(large))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cli.Reject(outbox.KeyOf(rejected))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cli.Approve(outbox.KeyOf(rejected)); err == nil {
+		t.Fatal("expected approval of rejected transfer to fail")
+	}
+	if err = cli.Approve(outbox.KeyOf(small)); err == nil {
+		t.Fatal("expected approval of unknown transfer to fail")
+	}
+
+	decided, err := s.Decided()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decided, []msg.Message{large, rejected}) {
+		t.Fatalf("unexpected decided transfers %v", decided)
+	}
+
+	checkStatus(t, s, large, Approved)
+	checkStatus(t, s, rejected, Rejected)
+
+	decided, err = s.Decided()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decided) != 0 {
+		t.Fatalf("expected resolved transfers not to be returned, got %v", decided)
+	}
+
+	// A replayed message keeps its decision after a restart
+	s, err = Open(dir, 1, thresholds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(t, s, large, Approved)
+	checkStatus(t, s, rejected, Rejected)
+}
+
+func TestStore_CheckReloads(t *testing.T) {
+	rId := msg.ResourceId{1}
+	s, dir := newTestStore(t, map[msg.ResourceId]*big.Int{rId: big.NewInt(100)})
+
+	m := msg.NewFungibleTransfer(0, 1, 1, big.NewInt(101), rId, []byte{0xab})
+	checkStatus(t, s, m, Pending)
+
+	cli, err := Open(dir, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cli.Approve(outbox.KeyOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The decision is seen without listing the decided transfers first
+	checkStatus(t, s, m, Approved)
+
+	decided, err := cli.Decided()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decided) != 0 {
+		t.Fatalf("expected transfer to be resolved, got %v", decided)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rId := msg.ResourceId{1}
+	s, _ := newTestStore(t, map[msg.ResourceId]*big.Int{rId: big.NewInt(100)})
+
+	for nonce := msg.Nonce(1); nonce <= 2; nonce++ {
+		checkStatus(t, s, msg.NewFungibleTransfer(0, 1, nonce, big.NewInt(200), rId, []byte{0xab}), Pending)
+	}
+	err := s.Reject(outbox.Key{Source: 0, Destination: 1, Nonce: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler([]*Store{s})(rec, httptest.NewRequest("GET", "/approvals?status=pending", nil))
+
+	var requests []Request
+	err = json.Unmarshal(rec.Body.Bytes(), &requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0].Nonce != 1 || requests[0].Amount.Cmp(big.NewInt(200)) != 0 {
+		t.Fatalf("unexpected response %s", rec.Body.String())
+	}
+}
+
+func TestParseThresholds_Invalid(t *testing.T) {
+	invalid := []string{
+		`{"0x1234": "1"}`,
+		`{"0x000000000000000000000000000000c76ebe4a02bbc34786d860b355f5a5ce00": "-1"}`,
+		`{"0x000000000000000000000000000000c76ebe4a02bbc34786d860b355f5a5ce00": 1}`,
+	}
+	for _, thresholds := range invalid {
+		_, err := parseThresholds([]byte(thresholds))
+		if err == nil {
+			t.Errorf("expected thresholds %s to be rejected", thresholds)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+/*
+The balance package monitors the balance of the relayer account on the writer's chain.
+
+The writer reports the balance of its account periodically. A balance below the warning threshold is logged, a
+balance below the critical threshold also fails the health check. If pausing is enabled, the writer stops voting
+while the balance is critical. The messages received in the meantime are held, and resolved once the account is
+topped up above the critical threshold.
+*/
+package balance
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ChainSafe/log15"
+)
+
+// Level is the state of the balance relative to the thresholds
+type Level int
+
+const (
+	OK Level = iota
+	Warning
+	Critical
+)
+
+func (l Level) String() string {
+	switch l {
+	case Warning:
+		return "warning"
+	case Critical:
+		return "critical"
+	default:
+		return "ok"
+	}
+}
+
+// Errors returned when the relayer account cannot pay for a transaction. ITX and geth report insufficient funds,
+// substrate reports the inability to pay the fees of an extrinsic.
+var insufficientFundsErrors = []string{"insufficient funds", "inability to pay some fees"}
+
+// IsInsufficientFunds returns true if the error reports that the relayer account cannot pay for a transaction
+func IsInsufficientFunds(err error) bool {
+	if err == nil {
+		return false
+	}
+	s := strings.ToLower(err.Error())
+	for _, e := range insufficientFundsErrors {
+		if strings.Contains(s, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Thresholds are the balances, in the base unit of the native token, below which the balance is reported. A nil
+// threshold is disabled.
+type Thresholds struct {
+	Warning  *big.Int
+	Critical *big.Int
+}
+
+// ParseThresholds parses the warning and critical thresholds, an empty string disables the threshold
+func ParseThresholds(warning, critical string) (Thresholds, error) {
+	var t Thresholds
+	var err error
+	if t.Warning, err = parseAmount(warning); err != nil {
+		return t, fmt.Errorf("invalid warning threshold %s", warning)
+	}
+	if t.Critical, err = parseAmount(critical); err != nil {
+		return t, fmt.Errorf("invalid critical threshold %s", critical)
+	}
+	if t.Warning != nil && t.Critical != nil && t.Warning.Cmp(t.Critical) < 0 {
+		return t, fmt.Errorf("warning threshold %s is below critical threshold %s", t.Warning, t.Critical)
+	}
+	return t, nil
+}
+
+func parseAmount(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %s", s)
+	}
+	return amount, nil
+}
+
+// Enabled returns true if any threshold is set
+func (t Thresholds) Enabled() bool {
+	return t.Warning != nil || t.Critical != nil
+}
+
+func (t Thresholds) level(balance *big.Int) Level {
+	if t.Critical != nil && balance.Cmp(t.Critical) < 0 {
+		return Critical
+	}
+	if t.Warning != nil && balance.Cmp(t.Warning) < 0 {
+		return Warning
+	}
+	return OK
+}
+
+// Status is the last balance reported for a chain
+type Status struct {
+	ChainId   msg.ChainId `json:"chainId"`
+	Balance   *big.Int    `json:"balance"`
+	Level     string      `json:"level"`
+	Paused    bool        `json:"paused"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+// Monitor tracks the balance of the relayer account on a chain, and holds messages while voting is paused
+type Monitor struct {
+	chainId    msg.ChainId
+	thresholds Thresholds
+	pause      bool // Pause voting while the balance is critical
+	log        log15.Logger
+	balance    *big.Int
+	level      Level
+	updatedAt  time.Time
+	held       []msg.Message
+	lock       sync.Mutex
+}
+
+// NewMonitor creates a monitor of the relayer balance on the chain. If pause is set, voting is paused while the
+// balance is below the critical threshold.
+func NewMonitor(chainId msg.ChainId, thresholds Thresholds, pause bool, log log15.Logger) *Monitor {
+	return &Monitor{
+		chainId:    chainId,
+		thresholds: thresholds,
+		pause:      pause,
+		log:        log,
+	}
+}
+
+// Update records the balance and logs changes of its level. Returns the messages held while voting was paused if
+// the balance is no longer critical.
+func (m *Monitor) Update(balance *big.Int) []msg.Message {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	level := m.thresholds.level(balance)
+	prev := m.level
+	m.balance = new(big.Int).Set(balance)
+	m.level = level
+	m.updatedAt = time.Now()
+
+	switch {
+	case level == Critical && prev != Critical:
+		if m.pause {
+			m.log.Error("Relayer balance below critical threshold, voting paused until the account is topped up", "balance", balance, "threshold", m.thresholds.Critical)
+		} else {
+			m.log.Error("Relayer balance below critical threshold, top up the account", "balance", balance, "threshold", m.thresholds.Critical)
+		}
+	case level == Warning && prev == OK:
+		m.log.Warn("Relayer balance below warning threshold", "balance", balance, "threshold", m.thresholds.Warning)
+	case level < prev:
+		m.log.Info("Relayer balance restored", "balance", balance, "level", level)
+	}
+
+	if level == Critical || len(m.held) == 0 {
+		return nil
+	}
+	held := m.held
+	m.held = nil
+	m.log.Info("Resuming voting", "held", len(held))
+	return held
+}
+
+// Hold holds the message and returns true if voting is paused, it is returned by Update once the account is
+// topped up
+func (m *Monitor) Hold(message msg.Message) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.paused() {
+		return false
+	}
+	for _, h := range m.held {
+		if h.Source == message.Source && h.DepositNonce == message.DepositNonce {
+			return true
+		}
+	}
+	m.held = append(m.held, message)
+	return true
+}
+
+// Level returns the level of the last reported balance
+func (m *Monitor) Level() Level {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.level
+}
+
+// Paused returns true if voting is paused until the account is topped up
+func (m *Monitor) Paused() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.paused()
+}
+
+func (m *Monitor) paused() bool {
+	return m.pause && m.level == Critical
+}
+
+// Status returns the last reported balance, nil if none was reported yet
+func (m *Monitor) Status() *Status {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.balance == nil {
+		return nil
+	}
+	return &Status{
+		ChainId:   m.chainId,
+		Balance:   new(big.Int).Set(m.balance),
+		Level:     m.level.String(),
+		Paused:    m.paused(),
+		UpdatedAt: m.updatedAt,
+	}
+}
+
+// Monitored is implemented by chains that monitor the balance of the relayer account
+type Monitored interface {
+	BalanceMonitor() *Monitor
+}
+
+type healthResponse struct {
+	Balances []Status `json:"balances"`
+	Error    string   `json:"error"`
+}
+
+// HealthHandler wraps the health check of the chains. It fails if the balance on any chain is below the critical
+// threshold, otherwise the request is passed to next.
+func HealthHandler(monitors []*Monitor, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var critical []Status
+		for _, m := range monitors {
+			if s := m.Status(); s != nil && s.Level == Critical.String() {
+				critical = append(critical, *s)
+			}
+		}
+		if len(critical) == 0 {
+			next(w, r)
+			return
+		}
+
+		var chains []string
+		for _, s := range critical {
+			chains = append(chains, fmt.Sprint(s.ChainId))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(healthResponse{
+			Balances: critical,
+			Error:    fmt.Sprintf("relayer balance below critical threshold on chains %s", strings.Join(chains, ", ")),
+		})
+	}
+}
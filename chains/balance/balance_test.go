@@ -0,0 +1,124 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package balance
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ChainSafe/log15"
+)
+
+func newTestMonitor(t *testing.T, pause bool) *Monitor {
+	thresholds, err := ParseThresholds("1000", "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMonitor(1, thresholds, pause, log15.Root())
+}
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := ParseThresholds("", "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thresholds.Warning != nil || thresholds.Critical.Int64() != 100 || !thresholds.Enabled() {
+		t.Fatalf("unexpected thresholds %+v", thresholds)
+	}
+
+	invalid := [][2]string{{"lots", ""}, {"", "-1"}, {"10", "100"}}
+	for _, tc := range invalid {
+		if _, err := ParseThresholds(tc[0], tc[1]); err == nil {
+			t.Errorf("expected error for thresholds %v", tc)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	mon := newTestMonitor(t, true)
+	m := msg.NewFungibleTransfer(2, 1, 10, big.NewInt(100), msg.ResourceId{1}, nil)
+
+	if mon.Status() != nil || mon.Hold(m) {
+		t.Fatal("expected no status and voting not paused before the first update")
+	}
+
+	mon.Update(big.NewInt(500))
+	if mon.Level() != Warning || mon.Hold(m) {
+		t.Fatalf("expected warning level without pausing, got %s", mon.Level())
+	}
+
+	mon.Update(big.NewInt(50))
+	if mon.Level() != Critical || !mon.Paused() {
+		t.Fatalf("expected voting to be paused at critical level, got %s", mon.Level())
+	}
+	// A message resolved again while paused is only held once
+	if !mon.Hold(m) || !mon.Hold(m) {
+		t.Fatal("expected message to be held")
+	}
+
+	if resumed := mon.Update(big.NewInt(80)); len(resumed) != 0 {
+		t.Fatalf("expected no messages while the balance is critical, got %d", len(resumed))
+	}
+	resumed := mon.Update(big.NewInt(200))
+	if len(resumed) != 1 || resumed[0].DepositNonce != 10 || mon.Paused() {
+		t.Fatalf("expected held message once topped up, got %+v", resumed)
+	}
+	if resumed := mon.Update(big.NewInt(2000)); len(resumed) != 0 || mon.Level() != OK {
+		t.Fatalf("expected ok level and no held messages, got %s and %d", mon.Level(), len(resumed))
+	}
+
+	// Without pausing, a critical balance is only reported
+	mon = newTestMonitor(t, false)
+	mon.Update(big.NewInt(50))
+	if mon.Level() != Critical || mon.Paused() || mon.Hold(m) {
+		t.Fatal("expected voting not to be paused")
+	}
+}
+
+func TestIsInsufficientFunds(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{errors.New("Insufficient funds."), true},
+		{errors.New("insufficient funds for gas * price + value"), true},
+		{errors.New("1010: Invalid Transaction: Inability to pay some fees , e.g. account balance too low"), true},
+		{errors.New("nonce too low"), false},
+		{nil, false},
+	}
+	for _, tc := range testCases {
+		if res := IsInsufficientFunds(tc.err); res != tc.expected {
+			t.Errorf("%v: expected %t, got %t", tc.err, tc.expected, res)
+		}
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	mon := newTestMonitor(t, false)
+	next := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	handler := HealthHandler([]*Monitor{mon}, next)
+
+	testCases := []struct {
+		balance int64
+		code    int
+	}{
+		{2000, http.StatusOK},
+		{500, http.StatusOK},
+		{50, http.StatusInternalServerError},
+	}
+	for _, tc := range testCases {
+		mon.Update(big.NewInt(tc.balance))
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if rec.Code != tc.code {
+			t.Errorf("balance %d: expected status %d, got %d", tc.balance, tc.code, rec.Code)
+		}
+	}
+}
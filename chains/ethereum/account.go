@@ -0,0 +1,72 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+// Time between checks of the relayer account balance and forwarder nonce
+var AccountPollInterval = time.Minute
+
+// reportAccount periodically checks the balance of the relayer account, and reports its nonce in the forwarder
+// contract if one is configured
+func (w *writer) reportAccount() {
+	for {
+		w.checkBalance()
+
+		if w.forwarderClient != nil && w.writerMetrics != nil {
+			nonce, err := w.forwarderClient.GetOnChainNonce()
+			if err != nil {
+				w.log.Warn("Failed to fetch forwarder nonce", "err", err)
+			} else {
+				w.writerMetrics.SetForwarderNonce(nonce)
+			}
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(AccountPollInterval):
+		}
+	}
+}
+
+// checkBalance fetches the balance of the relayer account and checks it against the thresholds. Messages held while
+// voting was paused are resolved once the account is topped up.
+func (w *writer) checkBalance() {
+	balance, err := w.conn.Client().BalanceAt(context.Background(), w.conn.Keypair().CommonAddress(), nil)
+	if err != nil {
+		w.log.Warn("Failed to fetch relayer balance", "err", err)
+		return
+	}
+	if w.writerMetrics != nil {
+		w.writerMetrics.SetBalance(balance)
+	}
+	if w.balanceMonitor == nil {
+		return
+	}
+
+	resumed := w.balanceMonitor.Update(balance)
+	if w.writerMetrics != nil {
+		w.writerMetrics.SetBalanceLevel(w.balanceMonitor.Level(), w.balanceMonitor.Paused())
+	}
+	for _, m := range resumed {
+		chains.TransferLogger(w.log, m).Info("Resolving held message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		w.ResolveMessage(m)
+	}
+}
+
+// insufficientFunds logs that the relayer account cannot pay for a transaction of the message, and checks the
+// balance so voting is paused without waiting for the next check
+func (w *writer) insufficientFunds(m msg.Message) {
+	chains.TransferLogger(w.log, m).Error("Relayer account has insufficient funds, top up the account", "account", w.conn.Keypair().Address(), "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+	if w.balanceMonitor != nil {
+		go w.checkBalance()
+	}
+}
@@ -28,6 +28,13 @@ import (
 	erc20Handler "github.com/ChainSafe/ChainBridge/bindings/ERC20Handler"
 	erc721Handler "github.com/ChainSafe/ChainBridge/bindings/ERC721Handler"
 	"github.com/ChainSafe/ChainBridge/bindings/GenericHandler"
+	"github.com/ChainSafe/ChainBridge/chains/approval"
+	"github.com/ChainSafe/ChainBridge/chains/balance"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/ChainBridge/chains/policy"
+	"github.com/ChainSafe/ChainBridge/chains/ratelimit"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
+	"github.com/ChainSafe/ChainBridge/chains/writermetrics"
 	connection "github.com/ChainSafe/ChainBridge/connections/ethereum"
 	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
 	"github.com/ChainSafe/chainbridge-utils/blockstore"
@@ -39,7 +46,7 @@ import (
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -54,12 +61,15 @@ type Connection interface {
 	CallOpts() *bind.CallOpts
 	LockAndUpdateOpts() error
 	UnlockOpts()
-	Client() *ethclient.Client
+	UnlockOptsAfterSend(err error)
+	Client() connection.Client
 	ItxClient() *rpc.Client
 	ItxSchedule() string
 	EnsureHasBytecode(address common.Address) error
 	LatestBlock() (*big.Int, error)
 	WaitForBlock(block *big.Int, delay *big.Int) error
+	ProcessableBlock(confirmations *big.Int) (*big.Int, error)
+	ReplaceTransaction(tx *types.Transaction) (*types.Transaction, error)
 	Close()
 }
 
@@ -102,7 +112,10 @@ func setupBlockstore(cfg *Config, kp *secp256k1.Keypair) (*blockstore.Blockstore
 	return bs, nil
 }
 
-func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, m *metrics.ChainMetrics) (*Chain, error) {
+// InitializeChain constructs the connection, listener and writer for the chain. If ob is provided, messages are
+// persisted to it by the listener and acknowledged by the writer. If tr is provided, deposits, votes and executions
+// are recorded in it.
+func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, m *metrics.ChainMetrics, ob *outbox.Outbox, tr *transfers.Tracker) (*Chain, error) {
 	cfg, err := parseChainConfig(chainCfg)
 	if err != nil {
 		return nil, err
@@ -122,6 +135,12 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 	stop := make(chan int)
 
 	conn := connection.NewConnection(cfg.endpoint, cfg.itxEndpoint, cfg.itxSchedule, cfg.http, kp, logger, cfg.gasLimit, cfg.maxGasPrice, cfg.gasMultiplier, cfg.egsApiKey, cfg.egsSpeed)
+	conn.SetGasPricer(cfg.gasPricer)
+	conn.SetFinality(cfg.finality)
+	conn.SetReadQuorum(cfg.readQuorum)
+	if m != nil {
+		conn.SetGasPriceMetrics(connection.NewGasPriceMetrics(chainCfg.Name))
+	}
 	err = conn.Connect()
 	if err != nil {
 		return nil, err
@@ -207,10 +226,68 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 
 	listener := NewListener(conn, cfg, logger, bs, stop, sysErr, m)
 	listener.setContracts(bridgeContract, erc20HandlerContract, erc721HandlerContract, genericHandlerContract)
+	listener.setOutbox(ob)
+	listener.setTransfers(tr)
+	blocks, err := openBlockTracker(cfg.blockstorePath, cfg.id)
+	if err != nil {
+		return nil, err
+	}
+	listener.setBlockTracker(blocks)
+	if m != nil {
+		listener.setReorgMetrics(newReorgMetrics(chainCfg.Name))
+	}
+	if cfg.depositPolicy != "" {
+		p, err := policy.Load(cfg.depositPolicy, logger)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			p.EnableMetrics(chainCfg.Name)
+		}
+		listener.setDepositPolicy(p)
+	}
 
 	writer := NewWriter(conn, cfg, logger, stop, sysErr, m)
 	writer.setContract(bridgeContract)
 	writer.setForwarder(forwarderClient)
+	writer.setOutbox(ob)
+	writer.setTransfers(tr)
+	scheduler, err := openExecutionScheduler(cfg.blockstorePath, cfg.id)
+	if err != nil {
+		return nil, err
+	}
+	writer.setScheduler(scheduler)
+	if cfg.rateLimits != "" {
+		limits, err := ratelimit.LoadLimits(cfg.rateLimits)
+		if err != nil {
+			return nil, err
+		}
+		guard, err := ratelimit.Open(cfg.blockstorePath, cfg.id, limits)
+		if err != nil {
+			return nil, err
+		}
+		writer.setRateLimit(guard)
+	}
+	if cfg.approvalThresholds != "" {
+		thresholds, err := approval.LoadThresholds(cfg.approvalThresholds)
+		if err != nil {
+			return nil, err
+		}
+		approvals, err := approval.Open(cfg.blockstorePath, cfg.id, thresholds)
+		if err != nil {
+			return nil, err
+		}
+		writer.setApprovals(approvals)
+	}
+	if cfg.balanceThresholds.Enabled() {
+		writer.setBalanceMonitor(balance.NewMonitor(cfg.id, cfg.balanceThresholds, cfg.pauseOnLowBalance, logger))
+	}
+	if m != nil {
+		writer.setWriterMetrics(writermetrics.New(chainCfg.Name))
+	}
+	if m != nil && cfg.cancelExpired {
+		writer.setProposalMetrics(newProposalMetrics(chainCfg.Name))
+	}
 
 	return &Chain{
 		cfg:      chainCfg,
@@ -253,6 +330,11 @@ func (c *Chain) LatestBlock() metrics.LatestBlock {
 	return c.listener.latestBlock
 }
 
+// BalanceMonitor returns the monitor of the relayer balance, nil if no thresholds are configured
+func (c *Chain) BalanceMonitor() *balance.Monitor {
+	return c.writer.balanceMonitor
+}
+
 // Stop signals to any running routines to exit
 func (c *Chain) Stop() {
 	close(c.stop)
@@ -38,7 +38,7 @@ func TestChain_ListenerShutdownOnFailure(t *testing.T) {
 		},
 	}
 	sysErr := make(chan error)
-	chain, err := InitializeChain(cfg, TestLogger, sysErr, nil)
+	chain, err := InitializeChain(cfg, TestLogger, sysErr, nil, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -97,7 +97,7 @@ func TestChain_WriterShutdownOnFailure(t *testing.T) {
 		},
 	}
 	sysErr := make(chan error)
-	chain, err := InitializeChain(cfg, TestLogger, sysErr, nil)
+	chain, err := InitializeChain(cfg, TestLogger, sysErr, nil, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
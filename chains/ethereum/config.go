@@ -7,7 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
+	"time"
 
+	"github.com/ChainSafe/ChainBridge/chains/balance"
+	connection "github.com/ChainSafe/ChainBridge/connections/ethereum"
 	"github.com/ChainSafe/ChainBridge/connections/ethereum/egs"
 	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
 	"github.com/ChainSafe/chainbridge-utils/core"
@@ -19,6 +23,13 @@ const DefaultGasLimit = 6721975
 const DefaultGasPrice = 20000000000
 const DefaultBlockConfirmations = 10
 const DefaultGasMultiplier = 1
+const DefaultBlockRange = 100
+const DefaultGasPercentile = 50
+const DefaultGasPercentileBlocks = 20
+const DefaultGasEscalationPercent = 15
+const DefaultStuckTxBlocks = 10
+const DefaultReadQuorum = 1
+const DefaultExecutionDelay = 30 * time.Second
 
 // Chain specific options
 var (
@@ -30,21 +41,38 @@ var (
 	GasLimitOpt           = "gasLimit"
 	GasMultiplier         = "gasMultiplier"
 	HttpOpt               = "http"
+	SubscribeOpt          = "subscribe"
 	StartBlockOpt         = "startBlock"
 	BlockConfirmationsOpt = "blockConfirmations"
+	FinalityOpt           = "finality"
+	BlockRangeOpt         = "blockRange"
 	EGSApiKey             = "egsApiKey"
 	EGSSpeed              = "egsSpeed"
 	ItxEndpoint           = "itxEndpoint"
 	ItxSchedule           = "itxSchedule"
 	ForwarderAddress      = "forwarderAddress"
 	ForwarderType         = "forwarderType"
+	GasStrategyOpt        = "gasStrategy"
+	GasPercentileOpt      = "gasPercentile"
+	GasHistoryBlocksOpt   = "gasPercentileBlocks"
+	GasEscalationOpt      = "gasEscalationPercent"
+	StuckTxBlocksOpt      = "stuckTxBlocks"
+	ReadQuorumOpt         = "readQuorum"
+	CancelExpiredOpt      = "cancelExpired"
+	ExecutionDelayOpt     = "executionDelay"
+	DepositPolicyOpt      = "depositPolicy"
+	RateLimitsOpt         = "rateLimits"
+	ApprovalsOpt          = "approvalThresholds"
+	BalanceWarningOpt     = "balanceWarning"
+	BalanceCriticalOpt    = "balanceCritical"
+	PauseOnLowBalanceOpt  = "pauseOnLowBalance"
 )
 
 // Config encapsulates all necessary parameters in ethereum compatible forms
 type Config struct {
 	name                   string            // Human-readable chain name
 	id                     msg.ChainId       // ChainID
-	endpoint               string            // url for rpc endpoint
+	endpoint               string            // url for rpc endpoint, or a comma separated list of urls
 	itxEndpoint            *string           // url for itx rpc endpoint
 	itxSchedule            string            // the gas pricing schedule to be used by itx eg "fast"
 	forwarderAddress       *common.Address   // address of a forwarder to use when submitting transactions
@@ -61,10 +89,23 @@ type Config struct {
 	maxGasPrice            *big.Int
 	gasMultiplier          *big.Float
 	http                   bool // Config for type of connection
+	subscribe              bool // Use websocket subscriptions rather than polling for deposit events
 	startBlock             *big.Int
 	blockConfirmations     *big.Int
-	egsApiKey              string // API key for ethgasstation to query gas prices
-	egsSpeed               string // The speed which a transaction should be processed: average, fast, fastest. Default: fast
+	finality               connection.Finality
+	blockRange             *big.Int // Maximum number of blocks to query for deposit events at once
+	egsApiKey              string   // API key for ethgasstation to query gas prices
+	egsSpeed               string   // The speed which a transaction should be processed: average, fast, fastest. Default: fast
+	gasPricer              connection.GasPricerConfig
+	stuckTxBlocks          uint64        // Number of blocks before a pending transaction is replaced with higher fees, 0 disables replacement
+	readQuorum             int           // Number of endpoints that must agree on block numbers and contract calls
+	cancelExpired          bool          // Cancel expired proposals the relayer voted on
+	executionDelay         time.Duration // Delay before each fallback relayer executes a passed proposal, 0 disables the rotation
+	depositPolicy          string        // Path to the per-resource deposit policy file
+	rateLimits             string        // Path to the per-resource volume caps of transfers to this chain
+	approvalThresholds     string        // Path to the per-resource amounts above which transfers require approval
+	balanceThresholds      balance.Thresholds
+	pauseOnLowBalance      bool // Pause voting while the relayer balance is below the critical threshold
 }
 
 type ForwarderTypeEnum string
@@ -95,8 +136,19 @@ func parseChainConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		http:                   false,
 		startBlock:             big.NewInt(0),
 		blockConfirmations:     big.NewInt(0),
+		finality:               connection.ConfirmationsFinality,
+		blockRange:             big.NewInt(DefaultBlockRange),
 		egsApiKey:              "",
 		egsSpeed:               "",
+		gasPricer: connection.GasPricerConfig{
+			Strategy:          connection.NodeGasStrategy,
+			Percentile:        DefaultGasPercentile,
+			PercentileBlocks:  DefaultGasPercentileBlocks,
+			EscalationPercent: DefaultGasEscalationPercent,
+		},
+		stuckTxBlocks:  DefaultStuckTxBlocks,
+		readQuorum:     DefaultReadQuorum,
+		executionDelay: DefaultExecutionDelay,
 	}
 
 	if contract, ok := chainCfg.Opts[BridgeOpt]; ok && contract != "" {
@@ -162,6 +214,17 @@ func parseChainConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		delete(chainCfg.Opts, HttpOpt)
 	}
 
+	if subscribe, ok := chainCfg.Opts[SubscribeOpt]; ok && subscribe == "true" {
+		if config.http {
+			return nil, fmt.Errorf("%s requires a websocket connection", SubscribeOpt)
+		}
+		config.subscribe = true
+		delete(chainCfg.Opts, SubscribeOpt)
+	} else if ok && subscribe == "false" {
+		config.subscribe = false
+		delete(chainCfg.Opts, SubscribeOpt)
+	}
+
 	if startBlock, ok := chainCfg.Opts[StartBlockOpt]; ok && startBlock != "" {
 		block := big.NewInt(0)
 		_, pass := block.SetString(startBlock, 10)
@@ -187,6 +250,29 @@ func parseChainConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		delete(chainCfg.Opts, BlockConfirmationsOpt)
 	}
 
+	if finality, ok := chainCfg.Opts[FinalityOpt]; ok && finality != "" {
+		switch connection.Finality(finality) {
+		case connection.ConfirmationsFinality, connection.SafeFinality, connection.FinalizedFinality:
+			config.finality = connection.Finality(finality)
+		default:
+			return nil, fmt.Errorf("unknown %s. Must be 'confirmations', 'safe' or 'finalized'", FinalityOpt)
+		}
+	}
+	delete(chainCfg.Opts, FinalityOpt)
+
+	if blockRange, ok := chainCfg.Opts[BlockRangeOpt]; ok && blockRange != "" {
+		val := big.NewInt(DefaultBlockRange)
+		_, pass := val.SetString(blockRange, 10)
+		if pass && val.Sign() == 1 {
+			config.blockRange = val
+			delete(chainCfg.Opts, BlockRangeOpt)
+		} else {
+			return nil, fmt.Errorf("unable to parse %s", BlockRangeOpt)
+		}
+	} else {
+		delete(chainCfg.Opts, BlockRangeOpt)
+	}
+
 	if gsnApiKey, ok := chainCfg.Opts[EGSApiKey]; ok && gsnApiKey != "" {
 		config.egsApiKey = gsnApiKey
 		delete(chainCfg.Opts, EGSApiKey)
@@ -201,6 +287,114 @@ func parseChainConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		delete(chainCfg.Opts, EGSSpeed)
 	}
 
+	if strategy, ok := chainCfg.Opts[GasStrategyOpt]; ok && strategy != "" {
+		switch connection.GasStrategy(strategy) {
+		case connection.NodeGasStrategy, connection.StaticGasStrategy, connection.PercentileGasStrategy, connection.EscalatingGasStrategy:
+			config.gasPricer.Strategy = connection.GasStrategy(strategy)
+		default:
+			return nil, fmt.Errorf("unknown %s. Must be 'node', 'static', 'percentile' or 'escalating'", GasStrategyOpt)
+		}
+	}
+	delete(chainCfg.Opts, GasStrategyOpt)
+
+	if percentile, ok := chainCfg.Opts[GasPercentileOpt]; ok && percentile != "" {
+		val, err := strconv.ParseFloat(percentile, 64)
+		if err != nil || val < 0 || val > 100 {
+			return nil, fmt.Errorf("unable to parse %s", GasPercentileOpt)
+		}
+		config.gasPricer.Percentile = val
+	}
+	delete(chainCfg.Opts, GasPercentileOpt)
+
+	if blocks, ok := chainCfg.Opts[GasHistoryBlocksOpt]; ok && blocks != "" {
+		val, err := strconv.ParseUint(blocks, 10, 64)
+		if err != nil || val == 0 {
+			return nil, fmt.Errorf("unable to parse %s", GasHistoryBlocksOpt)
+		}
+		config.gasPricer.PercentileBlocks = val
+	}
+	delete(chainCfg.Opts, GasHistoryBlocksOpt)
+
+	if escalation, ok := chainCfg.Opts[GasEscalationOpt]; ok && escalation != "" {
+		val, err := strconv.ParseUint(escalation, 10, 64)
+		if err != nil || val == 0 {
+			return nil, fmt.Errorf("unable to parse %s", GasEscalationOpt)
+		}
+		config.gasPricer.EscalationPercent = val
+	}
+	delete(chainCfg.Opts, GasEscalationOpt)
+
+	if stuckTxBlocks, ok := chainCfg.Opts[StuckTxBlocksOpt]; ok && stuckTxBlocks != "" {
+		val, err := strconv.ParseUint(stuckTxBlocks, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s", StuckTxBlocksOpt)
+		}
+		config.stuckTxBlocks = val
+	}
+	delete(chainCfg.Opts, StuckTxBlocksOpt)
+
+	if quorum, ok := chainCfg.Opts[ReadQuorumOpt]; ok && quorum != "" {
+		val, err := strconv.Atoi(quorum)
+		if err != nil || val < 1 {
+			return nil, fmt.Errorf("unable to parse %s", ReadQuorumOpt)
+		}
+		config.readQuorum = val
+	}
+	delete(chainCfg.Opts, ReadQuorumOpt)
+
+	if cancelExpired, ok := chainCfg.Opts[CancelExpiredOpt]; ok && cancelExpired != "" {
+		val, err := strconv.ParseBool(cancelExpired)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s", CancelExpiredOpt)
+		}
+		config.cancelExpired = val
+	}
+	delete(chainCfg.Opts, CancelExpiredOpt)
+
+	if delay, ok := chainCfg.Opts[ExecutionDelayOpt]; ok && delay != "" {
+		val, err := strconv.ParseUint(delay, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s", ExecutionDelayOpt)
+		}
+		config.executionDelay = time.Duration(val) * time.Second
+	}
+	delete(chainCfg.Opts, ExecutionDelayOpt)
+
+	if path, ok := chainCfg.Opts[DepositPolicyOpt]; ok && path != "" {
+		config.depositPolicy = path
+	}
+	delete(chainCfg.Opts, DepositPolicyOpt)
+
+	if path, ok := chainCfg.Opts[RateLimitsOpt]; ok && path != "" {
+		config.rateLimits = path
+	}
+	delete(chainCfg.Opts, RateLimitsOpt)
+
+	if path, ok := chainCfg.Opts[ApprovalsOpt]; ok && path != "" {
+		config.approvalThresholds = path
+	}
+	delete(chainCfg.Opts, ApprovalsOpt)
+
+	thresholds, err := balance.ParseThresholds(chainCfg.Opts[BalanceWarningOpt], chainCfg.Opts[BalanceCriticalOpt])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse balance thresholds: %w", err)
+	}
+	config.balanceThresholds = thresholds
+	delete(chainCfg.Opts, BalanceWarningOpt)
+	delete(chainCfg.Opts, BalanceCriticalOpt)
+
+	if pause, ok := chainCfg.Opts[PauseOnLowBalanceOpt]; ok && pause != "" {
+		val, err := strconv.ParseBool(pause)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s", PauseOnLowBalanceOpt)
+		}
+		if val && thresholds.Critical == nil {
+			return nil, fmt.Errorf("%s requires %s", PauseOnLowBalanceOpt, BalanceCriticalOpt)
+		}
+		config.pauseOnLowBalance = val
+	}
+	delete(chainCfg.Opts, PauseOnLowBalanceOpt)
+
 	if itxConfig, ok := chainCfg.Opts[ItxEndpoint]; ok && itxConfig != "" {
 		config.itxEndpoint = &itxConfig
 	}
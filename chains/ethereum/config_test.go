@@ -7,11 +7,21 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/ChainSafe/ChainBridge/chains/balance"
+	connection "github.com/ChainSafe/ChainBridge/connections/ethereum"
 	"github.com/ChainSafe/chainbridge-utils/core"
 	"github.com/ethereum/go-ethereum/common"
 )
 
+var defaultGasPricer = connection.GasPricerConfig{
+	Strategy:          connection.NodeGasStrategy,
+	Percentile:        DefaultGasPercentile,
+	PercentileBlocks:  DefaultGasPercentileBlocks,
+	EscalationPercent: DefaultGasEscalationPercent,
+}
+
 //TestParseChainConfig tests parseChainConfig with all handlerContracts provided
 func TestParseChainConfig(t *testing.T) {
 	var testItxEndpoint = "http://test-addr:6768"
@@ -25,22 +35,34 @@ func TestParseChainConfig(t *testing.T) {
 		KeystorePath: "./keys",
 		Insecure:     false,
 		Opts: map[string]string{
-			"bridge":             "0x1234",
-			"erc20Handler":       "0x1234",
-			"erc721Handler":      "0x1234",
-			"genericHandler":     "0x1234",
-			"gasLimit":           "10",
-			"gasMultiplier":      "1",
-			"maxGasPrice":        "20",
-			"http":               "true",
-			"startBlock":         "10",
-			"blockConfirmations": "50",
-			"egsApiKey":          "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx", // fake key
-			"egsSpeed":           "fast",
-			"itxEndpoint":        testItxEndpoint,
-			"itxSchedule":        "high-max",
-			"forwarderAddress":   testForwarderAddress.String(),
-			"forwarderType":      "gnosis",
+			"bridge":               "0x1234",
+			"erc20Handler":         "0x1234",
+			"erc721Handler":        "0x1234",
+			"genericHandler":       "0x1234",
+			"gasLimit":             "10",
+			"gasMultiplier":        "1",
+			"maxGasPrice":          "20",
+			"http":                 "true",
+			"startBlock":           "10",
+			"blockConfirmations":   "50",
+			"finality":             "finalized",
+			"blockRange":           "20",
+			"gasStrategy":          "percentile",
+			"gasPercentile":        "60",
+			"gasPercentileBlocks":  "10",
+			"gasEscalationPercent": "20",
+			"stuckTxBlocks":        "5",
+			"readQuorum":           "2",
+			"executionDelay":       "10",
+			"egsApiKey":            "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx", // fake key
+			"egsSpeed":             "fast",
+			"itxEndpoint":          testItxEndpoint,
+			"itxSchedule":          "high-max",
+			"forwarderAddress":     testForwarderAddress.String(),
+			"forwarderType":        "gnosis",
+			"balanceWarning":       "1000",
+			"balanceCritical":      "100",
+			"pauseOnLowBalance":    "true",
 		},
 	}
 
@@ -66,12 +88,28 @@ func TestParseChainConfig(t *testing.T) {
 		http:                   true,
 		startBlock:             big.NewInt(10),
 		blockConfirmations:     big.NewInt(50),
-		egsApiKey:              "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
-		egsSpeed:               "fast",
-		itxEndpoint:            &testItxEndpoint,
-		itxSchedule:            "high-max",
-		forwarderAddress:       &testForwarderAddress,
-		forwarderType:          "gnosis",
+		finality:               connection.FinalizedFinality,
+		blockRange:             big.NewInt(20),
+		gasPricer: connection.GasPricerConfig{
+			Strategy:          connection.PercentileGasStrategy,
+			Percentile:        60,
+			PercentileBlocks:  10,
+			EscalationPercent: 20,
+		},
+		stuckTxBlocks:    5,
+		readQuorum:       2,
+		executionDelay:   10 * time.Second,
+		egsApiKey:        "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
+		egsSpeed:         "fast",
+		itxEndpoint:      &testItxEndpoint,
+		itxSchedule:      "high-max",
+		forwarderAddress: &testForwarderAddress,
+		forwarderType:    "gnosis",
+		balanceThresholds: balance.Thresholds{
+			Warning:  big.NewInt(1000),
+			Critical: big.NewInt(100),
+		},
+		pauseOnLowBalance: true,
 	}
 
 	if !reflect.DeepEqual(&expected, out) {
@@ -124,6 +162,12 @@ func TestParseChainConfigWithNoBlockConfirmations(t *testing.T) {
 		http:                   true,
 		startBlock:             big.NewInt(10),
 		blockConfirmations:     big.NewInt(DefaultBlockConfirmations),
+		finality:               connection.ConfirmationsFinality,
+		blockRange:             big.NewInt(DefaultBlockRange),
+		gasPricer:              defaultGasPricer,
+		stuckTxBlocks:          DefaultStuckTxBlocks,
+		readQuorum:             DefaultReadQuorum,
+		executionDelay:         DefaultExecutionDelay,
 		egsApiKey:              "",
 		egsSpeed:               "fast",
 		itxEndpoint:            nil,
@@ -178,6 +222,12 @@ func TestChainConfigOneContract(t *testing.T) {
 		http:                 true,
 		startBlock:           big.NewInt(10),
 		blockConfirmations:   big.NewInt(DefaultBlockConfirmations),
+		finality:             connection.ConfirmationsFinality,
+		blockRange:           big.NewInt(DefaultBlockRange),
+		gasPricer:            defaultGasPricer,
+		stuckTxBlocks:        DefaultStuckTxBlocks,
+		readQuorum:           DefaultReadQuorum,
+		executionDelay:       DefaultExecutionDelay,
 		egsApiKey:            "",
 		egsSpeed:             "fast",
 		itxEndpoint:          nil,
@@ -251,6 +301,140 @@ func TestExtraOpts(t *testing.T) {
 	}
 }
 
+func TestInvalidGasStrategy(t *testing.T) {
+	input := core.ChainConfig{
+		Name:         "chain",
+		Id:           1,
+		Endpoint:     "endpoint",
+		From:         "0x0",
+		KeystorePath: "./keys",
+		Insecure:     false,
+		Opts: map[string]string{
+			"bridge":      "0x1234",
+			"gasStrategy": "cheapest",
+		},
+	}
+
+	_, err := parseChainConfig(&input)
+
+	if err == nil {
+		t.Error("Config should not accept unknown gas strategy.")
+	}
+}
+
+func TestInvalidFinality(t *testing.T) {
+	input := core.ChainConfig{
+		Name:         "chain",
+		Id:           1,
+		Endpoint:     "endpoint",
+		From:         "0x0",
+		KeystorePath: "./keys",
+		Insecure:     false,
+		Opts: map[string]string{
+			"bridge":   "0x1234",
+			"finality": "latest",
+		},
+	}
+
+	_, err := parseChainConfig(&input)
+
+	if err == nil {
+		t.Error("Config should not accept unknown finality.")
+	}
+}
+
+func TestInvalidCancelExpired(t *testing.T) {
+	input := core.ChainConfig{
+		Name:         "chain",
+		Id:           1,
+		Endpoint:     "endpoint",
+		From:         "0x0",
+		KeystorePath: "./keys",
+		Insecure:     false,
+		Opts: map[string]string{
+			"bridge":        "0x1234",
+			"cancelExpired": "yes please",
+		},
+	}
+
+	_, err := parseChainConfig(&input)
+
+	if err == nil {
+		t.Error("Config should not accept invalid cancelExpired.")
+	}
+}
+
+func TestInvalidExecutionDelay(t *testing.T) {
+	input := core.ChainConfig{
+		Name:         "chain",
+		Id:           1,
+		Endpoint:     "endpoint",
+		From:         "0x0",
+		KeystorePath: "./keys",
+		Insecure:     false,
+		Opts: map[string]string{
+			"bridge":         "0x1234",
+			"executionDelay": "-1",
+		},
+	}
+
+	_, err := parseChainConfig(&input)
+
+	if err == nil {
+		t.Error("Config should not accept invalid executionDelay.")
+	}
+}
+
+func TestInvalidBalanceThresholds(t *testing.T) {
+	testCases := []map[string]string{
+		{"balanceWarning": "lots"},
+		{"balanceCritical": "-1"},
+		{"balanceWarning": "10", "balanceCritical": "100"},
+		{"pauseOnLowBalance": "true"},
+		{"balanceCritical": "100", "pauseOnLowBalance": "maybe"},
+	}
+
+	for _, opts := range testCases {
+		opts["bridge"] = "0x1234"
+		input := core.ChainConfig{
+			Name:         "chain",
+			Id:           1,
+			Endpoint:     "endpoint",
+			From:         "0x0",
+			KeystorePath: "./keys",
+			Opts:         opts,
+		}
+
+		_, err := parseChainConfig(&input)
+
+		if err == nil {
+			t.Errorf("Config should not accept balance options %v.", opts)
+		}
+	}
+}
+
+func TestSubscribeRequiresWebsocket(t *testing.T) {
+	input := core.ChainConfig{
+		Name:         "chain",
+		Id:           1,
+		Endpoint:     "endpoint",
+		From:         "0x0",
+		KeystorePath: "./keys",
+		Insecure:     false,
+		Opts: map[string]string{
+			"bridge":    "0x1234",
+			"http":      "true",
+			"subscribe": "true",
+		},
+	}
+
+	_, err := parseChainConfig(&input)
+
+	if err == nil {
+		t.Error("Config should not accept subscriptions over http.")
+	}
+}
+
 func TestEthGasStationDefaultSpeed(t *testing.T) {
 	input := core.ChainConfig{
 		Name:         "chain",
@@ -296,6 +480,12 @@ func TestEthGasStationDefaultSpeed(t *testing.T) {
 		http:                   true,
 		startBlock:             big.NewInt(10),
 		blockConfirmations:     big.NewInt(DefaultBlockConfirmations),
+		finality:               connection.ConfirmationsFinality,
+		blockRange:             big.NewInt(DefaultBlockRange),
+		gasPricer:              defaultGasPricer,
+		stuckTxBlocks:          DefaultStuckTxBlocks,
+		readQuorum:             DefaultReadQuorum,
+		executionDelay:         DefaultExecutionDelay,
 		egsApiKey:              "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
 		egsSpeed:               "fast",
 		itxEndpoint:            nil,
@@ -354,6 +544,12 @@ func TestEthGasStationCustomSpeed(t *testing.T) {
 		http:                   true,
 		startBlock:             big.NewInt(10),
 		blockConfirmations:     big.NewInt(DefaultBlockConfirmations),
+		finality:               connection.ConfirmationsFinality,
+		blockRange:             big.NewInt(DefaultBlockRange),
+		gasPricer:              defaultGasPricer,
+		stuckTxBlocks:          DefaultStuckTxBlocks,
+		readQuorum:             DefaultReadQuorum,
+		executionDelay:         DefaultExecutionDelay,
 		egsApiKey:              "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
 		egsSpeed:               "average",
 		itxEndpoint:            nil,
@@ -412,6 +608,12 @@ func TestEthGasStationInvalidSpeed(t *testing.T) {
 		http:                   true,
 		startBlock:             big.NewInt(10),
 		blockConfirmations:     big.NewInt(DefaultBlockConfirmations),
+		finality:               connection.ConfirmationsFinality,
+		blockRange:             big.NewInt(DefaultBlockRange),
+		gasPricer:              defaultGasPricer,
+		stuckTxBlocks:          DefaultStuckTxBlocks,
+		readQuorum:             DefaultReadQuorum,
+		executionDelay:         DefaultExecutionDelay,
 		egsApiKey:              "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
 		egsSpeed:               "fast",
 		itxEndpoint:            nil,
@@ -4,16 +4,18 @@
 package ethereum
 
 import (
+	"github.com/ChainSafe/ChainBridge/chains"
 	"github.com/ChainSafe/chainbridge-utils/msg"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 )
 
 func (l *listener) handleErc20DepositedEvent(destId msg.ChainId, nonce msg.Nonce) (msg.Message, error) {
-	l.log.Info("Handling fungible deposit event", "dest", destId, "nonce", nonce)
+	log := l.log.New(chains.TransferKey, chains.TransferId(l.cfg.id, destId, nonce))
+	log.Info("Handling fungible deposit event", "src", l.cfg.id, "dst", destId, "nonce", nonce)
 
 	record, err := l.erc20HandlerContract.GetDepositRecord(&bind.CallOpts{From: l.conn.Keypair().CommonAddress()}, uint64(nonce), uint8(destId))
 	if err != nil {
-		l.log.Error("Error Unpacking ERC20 Deposit Record", "err", err)
+		log.Error("Error Unpacking ERC20 Deposit Record", "err", err)
 		return msg.Message{}, err
 	}
 
@@ -28,11 +30,12 @@ func (l *listener) handleErc20DepositedEvent(destId msg.ChainId, nonce msg.Nonce
 }
 
 func (l *listener) handleErc721DepositedEvent(destId msg.ChainId, nonce msg.Nonce) (msg.Message, error) {
-	l.log.Info("Handling nonfungible deposit event")
+	log := l.log.New(chains.TransferKey, chains.TransferId(l.cfg.id, destId, nonce))
+	log.Info("Handling nonfungible deposit event", "src", l.cfg.id, "dst", destId, "nonce", nonce)
 
 	record, err := l.erc721HandlerContract.GetDepositRecord(&bind.CallOpts{From: l.conn.Keypair().CommonAddress()}, uint64(nonce), uint8(destId))
 	if err != nil {
-		l.log.Error("Error Unpacking ERC721 Deposit Record", "err", err)
+		log.Error("Error Unpacking ERC721 Deposit Record", "err", err)
 		return msg.Message{}, err
 	}
 
@@ -48,11 +51,12 @@ func (l *listener) handleErc721DepositedEvent(destId msg.ChainId, nonce msg.Nonc
 }
 
 func (l *listener) handleGenericDepositedEvent(destId msg.ChainId, nonce msg.Nonce) (msg.Message, error) {
-	l.log.Info("Handling generic deposit event")
+	log := l.log.New(chains.TransferKey, chains.TransferId(l.cfg.id, destId, nonce))
+	log.Info("Handling generic deposit event", "src", l.cfg.id, "dst", destId, "nonce", nonce)
 
 	record, err := l.genericHandlerContract.GetDepositRecord(&bind.CallOpts{From: l.conn.Keypair().CommonAddress()}, uint64(nonce), uint8(destId))
 	if err != nil {
-		l.log.Error("Error Unpacking Generic Deposit Record", "err", err)
+		log.Error("Error Unpacking Generic Deposit Record", "err", err)
 		return msg.Message{}, nil
 	}
 
@@ -8,14 +8,14 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 // Keeps track of forwarder info include the state of the current
 // nonces
 type ForwarderClientBase struct {
-	client             *ethclient.Client
+	client             bind.ContractCaller
 	forwarderAddress   common.Address
 	forwarderAbi       abi.ABI
 	forwarderNonceLock sync.Mutex
@@ -17,7 +17,6 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	signer "github.com/ethereum/go-ethereum/signer/core"
 )
 
@@ -42,7 +41,7 @@ func init() {
 }
 
 func NewGnosisForwarderClient(
-	client *ethclient.Client,
+	client bind.ContractCaller,
 	forwarderAddress common.Address,
 	fromAddress common.Address,
 	chainId *big.Int,
@@ -0,0 +1,159 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains"
+	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// relayerCache holds the relayer set as of a block
+type relayerCache struct {
+	block    *big.Int
+	relayers []ethcommon.Address
+	lock     sync.Mutex
+}
+
+// executionRank returns the position of self in the execution rotation for the deposit. The relayer at rank 0
+// executes first, the others are fallbacks in order of rank. Relayers missing from the set are ranked last.
+func executionRank(relayers []ethcommon.Address, self ethcommon.Address, source msg.ChainId, nonce msg.Nonce) int {
+	n := uint64(len(relayers))
+	if n == 0 {
+		return 0
+	}
+
+	leader := (uint64(source) + uint64(nonce)) % n
+	for i, relayer := range relayers {
+		if relayer == self {
+			return int((uint64(i) + n - leader) % n)
+		}
+	}
+	return int(n)
+}
+
+// relayerSet returns the relayers with the relayer role, in the order they are stored by the bridge. The set is
+// cached, and only fetched again if a role was granted or revoked since the block it was fetched at.
+func (w *writer) relayerSet() ([]ethcommon.Address, error) {
+	c := &w.relayers
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	latest, err := w.conn.LatestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.block != nil {
+		if latest.Cmp(c.block) <= 0 {
+			return c.relayers, nil
+		}
+		changed, err := w.rolesChanged(new(big.Int).Add(c.block, big.NewInt(1)), latest)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			c.block = latest
+			return c.relayers, nil
+		}
+	}
+
+	relayers, err := w.fetchRelayerSet(latest)
+	if err != nil {
+		return nil, err
+	}
+	c.block = latest
+	c.relayers = relayers
+	return relayers, nil
+}
+
+// rolesChanged returns true if a role of the bridge may have been granted or revoked from startBlock to endBlock
+// (inclusive). Ranges longer than the block range are not queried and reported as changed.
+func (w *writer) rolesChanged(startBlock, endBlock *big.Int) (bool, error) {
+	if new(big.Int).Sub(endBlock, startBlock).Cmp(w.cfg.blockRange) >= 0 {
+		return true, nil
+	}
+
+	query := eth.FilterQuery{
+		FromBlock: startBlock,
+		ToBlock:   endBlock,
+		Addresses: []ethcommon.Address{w.cfg.bridgeContract},
+		Topics:    [][]ethcommon.Hash{{utils.RoleGranted.GetTopic(), utils.RoleRevoked.GetTopic()}},
+	}
+	logs, err := w.conn.Client().FilterLogs(context.Background(), query)
+	if err != nil {
+		return false, err
+	}
+	return len(logs) > 0, nil
+}
+
+// fetchRelayerSet reads the relayers with the relayer role from the bridge at the block
+func (w *writer) fetchRelayerSet(block *big.Int) ([]ethcommon.Address, error) {
+	opts := &bind.CallOpts{From: w.conn.CallOpts().From, BlockNumber: block}
+	role, err := w.bridgeContract.RELAYERROLE(opts)
+	if err != nil {
+		return nil, err
+	}
+	count, err := w.bridgeContract.GetRoleMemberCount(opts, role)
+	if err != nil {
+		return nil, err
+	}
+
+	var relayers []ethcommon.Address
+	for i := int64(0); i < count.Int64(); i++ {
+		relayer, err := w.bridgeContract.GetRoleMember(opts, role, big.NewInt(i))
+		if err != nil {
+			return nil, err
+		}
+		relayers = append(relayers, relayer)
+	}
+	return relayers, nil
+}
+
+// executeInTurn executes the passed proposal once it is this relayer's turn. The relayer chosen by the rotation
+// executes immediately, every other relayer waits executionDelay for each relayer ahead of it, and skips the
+// execution if it was completed in the meantime. done is called with whether the proposal was finalized, unless the
+// writer is stopped.
+func (w *writer) executeInTurn(m msg.Message, data []byte, dataHash [32]byte, done func(finalized bool)) {
+	log := chains.TransferLogger(w.log, m)
+
+	if w.cfg.executionDelay > 0 {
+		rank := 0
+		relayers, err := w.relayerSet()
+		if err != nil {
+			// Executing late is preferable to not executing
+			log.Warn("Unable to fetch relayer set, executing without rotation", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		} else {
+			rank = executionRank(relayers, w.conn.Opts().From, m.Source, m.DepositNonce)
+		}
+
+		if rank > 0 {
+			delay := time.Duration(rank) * w.cfg.executionDelay
+			log.Info("Waiting for other relayers to execute proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "rank", rank, "delay", delay)
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(delay):
+			}
+
+			if w.proposalIsFinalized(m.Source, m.DepositNonce, dataHash) {
+				log.Info("Proposal executed by another relayer", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+				w.trackOutcome(m, dataHash)
+				done(true)
+				return
+			}
+			log.Info("Executing proposal as fallback", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "rank", rank)
+		}
+	}
+
+	w.executeProposal(m, data, dataHash, done)
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+func TestExecutionRank(t *testing.T) {
+	relayers := []ethcommon.Address{{1}, {2}, {3}}
+
+	// The leader rotates with the nonce, every relayer is ranked exactly once per deposit
+	for nonce := msg.Nonce(0); nonce < 6; nonce++ {
+		seen := make(map[int]bool)
+		for i, relayer := range relayers {
+			rank := executionRank(relayers, relayer, msg.ChainId(1), nonce)
+			if rank < 0 || rank >= len(relayers) || seen[rank] {
+				t.Fatalf("nonce %d: unexpected rank %d for relayer %d", nonce, rank, i)
+			}
+			seen[rank] = true
+		}
+
+		leader := relayers[(1+int(nonce))%len(relayers)]
+		if rank := executionRank(relayers, leader, msg.ChainId(1), nonce); rank != 0 {
+			t.Fatalf("nonce %d: expected leader rank 0, got %d", nonce, rank)
+		}
+	}
+
+	// The relayer after the leader is the first fallback
+	if rank := executionRank(relayers, relayers[2], msg.ChainId(0), msg.Nonce(1)); rank != 1 {
+		t.Fatalf("expected rank 1, got %d", rank)
+	}
+
+	if rank := executionRank(relayers, ethcommon.Address{4}, msg.ChainId(0), msg.Nonce(1)); rank != len(relayers) {
+		t.Fatalf("expected unknown relayer to be ranked last, got %d", rank)
+	}
+	if rank := executionRank(nil, relayers[0], msg.ChainId(0), msg.Nonce(1)); rank != 0 {
+		t.Fatalf("expected rank 0 for empty relayer set, got %d", rank)
+	}
+}
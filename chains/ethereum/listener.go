@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/ChainSafe/ChainBridge/bindings/Bridge"
@@ -15,6 +16,10 @@ import (
 	"github.com/ChainSafe/ChainBridge/bindings/ERC721Handler"
 	"github.com/ChainSafe/ChainBridge/bindings/GenericHandler"
 	"github.com/ChainSafe/ChainBridge/chains"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/ChainBridge/chains/policy"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
+	connection "github.com/ChainSafe/ChainBridge/connections/ethereum"
 	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
 	"github.com/ChainSafe/chainbridge-utils/blockstore"
 	metrics "github.com/ChainSafe/chainbridge-utils/metrics/types"
@@ -23,16 +28,22 @@ import (
 	eth "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 var BlockRetryInterval = time.Second * 5
 var BlockRetryLimit = 5
 var ErrFatalPolling = errors.New("listener block polling failed")
+var ErrReorgDetected = errors.New("reorg of processed blocks detected")
+
+// Number of consecutive successful log queries after which a reduced block range is doubled again
+var BlockRangeGrowthInterval = 10
 
 type listener struct {
 	cfg                    Config
 	conn                   Connection
 	router                 chains.Router
+	outbox                 *outbox.Outbox
 	bridgeContract         *Bridge.Bridge // instance of bound bridge contract
 	erc20HandlerContract   *ERC20Handler.ERC20Handler
 	erc721HandlerContract  *ERC721Handler.ERC721Handler
@@ -44,6 +55,11 @@ type listener struct {
 	latestBlock            metrics.LatestBlock
 	metrics                *metrics.ChainMetrics
 	blockConfirmations     *big.Int
+	blocks                 *blockTracker
+	reorgMetrics           *reorgMetrics
+	policy                 *policy.Policy // Deposits violating the policy are skipped, may be nil
+	transfers              *transfers.Tracker
+	queryRange             *queryRange
 }
 
 // NewListener creates and returns a listener
@@ -58,6 +74,8 @@ func NewListener(conn Connection, cfg *Config, log log15.Logger, bs blockstore.B
 		latestBlock:        metrics.LatestBlock{LastUpdated: time.Now()},
 		metrics:            m,
 		blockConfirmations: cfg.blockConfirmations,
+		blocks:             newBlockTracker(),
+		queryRange:         newQueryRange(cfg.blockRange),
 	}
 }
 
@@ -74,12 +92,60 @@ func (l *listener) setRouter(r chains.Router) {
 	l.router = r
 }
 
+// setOutbox sets the outbox messages are persisted to before being routed
+func (l *listener) setOutbox(ob *outbox.Outbox) {
+	l.outbox = ob
+}
+
+// setBlockTracker sets the tracker of processed blocks used to detect reorgs
+func (l *listener) setBlockTracker(t *blockTracker) {
+	l.blocks = t
+}
+
+// setReorgMetrics sets the metrics reporting detected reorgs
+func (l *listener) setReorgMetrics(m *reorgMetrics) {
+	l.reorgMetrics = m
+}
+
+// setDepositPolicy sets the policy deposits must conform to before they are relayed
+func (l *listener) setDepositPolicy(p *policy.Policy) {
+	l.policy = p
+}
+
+// setTransfers sets the index deposits are recorded in
+func (l *listener) setTransfers(t *transfers.Tracker) {
+	l.transfers = t
+}
+
+// track applies the update to the transfer of the message in the index
+func (l *listener) track(m msg.Message, update transfers.Update) {
+	if l.transfers == nil {
+		return
+	}
+
+	_, err := l.transfers.Update(m, update)
+	if err != nil {
+		chains.TransferLogger(l.log, m).Warn("Failed to update transfer index", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+	}
+}
+
 // start registers all subscriptions provided by the config
 func (l *listener) start() error {
 	l.log.Debug("Starting listener...")
 
+	l.replayOutbox()
+
+	if l.policy != nil {
+		go l.policy.Watch(l.stop)
+	}
+
 	go func() {
-		err := l.pollBlocks()
+		var err error
+		if l.cfg.subscribe {
+			err = l.subscribeBlocks(l.cfg.startBlock)
+		} else {
+			err = l.pollBlocks(l.cfg.startBlock)
+		}
 		if err != nil {
 			l.log.Error("Polling blocks failed", "err", err)
 		}
@@ -89,23 +155,35 @@ func (l *listener) start() error {
 }
 
 // pollBlocks will poll for the latest block and proceed to parse the associated events as it sees new blocks.
-// Polling begins at the block defined in `l.cfg.startBlock`. Failed attempts to fetch the latest block or parse
-// a block will be retried up to BlockRetryLimit times before continuing to the next block.
-func (l *listener) pollBlocks() error {
-	var currentBlock = l.cfg.startBlock
-	l.log.Info("Polling Blocks...", "block", currentBlock)
+// Polling begins at startBlock. Blocks are queried in ranges of at most
+// `l.cfg.blockRange` blocks, bounded by the latest block that has reached `l.blockConfirmations`.
+// If the provider rejects a range as too large, the range is halved and the query is retried. The range grows back
+// once queries succeed again.
+// Failed attempts to fetch the latest block or parse a range will be retried up to BlockRetryLimit times
+// before reporting a fatal error.
+func (l *listener) pollBlocks(startBlock *big.Int) error {
+	_, err := l.poll(startBlock, time.Time{})
+	return err
+}
+
+// poll processes blocks from startBlock as described for pollBlocks. If until is set, it returns the first
+// unprocessed block once that time has passed and all processable blocks have been processed. Returns a nil
+// block if a fatal error was reported.
+func (l *listener) poll(startBlock *big.Int, until time.Time) (*big.Int, error) {
+	var currentBlock = new(big.Int).Set(startBlock)
+	l.log.Info("Polling Blocks...", "block", currentBlock, "range", l.queryRange.get())
 
 	var retry = BlockRetryLimit
 	for {
 		select {
 		case <-l.stop:
-			return errors.New("polling terminated")
+			return currentBlock, errors.New("polling terminated")
 		default:
 			// No more retries, goto next block
 			if retry == 0 {
 				l.log.Error("Polling failed, retries exceeded")
 				l.sysErr <- ErrFatalPolling
-				return nil
+				return nil, nil
 			}
 
 			latestBlock, err := l.conn.LatestBlock()
@@ -120,46 +198,263 @@ func (l *listener) pollBlocks() error {
 				l.metrics.LatestKnownBlock.Set(float64(latestBlock.Int64()))
 			}
 
-			// Sleep if the difference is less than BlockDelay; (latest - current) < BlockDelay
-			if big.NewInt(0).Sub(latestBlock, currentBlock).Cmp(l.blockConfirmations) == -1 {
-				l.log.Debug("Block not ready, will retry", "target", currentBlock, "latest", latestBlock)
+			finalBlock, err := l.processableBlock(latestBlock)
+			if err != nil {
+				l.log.Error("Unable to get processable block", "block", currentBlock, "finality", l.cfg.finality, "err", err)
+				retry--
 				time.Sleep(BlockRetryInterval)
 				continue
 			}
 
-			// Parse out events
-			err = l.getDepositEventsForBlock(currentBlock)
-			if err != nil {
-				l.log.Error("Failed to get events for block", "block", currentBlock, "err", err)
-				retry--
+			// Sleep if the current block is not final yet
+			if finalBlock.Cmp(currentBlock) == -1 {
+				if !until.IsZero() && time.Now().After(until) {
+					return currentBlock, nil
+				}
+				l.log.Debug("Block not ready, will retry", "target", currentBlock, "latest", latestBlock, "final", finalBlock)
+				time.Sleep(BlockRetryInterval)
 				continue
 			}
 
-			// Write to block store. Not a critical operation, no need to retry
-			err = l.blockstore.StoreBlock(currentBlock)
+			// Verify the blocks processed so far are still canonical
+			resume, err := l.checkReorg()
 			if err != nil {
-				l.log.Error("Failed to write latest block to blockstore", "block", currentBlock, "err", err)
+				l.log.Error("Unable to verify processed blocks", "block", currentBlock, "err", err)
+				retry--
+				time.Sleep(BlockRetryInterval)
+				continue
+			}
+			if resume != nil {
+				currentBlock = resume
+				continue
 			}
 
-			if l.metrics != nil {
-				l.metrics.BlocksProcessed.Inc()
-				l.metrics.LatestProcessedBlock.Set(float64(latestBlock.Int64()))
+			endBlock := rangeEnd(currentBlock, finalBlock, big.NewInt(0), l.queryRange.get())
+
+			// Parse out events
+			err = l.getDepositEventsForBlockRange(currentBlock, endBlock)
+			if err != nil && isRangeTooLarge(err) && l.queryRange.shrink() {
+				l.log.Warn("Block range rejected by provider, reducing range", "from", currentBlock, "to", endBlock, "range", l.queryRange.get(), "err", err)
+				continue
+			} else if err != nil {
+				l.log.Error("Failed to get events for block range", "from", currentBlock, "to", endBlock, "err", err)
+				retry--
+				continue
 			}
+			l.querySucceeded()
+
+			l.checkpoint(currentBlock, endBlock)
 
 			l.latestBlock.Height = big.NewInt(0).Set(latestBlock)
 			l.latestBlock.LastUpdated = time.Now()
 
-			// Goto next block and reset retry counter
-			currentBlock.Add(currentBlock, big.NewInt(1))
+			// Goto next range and reset retry counter
+			currentBlock = new(big.Int).Add(endBlock, big.NewInt(1))
 			retry = BlockRetryLimit
 		}
 	}
 }
 
-// getDepositEventsForBlock looks for the deposit event in the latest block
-func (l *listener) getDepositEventsForBlock(latestBlock *big.Int) error {
-	l.log.Debug("Querying block for deposit events", "block", latestBlock)
-	query := buildQuery(l.cfg.bridgeContract, utils.Deposit, latestBlock, latestBlock)
+// processableBlock returns the latest block that may be processed, given the latest block of the chain
+func (l *listener) processableBlock(latestBlock *big.Int) (*big.Int, error) {
+	if l.cfg.finality == connection.ConfirmationsFinality {
+		return new(big.Int).Sub(latestBlock, l.blockConfirmations), nil
+	}
+	return l.conn.ProcessableBlock(l.blockConfirmations)
+}
+
+// checkpoint records that the blocks from startBlock to endBlock (inclusive) have been processed
+func (l *listener) checkpoint(startBlock, endBlock *big.Int) {
+	// Write to block store. Not a critical operation, no need to retry
+	err := l.blockstore.StoreBlock(endBlock)
+	if err != nil {
+		l.log.Error("Failed to write latest block to blockstore", "block", endBlock, "err", err)
+	}
+
+	if l.metrics != nil {
+		l.metrics.BlocksProcessed.Add(float64(new(big.Int).Sub(endBlock, startBlock).Int64() + 1))
+		l.metrics.LatestProcessedBlock.Set(float64(endBlock.Int64()))
+	}
+
+	// Record the hash of the block, so reorgs of processed blocks can be detected
+	header, err := l.conn.Client().HeaderByNumber(context.Background(), endBlock)
+	if err != nil {
+		l.log.Warn("Unable to fetch header of processed block", "block", endBlock, "err", err)
+		return
+	}
+	l.blocks.record(endBlock.Uint64(), header.Hash())
+	err = l.blocks.save()
+	if err != nil {
+		l.log.Warn("Failed to persist processed blocks", "err", err)
+	}
+
+	// Deposits orphaned before the tracked history can no longer become canonical again
+	if l.outbox != nil && endBlock.Uint64() > ReorgTrackingDepth {
+		err = l.outbox.Prune(l.cfg.id, endBlock.Uint64()-ReorgTrackingDepth)
+		if err != nil {
+			l.log.Warn("Failed to prune cancelled messages from outbox", "err", err)
+		}
+	}
+}
+
+// checkReorg verifies the processed blocks are still part of the canonical chain. If a reorg is detected,
+// messages for orphaned deposits are cancelled and the block to resume processing from is returned.
+func (l *listener) checkReorg() (*big.Int, error) {
+	r, err := l.blocks.check(context.Background(), l.conn.Client())
+	if err != nil || r == nil {
+		return nil, err
+	}
+
+	l.log.Error("Reorg of processed blocks detected, rewinding", "depth", r.depth, "resume", r.resume, "orphaned", len(r.orphaned), "confirmations", l.blockConfirmations)
+	err = l.blocks.save()
+	if err != nil {
+		l.log.Warn("Failed to persist processed blocks", "err", err)
+	}
+	if l.reorgMetrics != nil {
+		l.reorgMetrics.reorgs.Inc()
+		l.reorgMetrics.reorgDepth.Set(float64(r.depth))
+	}
+
+	// Orphaned deposits were in blocks up to the latest processed one
+	latest := r.resume.Uint64() + r.depth - 1
+	for _, m := range r.orphaned {
+		l.cancelMessage(m, latest)
+	}
+
+	err = l.blockstore.StoreBlock(new(big.Int).Sub(r.resume, big.NewInt(1)))
+	if err != nil {
+		l.log.Error("Failed to write latest block to blockstore", "block", r.resume, "err", err)
+	}
+	return r.resume, nil
+}
+
+// cancelMessage cancels a message whose deposit was orphaned, so writers will not vote on it. Block is the latest
+// block the deposit may have been in.
+func (l *listener) cancelMessage(m msg.Message, block uint64) {
+	log := chains.TransferLogger(l.log, m)
+
+	if l.outbox == nil {
+		log.Warn("Deposit orphaned by reorg, message was already routed", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		return
+	}
+
+	log.Warn("Deposit orphaned by reorg, cancelling message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+	l.track(m, transfers.Cancel())
+	err := l.outbox.Cancel(m, block)
+	if err != nil {
+		log.Error("Failed to cancel message in outbox", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+	}
+}
+
+// rangeEnd returns the last block of the range starting at startBlock. The range spans at most
+// blockRange blocks and never includes blocks with fewer than confirmations blocks on top of them.
+func rangeEnd(startBlock, latestBlock, confirmations, blockRange *big.Int) *big.Int {
+	end := new(big.Int).Add(startBlock, blockRange)
+	end.Sub(end, big.NewInt(1))
+
+	confirmed := new(big.Int).Sub(latestBlock, confirmations)
+	if end.Cmp(confirmed) == 1 {
+		end = confirmed
+	}
+	return end
+}
+
+// Error fragments returned by common providers when a log query spans too many blocks or results
+var rangeTooLargeErrors = []string{
+	"block range",
+	"range too large",
+	"range is too large",
+	"range is too wide",
+	"more than 10000 results",
+	"query returned more than",
+	"query timeout exceeded",
+	"response size exceeded",
+	"logs matched by query exceeds limit",
+}
+
+// Error fragments of rate limits, which must be backed off from rather than shrinking the range
+var rateLimitErrors = []string{
+	"rate limit",
+	"request limit",
+	"too many requests",
+	"capacity exceeded",
+}
+
+// isRangeTooLarge returns true if err indicates the provider rejected a log query for spanning too many blocks
+func isRangeTooLarge(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, frag := range rateLimitErrors {
+		if strings.Contains(msg, frag) {
+			return false
+		}
+	}
+	for _, frag := range rangeTooLargeErrors {
+		if strings.Contains(msg, frag) {
+			return true
+		}
+	}
+	return false
+}
+
+// querySucceeded records a successful log query, growing the block range back if it was reduced
+func (l *listener) querySucceeded() {
+	if l.queryRange.succeeded() {
+		l.log.Info("Increasing block range", "range", l.queryRange.get())
+	}
+}
+
+// queryRange is the number of blocks queried for logs at once. It is halved when the provider rejects a range as
+// too large, and doubled back towards the configured range after BlockRangeGrowthInterval consecutive successful
+// queries.
+type queryRange struct {
+	max       *big.Int
+	size      *big.Int
+	successes int
+}
+
+func newQueryRange(max *big.Int) *queryRange {
+	return &queryRange{
+		max:  new(big.Int).Set(max),
+		size: new(big.Int).Set(max),
+	}
+}
+
+// get returns the current range
+func (r *queryRange) get() *big.Int {
+	return new(big.Int).Set(r.size)
+}
+
+// shrink halves the range, returns false if it cannot be reduced further
+func (r *queryRange) shrink() bool {
+	r.successes = 0
+	if r.size.Cmp(big.NewInt(1)) <= 0 {
+		return false
+	}
+	r.size.Rsh(r.size, 1)
+	return true
+}
+
+// succeeded records a successful query, returns true if the range grew
+func (r *queryRange) succeeded() bool {
+	if r.size.Cmp(r.max) >= 0 {
+		return false
+	}
+	r.successes++
+	if r.successes < BlockRangeGrowthInterval {
+		return false
+	}
+	r.successes = 0
+	r.size.Lsh(r.size, 1)
+	if r.size.Cmp(r.max) == 1 {
+		r.size.Set(r.max)
+	}
+	return true
+}
+
+// getDepositEventsForBlockRange looks for deposit events in the blocks from startBlock to endBlock (inclusive)
+func (l *listener) getDepositEventsForBlockRange(startBlock, endBlock *big.Int) error {
+	l.log.Debug("Querying block range for deposit events", "from", startBlock, "to", endBlock)
+	query := buildQuery(l.cfg.bridgeContract, utils.Deposit, startBlock, endBlock)
 
 	// querying for logs
 	logs, err := l.conn.Client().FilterLogs(context.Background(), query)
@@ -167,6 +462,11 @@ func (l *listener) getDepositEventsForBlock(latestBlock *big.Int) error {
 		return fmt.Errorf("unable to Filter Logs: %w", err)
 	}
 
+	return l.handleDepositLogs(logs)
+}
+
+// handleDepositLogs constructs a message for each deposit log and routes it
+func (l *listener) handleDepositLogs(logs []types.Log) error {
 	// read through the log events and handle their deposit event if handler is recognized
 	for _, log := range logs {
 		var m msg.Message
@@ -186,23 +486,54 @@ func (l *listener) getDepositEventsForBlock(latestBlock *big.Int) error {
 		} else if addr == l.cfg.genericHandlerContract {
 			m, err = l.handleGenericDepositedEvent(destId, nonce)
 		} else {
-			l.log.Error("event has unrecognized handler", "handler", addr.Hex())
-			return nil
+			l.log.Error("event has unrecognized handler", "handler", addr.Hex(), "block", log.BlockNumber)
+			continue
 		}
 
 		if err != nil {
 			return err
 		}
 
+		if l.policy != nil && !l.policy.Allowed(m) {
+			continue
+		}
+
+		// Persist the message before routing, the block range will not be processed again once it is stored
+		if l.outbox != nil {
+			err = l.outbox.Append(m)
+			if err != nil {
+				return fmt.Errorf("failed to persist message to outbox: %w", err)
+			}
+		}
+
+		l.blocks.recordMessage(log.BlockNumber, log.BlockHash, m)
+		l.track(m, transfers.Deposit(log.BlockNumber, log.TxHash.Hex()))
+
 		err = l.router.Send(m)
 		if err != nil {
-			l.log.Error("subscription error: failed to route message", "err", err)
+			chains.TransferLogger(l.log, m).Error("subscription error: failed to route message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 		}
 	}
 
 	return nil
 }
 
+// replayOutbox routes all messages from this chain that were not acknowledged by a writer before the last shutdown
+func (l *listener) replayOutbox() {
+	if l.outbox == nil {
+		return
+	}
+
+	for _, m := range l.outbox.Pending(l.cfg.id) {
+		log := chains.TransferLogger(l.log, m)
+		log.Info("Replaying pending message from outbox", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		err := l.router.Send(m)
+		if err != nil {
+			log.Error("Failed to route pending message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		}
+	}
+}
+
 // buildQuery constructs a query for the bridgeContract by hashing sig to get the event topic
 func buildQuery(contract ethcommon.Address, sig utils.EventSig, startBlock *big.Int, endBlock *big.Int) eth.FilterQuery {
 	query := eth.FilterQuery{
@@ -4,6 +4,7 @@
 package ethereum
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -272,3 +273,90 @@ func compareMessage(expected, actual msg.Message) error {
 	}
 	return nil
 }
+
+func TestListener_rangeEnd(t *testing.T) {
+	testCases := []struct {
+		start, latest, confirmations, blockRange int64
+		expected                                 int64
+	}{
+		// Full range available
+		{start: 100, latest: 500, confirmations: 10, blockRange: 50, expected: 149},
+		// Range limited by confirmations
+		{start: 100, latest: 130, confirmations: 10, blockRange: 50, expected: 120},
+		// Single block range
+		{start: 100, latest: 500, confirmations: 10, blockRange: 1, expected: 100},
+		// Only the start block is confirmed
+		{start: 100, latest: 110, confirmations: 10, blockRange: 50, expected: 100},
+	}
+
+	for _, tc := range testCases {
+		end := rangeEnd(big.NewInt(tc.start), big.NewInt(tc.latest), big.NewInt(tc.confirmations), big.NewInt(tc.blockRange))
+		if end.Int64() != tc.expected {
+			t.Errorf("unexpected range end for start=%d latest=%d. Expected: %d Got: %d", tc.start, tc.latest, tc.expected, end.Int64())
+		}
+	}
+}
+
+func TestListener_isRangeTooLarge(t *testing.T) {
+	tooLarge := []string{
+		"query returned more than 10000 results",
+		"exceed maximum block range: 5000",
+		"Block range is too large",
+		"eth_getLogs block range too large, range: 2001, max: 2000",
+	}
+	for _, e := range tooLarge {
+		if !isRangeTooLarge(fmt.Errorf("unable to Filter Logs: %w", errors.New(e))) {
+			t.Errorf("expected error to be recognised as range too large: %s", e)
+		}
+	}
+
+	notTooLarge := []string{
+		"connection refused",
+		"daily request limit exceeded",
+		"429 Too Many Requests",
+		"project ID request rate exceeded, rate limit reached",
+	}
+	for _, e := range notTooLarge {
+		if isRangeTooLarge(errors.New(e)) {
+			t.Errorf("unrelated error should not be recognised as range too large: %s", e)
+		}
+	}
+}
+
+func TestListener_queryRange(t *testing.T) {
+	r := newQueryRange(big.NewInt(8))
+
+	if !r.shrink() || !r.shrink() || r.get().Int64() != 2 {
+		t.Fatalf("expected range to be halved twice, got %s", r.get())
+	}
+
+	for i := 0; i < BlockRangeGrowthInterval-1; i++ {
+		if r.succeeded() {
+			t.Fatalf("range grew after %d successes", i+1)
+		}
+	}
+	if !r.succeeded() || r.get().Int64() != 4 {
+		t.Fatalf("expected range to double, got %s", r.get())
+	}
+
+	// A rejection resets the successes
+	for i := 0; i < BlockRangeGrowthInterval-1; i++ {
+		r.succeeded()
+	}
+	r.shrink()
+	if r.succeeded() || r.get().Int64() != 2 {
+		t.Fatalf("expected range not to grow after a rejection, got %s", r.get())
+	}
+
+	for i := 0; i < 3*BlockRangeGrowthInterval; i++ {
+		r.succeeded()
+	}
+	if r.get().Int64() != 8 {
+		t.Fatalf("expected range to be capped at the configured range, got %s", r.get())
+	}
+
+	r = newQueryRange(big.NewInt(1))
+	if r.shrink() {
+		t.Fatal("expected range of a single block not to shrink")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// reorgMetrics report reorgs detected below the confirmation depth
+type reorgMetrics struct {
+	reorgs     prometheus.Counter
+	reorgDepth prometheus.Gauge
+}
+
+func newReorgMetrics(chain string) *reorgMetrics {
+	m := &reorgMetrics{
+		reorgs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_reorgs_detected", chain),
+			Help: "Number of reorgs of processed blocks detected by the listener",
+		}),
+		reorgDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_reorg_depth", chain),
+			Help: "Number of processed blocks orphaned by the latest reorg",
+		}),
+	}
+
+	prometheus.MustRegister(m.reorgs)
+	prometheus.MustRegister(m.reorgDepth)
+
+	return m
+}
+
+// proposalMetrics report the cancellation of expired proposals
+type proposalMetrics struct {
+	cancelled      prometheus.Counter
+	cancelFailures prometheus.Counter
+	expired        prometheus.Gauge
+}
+
+func newProposalMetrics(chain string) *proposalMetrics {
+	m := &proposalMetrics{
+		cancelled: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_proposals_cancelled", chain),
+			Help: "Number of expired proposals cancelled by the relayer",
+		}),
+		cancelFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_proposal_cancel_failures", chain),
+			Help: "Number of expired proposals the relayer failed to cancel",
+		}),
+		expired: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_expired_proposals", chain),
+			Help: "Number of expired proposals voted on by the relayer that are still active",
+		}),
+	}
+
+	prometheus.MustRegister(m.cancelled)
+	prometheus.MustRegister(m.cancelFailures)
+	prometheus.MustRegister(m.expired)
+
+	return m
+}
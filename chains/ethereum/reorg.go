@@ -0,0 +1,235 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Number of blocks behind the latest processed block for which hashes are kept
+const ReorgTrackingDepth = 256
+
+// headerSource provides block headers from the canonical chain
+type headerSource interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// processedBlock is a block the listener has processed, with the messages routed for deposits in it
+type processedBlock struct {
+	number   uint64
+	hash     common.Hash
+	messages []msg.Message
+}
+
+// trackedBlock is the persisted form of a processedBlock
+type trackedBlock struct {
+	Number   uint64           `json:"number"`
+	Hash     common.Hash      `json:"hash"`
+	Messages []trackedMessage `json:"messages,omitempty"`
+}
+
+// trackedMessage is the persisted form of a message, the payload of Ethereum deposits only contains byte slices
+type trackedMessage struct {
+	Source      msg.ChainId      `json:"src"`
+	Destination msg.ChainId      `json:"dst"`
+	Type        msg.TransferType `json:"type"`
+	Nonce       msg.Nonce        `json:"nonce"`
+	ResourceId  msg.ResourceId   `json:"resourceId"`
+	Payload     [][]byte         `json:"payload"`
+}
+
+func newTrackedMessage(m msg.Message) trackedMessage {
+	t := trackedMessage{Source: m.Source, Destination: m.Destination, Type: m.Type, Nonce: m.DepositNonce, ResourceId: m.ResourceId}
+	for _, p := range m.Payload {
+		bz, _ := p.([]byte)
+		t.Payload = append(t.Payload, bz)
+	}
+	return t
+}
+
+func (t trackedMessage) message() msg.Message {
+	m := msg.Message{Source: t.Source, Destination: t.Destination, Type: t.Type, DepositNonce: t.Nonce, ResourceId: t.ResourceId}
+	for _, p := range t.Payload {
+		m.Payload = append(m.Payload, p)
+	}
+	return m
+}
+
+// reorg describes a reorganisation of processed blocks
+type reorg struct {
+	resume   *big.Int      // First block to process again
+	depth    uint64        // Number of processed blocks that were orphaned
+	orphaned []msg.Message // Messages routed for deposits in orphaned blocks
+}
+
+// blockTracker records the hashes of processed blocks, so that reorgs deeper than the confirmation depth
+// can be detected. Only the end of each processed range and blocks containing deposits are recorded. If a path
+// is set, the blocks are persisted to it on save so reorgs across restarts are detected.
+type blockTracker struct {
+	path   string
+	blocks []*processedBlock // Ordered by block number
+}
+
+func newBlockTracker() *blockTracker {
+	return &blockTracker{}
+}
+
+// blockTrackerFileName returns the name of the file the tracked blocks are persisted to for the chain
+func blockTrackerFileName(chain msg.ChainId) string {
+	return fmt.Sprintf("blocks-%d.json", chain)
+}
+
+// openBlockTracker loads the blocks tracked for the chain stored in dir. An empty dir uses the default
+// blockstore location in the home directory.
+func openBlockTracker(dir string, chain msg.ChainId) (*blockTracker, error) {
+	dir, err := msglog.DataDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newBlockTracker()
+	t.path = filepath.Join(dir, blockTrackerFileName(chain))
+
+	bz, err := ioutil.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return t, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var blocks []trackedBlock
+	err = json.Unmarshal(bz, &blocks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", t.path, err)
+	}
+	for _, b := range blocks {
+		block := &processedBlock{number: b.Number, hash: b.Hash}
+		for _, m := range b.Messages {
+			block.messages = append(block.messages, m.message())
+		}
+		t.blocks = append(t.blocks, block)
+	}
+	return t, nil
+}
+
+// save persists the tracked blocks
+func (t *blockTracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	blocks := make([]trackedBlock, 0, len(t.blocks))
+	for _, block := range t.blocks {
+		b := trackedBlock{Number: block.number, Hash: block.hash}
+		for _, m := range block.messages {
+			b.Messages = append(b.Messages, newTrackedMessage(m))
+		}
+		blocks = append(blocks, b)
+	}
+	bz, err := json.Marshal(blocks)
+	if err != nil {
+		return err
+	}
+
+	tmp := t.path + ".tmp"
+	err = ioutil.WriteFile(tmp, bz, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// record adds the processed block, blocks older than ReorgTrackingDepth are discarded. A block that was already
+// recorded, as happens when a range is processed again after a failure, is returned rather than added again.
+func (t *blockTracker) record(number uint64, hash common.Hash) *processedBlock {
+	for i := len(t.blocks) - 1; i >= 0 && t.blocks[i].number >= number; i-- {
+		if t.blocks[i].number == number && t.blocks[i].hash == hash {
+			return t.blocks[i]
+		}
+	}
+
+	block := &processedBlock{number: number, hash: hash}
+	t.blocks = append(t.blocks, block)
+
+	i := 0
+	for i < len(t.blocks) && t.blocks[i].number+ReorgTrackingDepth < number {
+		i++
+	}
+	t.blocks = t.blocks[i:]
+	return block
+}
+
+// recordMessage adds a message routed for a deposit in the block, unless it was already recorded
+func (t *blockTracker) recordMessage(number uint64, hash common.Hash, m msg.Message) {
+	block := t.record(number, hash)
+	key := msglog.KeyOf(m)
+	for _, recorded := range block.messages {
+		if msglog.KeyOf(recorded) == key {
+			return
+		}
+	}
+	block.messages = append(block.messages, m)
+}
+
+func (t *blockTracker) last() *processedBlock {
+	if len(t.blocks) == 0 {
+		return nil
+	}
+	return t.blocks[len(t.blocks)-1]
+}
+
+// check verifies the latest processed block is still part of the canonical chain. If it is not, orphaned blocks
+// are discarded until a block that is still canonical is found, and the reorg is returned.
+func (t *blockTracker) check(ctx context.Context, headers headerSource) (*reorg, error) {
+	last := t.last()
+	if last == nil {
+		return nil, nil
+	}
+
+	canonical, err := isCanonical(ctx, headers, last)
+	if err != nil || canonical {
+		return nil, err
+	}
+
+	r := &reorg{}
+	for len(t.blocks) > 0 {
+		block := t.last()
+		canonical, err := isCanonical(ctx, headers, block)
+		if err != nil {
+			return nil, err
+		}
+		if canonical {
+			break
+		}
+		r.orphaned = append(r.orphaned, block.messages...)
+		r.resume = new(big.Int).SetUint64(block.number)
+		t.blocks = t.blocks[:len(t.blocks)-1]
+	}
+
+	// Resume after the common ancestor, or from the oldest orphaned block if the reorg is deeper than the tracked history
+	if ancestor := t.last(); ancestor != nil {
+		r.resume = new(big.Int).SetUint64(ancestor.number + 1)
+	}
+	r.depth = last.number - r.resume.Uint64() + 1
+	return r, nil
+}
+
+func isCanonical(ctx context.Context, headers headerSource, block *processedBlock) (bool, error) {
+	header, err := headers.HeaderByNumber(ctx, new(big.Int).SetUint64(block.number))
+	if err != nil {
+		return false, err
+	}
+	return header.Hash() == block.hash, nil
+}
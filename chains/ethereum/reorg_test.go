@@ -0,0 +1,193 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeHeaderSource serves headers for a chain, forking from a block changes the hashes of all later blocks
+type fakeHeaderSource struct {
+	forks map[uint64]byte
+}
+
+func (s *fakeHeaderSource) header(number uint64) *types.Header {
+	fork := byte(0)
+	for block, f := range s.forks {
+		if number >= block && f > fork {
+			fork = f
+		}
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(number), Extra: []byte{fork}}
+}
+
+func (s *fakeHeaderSource) hash(number uint64) common.Hash {
+	return s.header(number).Hash()
+}
+
+func (s *fakeHeaderSource) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	return s.header(number.Uint64()), nil
+}
+
+func TestBlockTracker_NoReorg(t *testing.T) {
+	headers := &fakeHeaderSource{forks: map[uint64]byte{}}
+	tracker := newBlockTracker()
+
+	tracker.recordMessage(5, headers.hash(5), msg.Message{DepositNonce: 1})
+	tracker.record(10, headers.hash(10))
+
+	r, err := tracker.check(context.Background(), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != nil {
+		t.Fatalf("unexpected reorg: %+v", r)
+	}
+}
+
+func TestBlockTracker_Reorg(t *testing.T) {
+	headers := &fakeHeaderSource{forks: map[uint64]byte{}}
+	tracker := newBlockTracker()
+
+	tracker.record(4, headers.hash(4))
+	tracker.recordMessage(6, headers.hash(6), msg.Message{DepositNonce: 1})
+	tracker.recordMessage(8, headers.hash(8), msg.Message{DepositNonce: 2})
+	tracker.record(10, headers.hash(10))
+
+	headers.forks[6] = 1
+	r, err := tracker.check(context.Background(), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil {
+		t.Fatal("expected reorg to be detected")
+	}
+	if r.resume.Uint64() != 5 {
+		t.Fatalf("expected to resume from block 5, got %s", r.resume)
+	}
+	if r.depth != 6 {
+		t.Fatalf("expected depth 6, got %d", r.depth)
+	}
+	if len(r.orphaned) != 2 {
+		t.Fatalf("expected 2 orphaned messages, got %d", len(r.orphaned))
+	}
+	if tracker.last().number != 4 {
+		t.Fatalf("expected block 4 to be the latest tracked block, got %d", tracker.last().number)
+	}
+
+	// Blocks of the new chain can be tracked again
+	tracker.record(10, headers.hash(10))
+	r, err = tracker.check(context.Background(), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != nil {
+		t.Fatalf("unexpected reorg: %+v", r)
+	}
+}
+
+func TestBlockTracker_RangeRetried(t *testing.T) {
+	headers := &fakeHeaderSource{forks: map[uint64]byte{}}
+	tracker := newBlockTracker()
+
+	// The range fails after its deposits were recorded, and is processed again
+	for i := 0; i < 2; i++ {
+		tracker.recordMessage(6, headers.hash(6), msg.Message{DepositNonce: 1})
+		tracker.recordMessage(6, headers.hash(6), msg.Message{DepositNonce: 2})
+		tracker.recordMessage(8, headers.hash(8), msg.Message{DepositNonce: 3})
+	}
+	tracker.record(10, headers.hash(10))
+
+	if len(tracker.blocks) != 3 {
+		t.Fatalf("expected 3 tracked blocks, got %d", len(tracker.blocks))
+	}
+
+	headers.forks[6] = 1
+	r, err := tracker.check(context.Background(), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil {
+		t.Fatal("expected reorg to be detected")
+	}
+	if len(r.orphaned) != 3 {
+		t.Fatalf("expected 3 orphaned messages, got %d", len(r.orphaned))
+	}
+}
+
+func TestBlockTracker_DeepReorg(t *testing.T) {
+	headers := &fakeHeaderSource{forks: map[uint64]byte{}}
+	tracker := newBlockTracker()
+
+	for i := uint64(1); i <= ReorgTrackingDepth*2; i++ {
+		tracker.record(i, headers.hash(i))
+	}
+	if tracker.blocks[0].number != ReorgTrackingDepth {
+		t.Fatalf("expected oldest tracked block to be %d, got %d", ReorgTrackingDepth, tracker.blocks[0].number)
+	}
+
+	headers.forks[1] = 1
+	r, err := tracker.check(context.Background(), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil {
+		t.Fatal("expected reorg to be detected")
+	}
+	if r.resume.Uint64() != ReorgTrackingDepth {
+		t.Fatalf("expected to resume from block %d, got %s", ReorgTrackingDepth, r.resume)
+	}
+}
+
+func TestBlockTracker_Persisted(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "blocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	headers := &fakeHeaderSource{forks: map[uint64]byte{}}
+	tracker, err := openBlockTracker(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := msg.Message{Source: 1, Destination: 2, Type: msg.FungibleTransfer, DepositNonce: 1, Payload: []interface{}{[]byte{1}, []byte{2}}}
+	tracker.record(4, headers.hash(4))
+	tracker.recordMessage(6, headers.hash(6), m)
+	tracker.record(10, headers.hash(10))
+	err = tracker.save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The reorg happens while the relayer is offline
+	headers.forks[6] = 1
+	tracker, err = openBlockTracker(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tracker.check(context.Background(), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil {
+		t.Fatal("expected reorg to be detected")
+	}
+	if r.resume.Uint64() != 5 {
+		t.Fatalf("expected to resume from block 5, got %s", r.resume)
+	}
+	if len(r.orphaned) != 1 || !reflect.DeepEqual(r.orphaned[0], m) {
+		t.Fatalf("expected orphaned message %+v, got %+v", m, r.orphaned)
+	}
+}
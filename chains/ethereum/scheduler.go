@@ -0,0 +1,420 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains"
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Time between scans for passed proposals
+var ExecutionScanInterval = time.Second * 5
+
+// Time to wait before retrying a failed execution
+var ExecutionRetryInterval = time.Minute
+
+// pendingExecution is a proposal this relayer voted on, to be executed once it passes
+type pendingExecution struct {
+	Source      msg.ChainId    `json:"src"`
+	Destination msg.ChainId    `json:"dst"`
+	Nonce       msg.Nonce      `json:"nonce"`
+	ResourceId  msg.ResourceId `json:"resourceId"`
+	Data        []byte         `json:"data"`
+	DataHash    ethcommon.Hash `json:"dataHash"`
+	executing   bool
+	retryAt     time.Time // Set if an execution failed, the status is checked again after it
+}
+
+func (p *pendingExecution) key() proposalKey {
+	return proposalKey{source: p.Source, nonce: p.Nonce, dataHash: p.DataHash}
+}
+
+// message returns the parts of the original message required for execution
+func (p *pendingExecution) message() msg.Message {
+	return msg.Message{Source: p.Source, Destination: p.Destination, DepositNonce: p.Nonce, ResourceId: p.ResourceId}
+}
+
+// schedulerState is the persisted state of the scheduler
+type schedulerState struct {
+	Next    *big.Int            `json:"next"`
+	Pending []*pendingExecution `json:"pending"`
+}
+
+// executionScheduler keeps the proposals awaiting execution, and the first block that has not been scanned for
+// ProposalEvent logs. If a path is set, the state is persisted to it on every change.
+type executionScheduler struct {
+	path    string
+	pending map[proposalKey]*pendingExecution
+	next    *big.Int
+	lock    sync.Mutex
+}
+
+func newExecutionScheduler() *executionScheduler {
+	return &executionScheduler{pending: make(map[proposalKey]*pendingExecution)}
+}
+
+// schedulerFileName returns the name of the file the scheduler state is persisted to for the chain
+func schedulerFileName(chain msg.ChainId) string {
+	return fmt.Sprintf("executions-%d.json", chain)
+}
+
+// openExecutionScheduler loads the scheduler state for the chain stored in dir. An empty dir uses the default
+// blockstore location in the home directory.
+func openExecutionScheduler(dir string, chain msg.ChainId) (*executionScheduler, error) {
+	dir, err := msglog.DataDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newExecutionScheduler()
+	s.path = filepath.Join(dir, schedulerFileName(chain))
+
+	bz, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var state schedulerState
+	err = json.Unmarshal(bz, &state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", s.path, err)
+	}
+	s.next = state.Next
+	for _, p := range state.Pending {
+		s.pending[p.key()] = p
+	}
+	return s, nil
+}
+
+// save persists the state, the lock must be held
+func (s *executionScheduler) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	state := schedulerState{Next: s.next}
+	for _, p := range s.pending {
+		state.Pending = append(state.Pending, p)
+	}
+	bz, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	err = ioutil.WriteFile(tmp, bz, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// add schedules the proposal for execution. Logs are scanned again from fromBlock if it has already been scanned.
+func (s *executionScheduler) add(p *pendingExecution, fromBlock *big.Int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.pending) == 0 || s.next == nil || fromBlock.Cmp(s.next) == -1 {
+		s.next = new(big.Int).Set(fromBlock)
+	}
+	if existing, ok := s.pending[p.key()]; ok && existing.executing {
+		return nil
+	}
+	s.pending[p.key()] = p
+	return s.save()
+}
+
+// remove removes the proposal from the pending set
+func (s *executionScheduler) remove(key proposalKey) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.pending[key]; !ok {
+		return nil
+	}
+	delete(s.pending, key)
+	return s.save()
+}
+
+// list returns all pending proposals
+func (s *executionScheduler) list() []*pendingExecution {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var pending []*pendingExecution
+	for _, p := range s.pending {
+		pending = append(pending, p)
+	}
+	return pending
+}
+
+// markExecuting marks the proposal as being executed. Returns false if it is not pending or already executing.
+func (s *executionScheduler) markExecuting(key proposalKey) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	p, ok := s.pending[key]
+	if !ok || p.executing {
+		return false
+	}
+	p.executing = true
+	p.retryAt = time.Time{}
+	return true
+}
+
+// release marks the proposal as no longer executing after an execution failed. It is checked again once retryAt
+// has passed.
+func (s *executionScheduler) release(key proposalKey, retryAt time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	p, ok := s.pending[key]
+	if !ok {
+		return
+	}
+	p.executing = false
+	p.retryAt = retryAt
+}
+
+// due returns the proposals whose failed execution should be retried at now
+func (s *executionScheduler) due(now time.Time) []*pendingExecution {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var due []*pendingExecution
+	for _, p := range s.pending {
+		if !p.executing && !p.retryAt.IsZero() && !now.Before(p.retryAt) {
+			due = append(due, p)
+		}
+	}
+	return due
+}
+
+// nextBlock returns the first block that has not been scanned, nil if no proposals are pending
+func (s *executionScheduler) nextBlock() *big.Int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.pending) == 0 || s.next == nil {
+		return nil
+	}
+	return new(big.Int).Set(s.next)
+}
+
+// scanned records that logs up to and including block have been processed
+func (s *executionScheduler) scanned(block *big.Int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	next := new(big.Int).Add(block, big.NewInt(1))
+	if s.next != nil && next.Cmp(s.next) <= 0 {
+		return nil
+	}
+	s.next = next
+	return s.save()
+}
+
+// handleLog processes a ProposalEvent log. Returns the proposal if it passed and is not already being executed,
+// it is marked as executing. Executed and cancelled proposals are removed.
+func (s *executionScheduler) handleLog(log types.Log) (*pendingExecution, error) {
+	if len(log.Topics) < 4 || len(log.Data) < 64 {
+		return nil, nil
+	}
+	key := proposalKey{
+		source: msg.ChainId(log.Topics[1].Big().Uint64()),
+		nonce:  msg.Nonce(log.Topics[2].Big().Uint64()),
+	}
+	copy(key.dataHash[:], log.Data[32:64])
+	status := utils.ProposalStatus(log.Topics[3].Big().Uint64())
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	p, ok := s.pending[key]
+	if !ok {
+		return nil, nil
+	}
+
+	switch status {
+	case utils.Passed:
+		if p.executing {
+			return nil, nil
+		}
+		p.executing = true
+		p.retryAt = time.Time{}
+		return p, nil
+	case utils.Executed, utils.Cancelled:
+		delete(s.pending, key)
+		return nil, s.save()
+	}
+	return nil, nil
+}
+
+// scheduleExecution adds the proposal to the scheduler, it is executed once it passes
+func (w *writer) scheduleExecution(m msg.Message, data []byte, dataHash [32]byte, fromBlock *big.Int) *pendingExecution {
+	log := chains.TransferLogger(w.log, m)
+
+	p := &pendingExecution{
+		Source:      m.Source,
+		Destination: m.Destination,
+		Nonce:       m.DepositNonce,
+		ResourceId:  m.ResourceId,
+		Data:        data,
+		DataHash:    dataHash,
+	}
+	log.Info("Scheduling proposal for execution", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "block", fromBlock)
+	err := w.scheduler.add(p, fromBlock)
+	if err != nil {
+		log.Error("Failed to persist scheduled execution", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+	}
+	return p
+}
+
+// scheduleExecutionFromLatest schedules the proposal, watching for it to pass from the latest block. Returns nil if
+// the latest block can't be fetched.
+func (w *writer) scheduleExecutionFromLatest(m msg.Message, data []byte, dataHash [32]byte) *pendingExecution {
+	latestBlock, err := w.conn.LatestBlock()
+	if err != nil {
+		chains.TransferLogger(w.log, m).Error("Unable to fetch latest block", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		return nil
+	}
+	return w.scheduleExecution(m, data, dataHash, latestBlock)
+}
+
+// executePassed schedules a proposal that has already passed and executes it, unless the scheduler is already
+// executing it. Returns false if the proposal could not be scheduled.
+func (w *writer) executePassed(m msg.Message, data []byte, dataHash [32]byte) bool {
+	p := w.scheduleExecutionFromLatest(m, data, dataHash)
+	if p == nil {
+		return false
+	}
+	if w.scheduler.markExecuting(p.key()) {
+		go w.executePending(p)
+	}
+	return true
+}
+
+// runScheduler watches ProposalEvent logs for all pending proposals, and executes them once they pass. Failed
+// executions are retried after ExecutionRetryInterval.
+func (w *writer) runScheduler() {
+	w.checkPendingExecutions(w.scheduler.list())
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(ExecutionScanInterval):
+		}
+
+		w.checkPendingExecutions(w.scheduler.due(time.Now()))
+
+		next := w.scheduler.nextBlock()
+		if next == nil {
+			continue
+		}
+
+		end, err := w.conn.ProcessableBlock(w.cfg.blockConfirmations)
+		if err != nil {
+			w.log.Warn("Unable to fetch processable block for execution scheduler", "err", err)
+			continue
+		}
+		w.scanExecutions(next, end)
+	}
+}
+
+// checkPendingExecutions executes the pending proposals that have passed, such as those that passed while the
+// relayer was offline, and drops those that are already complete
+func (w *writer) checkPendingExecutions(pending []*pendingExecution) {
+	for _, p := range pending {
+		log := w.transferLog(p.Source, p.Nonce)
+		prop, err := w.bridgeContract.GetProposal(w.conn.CallOpts(), uint8(p.Source), uint64(p.Nonce), p.DataHash)
+		if err != nil {
+			log.Warn("Failed to fetch pending proposal", "src", p.Source, "dst", p.Destination, "nonce", p.Nonce, "err", err)
+			continue
+		}
+
+		switch utils.ProposalStatus(prop.Status) {
+		case utils.Passed:
+			if w.scheduler.markExecuting(p.key()) {
+				go w.executePending(p)
+			}
+		case utils.Executed, utils.Cancelled:
+			err = w.scheduler.remove(p.key())
+		}
+		if err != nil {
+			log.Error("Failed to persist scheduled execution", "src", p.Source, "dst", p.Destination, "nonce", p.Nonce, "err", err)
+		}
+	}
+}
+
+// scanExecutions processes the ProposalEvent logs from startBlock to endBlock (inclusive)
+func (w *writer) scanExecutions(startBlock, endBlock *big.Int) {
+	current := new(big.Int).Set(startBlock)
+	for current.Cmp(endBlock) <= 0 {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		rangeEndBlock := rangeEnd(current, endBlock, big.NewInt(0), w.cfg.blockRange)
+		query := buildQuery(w.cfg.bridgeContract, utils.ProposalEvent, current, rangeEndBlock)
+		logs, err := w.conn.Client().FilterLogs(context.Background(), query)
+		if err != nil {
+			w.log.Warn("Unable to fetch proposal logs", "from", current, "to", rangeEndBlock, "err", err)
+			return
+		}
+
+		for _, log := range logs {
+			p, err := w.scheduler.handleLog(log)
+			if err != nil {
+				w.log.Error("Failed to persist scheduled execution", "err", err)
+			}
+			if p != nil {
+				go w.executePending(p)
+			}
+		}
+
+		err = w.scheduler.scanned(rangeEndBlock)
+		if err != nil {
+			w.log.Error("Failed to persist scheduled execution", "err", err)
+		}
+		current = new(big.Int).Add(rangeEndBlock, big.NewInt(1))
+	}
+}
+
+// executePending executes the passed proposal in turn. It is removed from the scheduler once the execution is
+// mined, or the proposal was finalized by another relayer. Otherwise its status is checked again after
+// ExecutionRetryInterval, and it is executed again if it is still passed.
+func (w *writer) executePending(p *pendingExecution) {
+	log := w.transferLog(p.Source, p.Nonce)
+	w.executeInTurn(p.message(), p.Data, p.DataHash, func(finalized bool) {
+		if !finalized {
+			log.Warn("Proposal execution not confirmed, will check again", "src", p.Source, "dst", p.Destination, "nonce", p.Nonce, "retryIn", ExecutionRetryInterval)
+			w.scheduler.release(p.key(), time.Now().Add(ExecutionRetryInterval))
+			return
+		}
+
+		err := w.scheduler.remove(p.key())
+		if err != nil {
+			log.Error("Failed to persist scheduled execution", "src", p.Source, "dst", p.Destination, "nonce", p.Nonce, "err", err)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+func TestExecutionScheduler(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "scheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := openExecutionScheduler(dir, msg.ChainId(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.nextBlock() != nil {
+		t.Fatalf("expected no block to scan, got %s", s.nextBlock())
+	}
+
+	a := &pendingExecution{Source: 0, Destination: 1, Nonce: 1, Data: []byte{1}, DataHash: [32]byte{1}}
+	b := &pendingExecution{Source: 0, Destination: 1, Nonce: 2, Data: []byte{2}, DataHash: [32]byte{2}}
+	err = s.add(a, big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.scanned(big.NewInt(150))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Blocks that were already scanned are scanned again for a proposal added later
+	err = s.add(b, big.NewInt(120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.nextBlock().Cmp(big.NewInt(120)) != 0 {
+		t.Fatalf("expected next block 120, got %s", s.nextBlock())
+	}
+
+	// The pending set is restored after a restart
+	s, err = openExecutionScheduler(dir, msg.ChainId(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.list()) != 2 || s.nextBlock().Cmp(big.NewInt(120)) != 0 {
+		t.Fatalf("expected 2 pending proposals from block 120, got %d from %s", len(s.list()), s.nextBlock())
+	}
+
+	// A passed proposal is returned once
+	p, err := s.handleLog(proposalLog(utils.ProposalEvent, 0, 1, utils.Passed, a.DataHash, 130))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.Nonce != 1 || p.Data[0] != 1 {
+		t.Fatalf("expected passed proposal, got %v", p)
+	}
+	p, _ = s.handleLog(proposalLog(utils.ProposalEvent, 0, 1, utils.Passed, a.DataHash, 130))
+	if p != nil {
+		t.Fatalf("expected proposal to be executing, got %v", p)
+	}
+
+	// Proposals for another data hash are ignored
+	p, _ = s.handleLog(proposalLog(utils.ProposalEvent, 0, 2, utils.Passed, [32]byte{3}, 131))
+	if p != nil {
+		t.Fatalf("expected no proposal, got %v", p)
+	}
+
+	// Executed and cancelled proposals are removed
+	_, err = s.handleLog(proposalLog(utils.ProposalEvent, 0, 2, utils.Cancelled, b.DataHash, 132))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.remove(a.key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.nextBlock() != nil {
+		t.Fatalf("expected no block to scan, got %s", s.nextBlock())
+	}
+
+	s, err = openExecutionScheduler(dir, msg.ChainId(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.list()) != 0 {
+		t.Fatalf("expected no pending proposals, got %d", len(s.list()))
+	}
+}
+
+func TestExecutionScheduler_Release(t *testing.T) {
+	s := newExecutionScheduler()
+
+	p := &pendingExecution{Source: 0, Destination: 1, Nonce: 1, Data: []byte{1}, DataHash: [32]byte{1}}
+	err := s.add(p, big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.markExecuting(p.key()) {
+		t.Fatal("expected proposal to be marked as executing")
+	}
+
+	// A failed execution keeps the proposal, and it is due once the retry time has passed
+	now := time.Now()
+	s.release(p.key(), now.Add(time.Minute))
+	if len(s.list()) != 1 {
+		t.Fatalf("expected failed proposal to remain pending, got %d", len(s.list()))
+	}
+	if due := s.due(now); len(due) != 0 {
+		t.Fatalf("expected no proposals due, got %d", len(due))
+	}
+	if due := s.due(now.Add(time.Minute)); len(due) != 1 {
+		t.Fatalf("expected 1 proposal due, got %d", len(due))
+	}
+
+	// It is no longer due once it is executing again
+	if !s.markExecuting(p.key()) {
+		t.Fatal("expected released proposal to be marked as executing")
+	}
+	if due := s.due(now.Add(time.Minute)); len(due) != 0 {
+		t.Fatalf("expected no proposals due, got %d", len(due))
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Time spent polling after the first subscription failure before subscribing again
+var SubscriptionRetryInterval = time.Second * 30
+
+// Maximum time spent polling after consecutive subscription failures before subscribing again
+var SubscriptionRetryMaxInterval = time.Minute * 10
+
+var ErrSubscriptionTerminated = errors.New("subscription terminated")
+
+// subscribeBlocks processes blocks from startBlock using websocket subscriptions. On any subscription or processing
+// error the listener falls back to polling from the first unprocessed block, and subscribes again once polling has
+// caught up with the chain. The time spent polling before subscribing again doubles after each subscription that
+// failed without processing any blocks, up to SubscriptionRetryMaxInterval.
+func (l *listener) subscribeBlocks(startBlock *big.Int) error {
+	currentBlock := new(big.Int).Set(startBlock)
+	backoff := SubscriptionRetryInterval
+	for {
+		next, err := l.subscribe(currentBlock)
+		if errors.Is(err, ErrSubscriptionTerminated) {
+			return err
+		}
+
+		if next.Cmp(currentBlock) == 1 {
+			backoff = SubscriptionRetryInterval
+		}
+		l.log.Warn("Subscription failed, falling back to polling", "block", next, "retryIn", backoff, "err", err)
+
+		currentBlock, err = l.poll(next, time.Now().Add(backoff))
+		if err != nil || currentBlock == nil {
+			return err
+		}
+
+		backoff *= 2
+		if backoff > SubscriptionRetryMaxInterval {
+			backoff = SubscriptionRetryMaxInterval
+		}
+		l.log.Info("Polling caught up, subscribing again", "block", currentBlock)
+	}
+}
+
+// subscribe listens for deposit events and new heads using websocket subscriptions, starting at startBlock.
+// Deposit logs are buffered until their block is final. Blocks up to the start of the
+// subscription may have been missed by it, so they are queried from the node instead. Returns the first
+// unprocessed block and the error that ended the subscription.
+func (l *listener) subscribe(startBlock *big.Int) (*big.Int, error) {
+	currentBlock := new(big.Int).Set(startBlock)
+
+	heads := make(chan *types.Header)
+	headSub, err := l.conn.Client().SubscribeNewHead(context.Background(), heads)
+	if err != nil {
+		return currentBlock, err
+	}
+
+	logs := make(chan types.Log)
+	query := buildQuery(l.cfg.bridgeContract, utils.Deposit, nil, nil)
+	logSub, err := l.conn.Client().SubscribeFilterLogs(context.Background(), query, logs)
+	if err != nil {
+		unsubscribe(headSub)
+		return currentBlock, err
+	}
+
+	// Logs up to and including this block may have been emitted before the subscription started
+	subStart, err := l.conn.LatestBlock()
+	if err != nil {
+		unsubscribe(headSub, logSub)
+		return currentBlock, err
+	}
+	l.log.Info("Subscribed to deposit events", "block", currentBlock, "subscriptionStart", subStart)
+
+	buffer := newLogBuffer()
+	for {
+		select {
+		case <-l.stop:
+			unsubscribe(headSub, logSub)
+			return currentBlock, ErrSubscriptionTerminated
+		case err := <-headSub.Err():
+			unsubscribe(headSub, logSub)
+			return currentBlock, err
+		case err := <-logSub.Err():
+			unsubscribe(headSub, logSub)
+			return currentBlock, err
+		case log := <-logs:
+			buffer.add(log)
+		case head := <-heads:
+			if l.metrics != nil {
+				l.metrics.LatestKnownBlock.Set(float64(head.Number.Int64()))
+			}
+			l.latestBlock.Height = new(big.Int).Set(head.Number)
+			l.latestBlock.LastUpdated = time.Now()
+
+			confirmed, err := l.processableBlock(head.Number)
+			if err != nil {
+				unsubscribe(headSub, logSub)
+				return currentBlock, err
+			}
+			if confirmed.Cmp(currentBlock) == -1 {
+				l.log.Debug("Block not ready, waiting for next head", "target", currentBlock, "latest", head.Number)
+				continue
+			}
+
+			// Rewinding requires querying blocks that were already taken from the buffer
+			resume, err := l.checkReorg()
+			if err != nil {
+				unsubscribe(headSub, logSub)
+				return currentBlock, err
+			} else if resume != nil {
+				unsubscribe(headSub, logSub)
+				return resume, ErrReorgDetected
+			}
+
+			currentBlock, err = l.processConfirmedBlocks(currentBlock, confirmed, subStart, buffer)
+			if err != nil {
+				unsubscribe(headSub, logSub)
+				return currentBlock, err
+			}
+		}
+	}
+}
+
+// unsubscribe closes the subscriptions
+func unsubscribe(subs ...eth.Subscription) {
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+}
+
+// processConfirmedBlocks handles the deposits in the blocks from startBlock to endBlock (inclusive). Blocks up to
+// subStart are queried from the node, later blocks use the logs received from the subscription. Returns the
+// first block that has not been processed.
+func (l *listener) processConfirmedBlocks(startBlock, endBlock, subStart *big.Int, buffer *logBuffer) (*big.Int, error) {
+	currentBlock := startBlock
+	if currentBlock.Cmp(subStart) <= 0 {
+		queryEnd := endBlock
+		if queryEnd.Cmp(subStart) == 1 {
+			queryEnd = subStart
+		}
+
+		var err error
+		currentBlock, err = l.queryBlocks(currentBlock, queryEnd)
+		if err != nil {
+			return currentBlock, err
+		}
+		if currentBlock.Cmp(endBlock) == 1 {
+			return currentBlock, nil
+		}
+	}
+
+	err := l.handleDepositLogs(buffer.take(currentBlock, endBlock))
+	if err != nil {
+		return currentBlock, err
+	}
+	l.checkpoint(currentBlock, endBlock)
+
+	return new(big.Int).Add(endBlock, big.NewInt(1)), nil
+}
+
+// queryBlocks queries the node for deposits in the blocks from startBlock to endBlock (inclusive), in ranges of at most
+// `l.cfg.blockRange` blocks, reduced while the provider rejects them as too large. Returns the first block that has not been processed.
+func (l *listener) queryBlocks(startBlock, endBlock *big.Int) (*big.Int, error) {
+	currentBlock := new(big.Int).Set(startBlock)
+
+	for currentBlock.Cmp(endBlock) <= 0 {
+		select {
+		case <-l.stop:
+			return currentBlock, errors.New("subscription terminated")
+		default:
+		}
+
+		rangeEndBlock := rangeEnd(currentBlock, endBlock, big.NewInt(0), l.queryRange.get())
+		err := l.getDepositEventsForBlockRange(currentBlock, rangeEndBlock)
+		if err != nil && isRangeTooLarge(err) && l.queryRange.shrink() {
+			l.log.Warn("Block range rejected by provider, reducing range", "from", currentBlock, "to", rangeEndBlock, "range", l.queryRange.get(), "err", err)
+			continue
+		} else if err != nil {
+			return currentBlock, err
+		}
+		l.querySucceeded()
+
+		l.checkpoint(currentBlock, rangeEndBlock)
+		currentBlock = new(big.Int).Add(rangeEndBlock, big.NewInt(1))
+	}
+	return currentBlock, nil
+}
+
+// logBuffer holds the logs received from a subscription until their block is confirmed
+type logBuffer struct {
+	logs map[uint64][]types.Log
+}
+
+func newLogBuffer() *logBuffer {
+	return &logBuffer{logs: make(map[uint64][]types.Log)}
+}
+
+// add buffers the log, or removes it if it was reverted by a reorg
+func (b *logBuffer) add(log types.Log) {
+	if !log.Removed {
+		b.logs[log.BlockNumber] = append(b.logs[log.BlockNumber], log)
+		return
+	}
+
+	blockLogs := b.logs[log.BlockNumber]
+	for i, l := range blockLogs {
+		if l.TxHash == log.TxHash && l.Index == log.Index {
+			b.logs[log.BlockNumber] = append(blockLogs[:i], blockLogs[i+1:]...)
+			break
+		}
+	}
+}
+
+// take removes and returns the logs for the blocks from startBlock to endBlock (inclusive), ordered by block and
+// log index. Logs for blocks before startBlock have already been processed and are discarded.
+func (b *logBuffer) take(startBlock, endBlock *big.Int) []types.Log {
+	var logs []types.Log
+	for block, blockLogs := range b.logs {
+		if block > endBlock.Uint64() {
+			continue
+		}
+		if block >= startBlock.Uint64() {
+			logs = append(logs, blockLogs...)
+		}
+		delete(b.logs, block)
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+	return logs
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func testLog(block uint64, index uint, tx byte) types.Log {
+	return types.Log{BlockNumber: block, Index: index, TxHash: common.BytesToHash([]byte{tx})}
+}
+
+func TestLogBuffer_Take(t *testing.T) {
+	buffer := newLogBuffer()
+	buffer.add(testLog(12, 1, 3))
+	buffer.add(testLog(10, 4, 1))
+	buffer.add(testLog(12, 0, 2))
+	buffer.add(testLog(9, 0, 9))
+	buffer.add(testLog(14, 0, 4))
+
+	// Block 9 was already processed, block 14 is not confirmed
+	logs := buffer.take(big.NewInt(10), big.NewInt(13))
+	expected := []types.Log{testLog(10, 4, 1), testLog(12, 0, 2), testLog(12, 1, 3)}
+	if !reflect.DeepEqual(logs, expected) {
+		t.Fatalf("unexpected logs.\n\tExpected: %#v\n\tGot: %#v", expected, logs)
+	}
+
+	logs = buffer.take(big.NewInt(14), big.NewInt(14))
+	expected = []types.Log{testLog(14, 0, 4)}
+	if !reflect.DeepEqual(logs, expected) {
+		t.Fatalf("unexpected logs.\n\tExpected: %#v\n\tGot: %#v", expected, logs)
+	}
+
+	if len(buffer.logs) != 0 {
+		t.Fatalf("expected empty buffer, got %d blocks", len(buffer.logs))
+	}
+}
+
+func TestLogBuffer_Removed(t *testing.T) {
+	buffer := newLogBuffer()
+	buffer.add(testLog(10, 0, 1))
+	buffer.add(testLog(10, 1, 2))
+
+	removed := testLog(10, 0, 1)
+	removed.Removed = true
+	buffer.add(removed)
+
+	logs := buffer.take(big.NewInt(10), big.NewInt(10))
+	expected := []types.Log{testLog(10, 1, 2)}
+	if !reflect.DeepEqual(logs, expected) {
+		t.Fatalf("unexpected logs.\n\tExpected: %#v\n\tGot: %#v", expected, logs)
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	eth "github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Time between sweeps for expired proposals
+var ProposalSweepInterval = time.Minute
+
+// Number of blocks before the latest block that are scanned for proposals when the sweeper starts
+var ProposalSweepLookback = big.NewInt(10000)
+
+// Maximum number of attempts to submit a cancellation
+const CancelRetryLimit = 3
+
+type proposalKey struct {
+	source   msg.ChainId
+	nonce    msg.Nonce
+	dataHash [32]byte
+}
+
+// trackedProposal is an active proposal found in the bridge logs
+type trackedProposal struct {
+	proposalKey
+	proposedBlock uint64
+	voted         bool // Whether this relayer's vote has been confirmed
+}
+
+// proposalTracker keeps the proposals found in ProposalEvent and ProposalVote logs until they are complete
+type proposalTracker struct {
+	proposals map[proposalKey]*trackedProposal
+}
+
+func newProposalTracker() *proposalTracker {
+	return &proposalTracker{proposals: make(map[proposalKey]*trackedProposal)}
+}
+
+// handleLog updates the tracked proposals. For a ProposalVote log, the tracked proposals the vote may be for are
+// returned if this relayer's vote on them has not been confirmed yet.
+func (t *proposalTracker) handleLog(log types.Log) []*trackedProposal {
+	if len(log.Topics) < 4 {
+		return nil
+	}
+	source := msg.ChainId(log.Topics[1].Big().Uint64())
+	nonce := msg.Nonce(log.Topics[2].Big().Uint64())
+	status := utils.ProposalStatus(log.Topics[3].Big().Uint64())
+
+	switch log.Topics[0] {
+	case utils.ProposalEvent.GetTopic():
+		if len(log.Data) < 64 {
+			return nil
+		}
+		key := proposalKey{source: source, nonce: nonce}
+		copy(key.dataHash[:], log.Data[32:64])
+
+		switch status {
+		case utils.Active:
+			t.proposals[key] = &trackedProposal{proposalKey: key, proposedBlock: log.BlockNumber}
+		case utils.Executed, utils.Cancelled:
+			delete(t.proposals, key)
+		}
+	case utils.ProposalVote.GetTopic():
+		// Votes don't include the data hash, any proposal for the deposit may have received it
+		var unconfirmed []*trackedProposal
+		for key, prop := range t.proposals {
+			if key.source == source && key.nonce == nonce && !prop.voted {
+				unconfirmed = append(unconfirmed, prop)
+			}
+		}
+		return unconfirmed
+	}
+	return nil
+}
+
+// expired returns the proposals this relayer voted on that were proposed more than expiry blocks before latest
+func (t *proposalTracker) expired(latest uint64, expiry uint64) []*trackedProposal {
+	var expired []*trackedProposal
+	for _, prop := range t.proposals {
+		if prop.voted && latest > prop.proposedBlock && latest-prop.proposedBlock > expiry {
+			expired = append(expired, prop)
+		}
+	}
+	return expired
+}
+
+func (t *proposalTracker) remove(key proposalKey) {
+	delete(t.proposals, key)
+}
+
+// sweepProposals periodically scans the bridge logs for proposals this relayer voted on, and cancels those that
+// are still active after the bridge's expiry
+func (w *writer) sweepProposals() {
+	latest, err := w.conn.LatestBlock()
+	for err != nil {
+		w.log.Error("Unable to fetch latest block for proposal sweeper", "err", err)
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(BlockRetryInterval):
+		}
+		latest, err = w.conn.LatestBlock()
+	}
+
+	tracker := newProposalTracker()
+	next := new(big.Int).Sub(latest, ProposalSweepLookback)
+	if next.Cmp(w.cfg.startBlock) == -1 {
+		next = new(big.Int).Set(w.cfg.startBlock)
+	}
+	w.log.Info("Starting proposal sweeper", "block", next)
+
+	for {
+		latest, err := w.conn.LatestBlock()
+		if err != nil {
+			w.log.Error("Unable to fetch latest block for proposal sweeper", "err", err)
+		} else {
+			next = w.scanProposals(tracker, next, latest)
+			w.cancelExpiredProposals(tracker, latest)
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(ProposalSweepInterval):
+		}
+	}
+}
+
+// scanProposals processes the ProposalEvent and ProposalVote logs from startBlock to endBlock (inclusive). Returns
+// the first block that has not been scanned.
+func (w *writer) scanProposals(tracker *proposalTracker, startBlock, endBlock *big.Int) *big.Int {
+	current := new(big.Int).Set(startBlock)
+	for current.Cmp(endBlock) <= 0 {
+		rangeEndBlock := rangeEnd(current, endBlock, big.NewInt(0), w.cfg.blockRange)
+		query := eth.FilterQuery{
+			FromBlock: current,
+			ToBlock:   rangeEndBlock,
+			Addresses: []ethcommon.Address{w.cfg.bridgeContract},
+			Topics:    [][]ethcommon.Hash{{utils.ProposalEvent.GetTopic(), utils.ProposalVote.GetTopic()}},
+		}
+		logs, err := w.conn.Client().FilterLogs(context.Background(), query)
+		if err != nil {
+			w.log.Warn("Unable to fetch proposal logs", "from", current, "to", rangeEndBlock, "err", err)
+			return current
+		}
+
+		for _, log := range logs {
+			for _, prop := range tracker.handleLog(log) {
+				prop.voted = w.hasVoted(prop.source, prop.nonce, prop.dataHash)
+			}
+		}
+		current = new(big.Int).Add(rangeEndBlock, big.NewInt(1))
+	}
+	return current
+}
+
+// cancelExpiredProposals cancels the tracked proposals that have expired, if this relayer is allowed to
+func (w *writer) cancelExpiredProposals(tracker *proposalTracker, latest *big.Int) {
+	expiry, err := w.bridgeContract.Expiry(w.conn.CallOpts())
+	if err != nil {
+		w.log.Warn("Unable to fetch proposal expiry", "err", err)
+		return
+	}
+
+	expired := tracker.expired(latest.Uint64(), expiry.Uint64())
+	if w.proposalMetrics != nil {
+		w.proposalMetrics.expired.Set(float64(len(expired)))
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	if !w.canCancel() {
+		w.log.Debug("Relayer is not allowed to cancel proposals", "expired", len(expired))
+		return
+	}
+
+	for _, prop := range expired {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		onChain, err := w.bridgeContract.GetProposal(w.conn.CallOpts(), uint8(prop.source), uint64(prop.nonce), prop.dataHash)
+		if err != nil {
+			w.transferLog(prop.source, prop.nonce).Warn("Failed to fetch proposal", "src", prop.source, "dst", w.cfg.id, "nonce", prop.nonce, "err", err)
+			continue
+		}
+		if utils.ProposalStatus(onChain.Status) != utils.Active {
+			// Passed proposals can still be executed
+			tracker.remove(prop.proposalKey)
+			continue
+		}
+		if new(big.Int).Sub(latest, onChain.ProposedBlock).Cmp(expiry) <= 0 {
+			continue
+		}
+
+		if w.cancelProposal(prop) {
+			tracker.remove(prop.proposalKey)
+		}
+	}
+}
+
+// canCancel returns true if the relayer has the admin or relayer role required to cancel proposals
+func (w *writer) canCancel() bool {
+	from := w.conn.Opts().From
+	isRelayer, err := w.bridgeContract.IsRelayer(w.conn.CallOpts(), from)
+	if err != nil {
+		w.log.Warn("Unable to check relayer role", "err", err)
+		return false
+	}
+	if isRelayer {
+		return true
+	}
+
+	adminRole, err := w.bridgeContract.DEFAULTADMINROLE(w.conn.CallOpts())
+	if err != nil {
+		w.log.Warn("Unable to fetch admin role", "err", err)
+		return false
+	}
+	isAdmin, err := w.bridgeContract.HasRole(w.conn.CallOpts(), adminRole, from)
+	if err != nil {
+		w.log.Warn("Unable to check admin role", "err", err)
+		return false
+	}
+	return isAdmin
+}
+
+// cancelProposal submits a cancellation for the expired proposal. Returns true if the proposal was cancelled.
+func (w *writer) cancelProposal(prop *trackedProposal) bool {
+	log := w.transferLog(prop.source, prop.nonce)
+
+	for i := 0; i < CancelRetryLimit; i++ {
+		err := w.conn.LockAndUpdateOpts()
+		if err != nil {
+			log.Error("Failed to update tx opts", "err", err)
+			continue
+		}
+
+		tx, err := w.bridgeContract.CancelProposal(w.conn.Opts(), uint8(prop.source), uint64(prop.nonce), prop.dataHash)
+		w.conn.UnlockOptsAfterSend(err)
+		if err != nil {
+			log.Warn("Proposal cancellation failed", "src", prop.source, "dst", w.cfg.id, "nonce", prop.nonce, "err", err)
+			time.Sleep(TxRetryInterval)
+		} else {
+			log.Info("Submitted proposal cancellation", "tx", tx.Hash(), "src", prop.source, "dst", w.cfg.id, "nonce", prop.nonce, "dataHash", ethcommon.Hash(prop.dataHash))
+			receipt, err := w.txTracker.wait(tx)
+			if err == nil && receipt.Status == types.ReceiptStatusSuccessful {
+				log.Info("Expired proposal cancelled", "tx", receipt.TxHash, "src", prop.source, "dst", w.cfg.id, "nonce", prop.nonce)
+				if w.proposalMetrics != nil {
+					w.proposalMetrics.cancelled.Inc()
+				}
+				return true
+			}
+			log.Warn("Proposal cancellation not confirmed", "tx", tx.Hash(), "src", prop.source, "dst", w.cfg.id, "nonce", prop.nonce, "err", err)
+		}
+
+		// Another relayer may have cancelled it, or it may have been executed
+		if w.proposalIsFinalized(prop.source, prop.nonce, prop.dataHash) {
+			return true
+		}
+	}
+
+	if w.proposalMetrics != nil {
+		w.proposalMetrics.cancelFailures.Inc()
+	}
+	return false
+}
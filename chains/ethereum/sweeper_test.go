@@ -0,0 +1,80 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func proposalLog(sig utils.EventSig, source msg.ChainId, nonce msg.Nonce, status utils.ProposalStatus, dataHash [32]byte, block uint64) types.Log {
+	data := make([]byte, 32)
+	if sig == utils.ProposalEvent {
+		data = append(data, dataHash[:]...)
+	}
+	return types.Log{
+		Topics: []ethcommon.Hash{
+			sig.GetTopic(),
+			ethcommon.BigToHash(big.NewInt(int64(source))),
+			ethcommon.BigToHash(big.NewInt(int64(nonce))),
+			ethcommon.BigToHash(big.NewInt(int64(status))),
+		},
+		Data:        data,
+		BlockNumber: block,
+	}
+}
+
+func TestProposalTracker(t *testing.T) {
+	tracker := newProposalTracker()
+	hashA := [32]byte{1}
+	hashB := [32]byte{2}
+
+	// Two competing proposals for the same deposit, and one for another deposit
+	tracker.handleLog(proposalLog(utils.ProposalEvent, 1, 5, utils.Active, hashA, 100))
+	tracker.handleLog(proposalLog(utils.ProposalEvent, 1, 5, utils.Active, hashB, 101))
+	tracker.handleLog(proposalLog(utils.ProposalEvent, 1, 6, utils.Active, hashA, 102))
+	if len(tracker.proposals) != 3 {
+		t.Fatalf("expected 3 proposals, got %d", len(tracker.proposals))
+	}
+
+	unconfirmed := tracker.handleLog(proposalLog(utils.ProposalVote, 1, 5, utils.Active, [32]byte{}, 103))
+	if len(unconfirmed) != 2 {
+		t.Fatalf("expected 2 unconfirmed proposals, got %d", len(unconfirmed))
+	}
+	for _, prop := range unconfirmed {
+		if prop.dataHash == hashA {
+			prop.voted = true
+		}
+	}
+
+	// Proposals that were voted on are no longer returned for later votes
+	unconfirmed = tracker.handleLog(proposalLog(utils.ProposalVote, 1, 5, utils.Active, [32]byte{}, 104))
+	if len(unconfirmed) != 1 || unconfirmed[0].dataHash != hashB {
+		t.Fatalf("expected unconfirmed proposal %x, got %v", hashB, unconfirmed)
+	}
+
+	// Only proposals voted on are expired
+	expired := tracker.expired(200, 50)
+	if len(expired) != 1 || expired[0].dataHash != hashA || expired[0].nonce != 5 {
+		t.Fatalf("expected expired proposal %x, got %v", hashA, expired)
+	}
+	if expired := tracker.expired(150, 50); len(expired) != 0 {
+		t.Fatalf("expected no expired proposals, got %v", expired)
+	}
+
+	// Executed and cancelled proposals are no longer tracked
+	tracker.handleLog(proposalLog(utils.ProposalEvent, 1, 5, utils.Executed, hashA, 105))
+	tracker.handleLog(proposalLog(utils.ProposalEvent, 1, 5, utils.Cancelled, hashB, 106))
+	if len(tracker.proposals) != 1 {
+		t.Fatalf("expected 1 proposal, got %d", len(tracker.proposals))
+	}
+	if expired := tracker.expired(200, 50); len(expired) != 0 {
+		t.Fatalf("expected no expired proposals, got %v", expired)
+	}
+}
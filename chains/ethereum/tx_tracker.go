@@ -0,0 +1,119 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Maximum number of times to poll for the receipt of a submitted transaction
+const TxReceiptPollLimit = 100
+
+var ErrTxNotMined = errors.New("transaction not mined")
+var ErrTrackerStopped = errors.New("transaction tracker stopped")
+
+// txBackend provides the chain access required by the txTracker
+type txBackend interface {
+	LatestBlock() (*big.Int, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	ReplaceTransaction(tx *types.Transaction) (*types.Transaction, error)
+}
+
+// connTxBackend adapts a Connection to a txBackend
+type connTxBackend struct {
+	Connection
+}
+
+func (b connTxBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return b.Client().TransactionReceipt(ctx, txHash)
+}
+
+// txTracker watches submitted transactions until they are mined. A transaction that has not been mined
+// after stuckBlocks blocks is replaced by one with the same nonce and higher fees. Replacement is disabled
+// if stuckBlocks is zero.
+type txTracker struct {
+	backend     txBackend
+	stuckBlocks *big.Int
+	interval    time.Duration
+	log         log15.Logger
+	stop        <-chan int
+}
+
+func newTxTracker(backend txBackend, stuckBlocks uint64, log log15.Logger, stop <-chan int) *txTracker {
+	return &txTracker{
+		backend:     backend,
+		stuckBlocks: new(big.Int).SetUint64(stuckBlocks),
+		interval:    BlockRetryInterval,
+		log:         log,
+		stop:        stop,
+	}
+}
+
+// track watches tx in the background until it, or one of its replacements, is mined and calls done with the
+// receipt, or with the error if it was not mined. done is not called once the tracker is stopped.
+func (t *txTracker) track(tx *types.Transaction, done func(*types.Receipt, error)) {
+	go func() {
+		receipt, err := t.wait(tx)
+		if errors.Is(err, ErrTrackerStopped) {
+			return
+		}
+		done(receipt, err)
+	}()
+}
+
+// wait blocks until tx, or one of its replacements, is mined and returns the receipt
+func (t *txTracker) wait(tx *types.Transaction) (*types.Receipt, error) {
+	hashes := []common.Hash{tx.Hash()}
+
+	submitted, err := t.backend.LatestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < TxReceiptPollLimit; i++ {
+		select {
+		case <-t.stop:
+			return nil, ErrTrackerStopped
+		default:
+			// Any of the transactions sharing the nonce may have been mined
+			for _, hash := range hashes {
+				receipt, err := t.backend.TransactionReceipt(context.Background(), hash)
+				if err == nil {
+					return receipt, nil
+				} else if !errors.Is(err, eth.NotFound) {
+					t.log.Debug("Failed to fetch transaction receipt", "tx", hash, "err", err)
+				}
+			}
+
+			if t.stuckBlocks.Sign() == 1 {
+				latest, err := t.backend.LatestBlock()
+				if err != nil {
+					t.log.Warn("Unable to fetch latest block", "err", err)
+				} else if new(big.Int).Sub(latest, submitted).Cmp(t.stuckBlocks) >= 0 {
+					replacement, err := t.backend.ReplaceTransaction(tx)
+					if err != nil {
+						t.log.Warn("Failed to replace stuck transaction", "tx", tx.Hash(), "nonce", tx.Nonce(), "err", err)
+					} else {
+						t.log.Info("Replaced stuck transaction", "tx", tx.Hash(), "replacement", replacement.Hash(), "nonce", tx.Nonce(), "gasPrice", replacement.GasPrice(), "gasTipCap", replacement.GasTipCap())
+						hashes = append(hashes, replacement.Hash())
+						tx = replacement
+					}
+					// Allow the replacement, or the node's pool, another stuckBlocks blocks
+					submitted = latest
+				}
+			}
+
+			time.Sleep(t.interval)
+		}
+	}
+	return nil, ErrTxNotMined
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeTxBackend advances one block each time the latest block is queried. Replacements are mined immediately.
+type fakeTxBackend struct {
+	block        int64
+	receipts     map[common.Hash]*types.Receipt
+	replacements []*types.Transaction
+}
+
+func (b *fakeTxBackend) LatestBlock() (*big.Int, error) {
+	b.block++
+	return big.NewInt(b.block), nil
+}
+
+func (b *fakeTxBackend) TransactionReceipt(_ context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if receipt, ok := b.receipts[txHash]; ok {
+		return receipt, nil
+	}
+	return nil, eth.NotFound
+}
+
+func (b *fakeTxBackend) ReplaceTransaction(tx *types.Transaction) (*types.Transaction, error) {
+	replacement := types.NewTransaction(tx.Nonce(), common.Address{}, big.NewInt(0), tx.Gas(), new(big.Int).Add(tx.GasPrice(), big.NewInt(1)), nil)
+	b.replacements = append(b.replacements, replacement)
+	b.receipts[replacement.Hash()] = &types.Receipt{TxHash: replacement.Hash(), Status: types.ReceiptStatusSuccessful}
+	return replacement, nil
+}
+
+func newTestTxTracker(backend txBackend, stuckBlocks uint64, stop <-chan int) *txTracker {
+	tracker := newTxTracker(backend, stuckBlocks, newTestLogger("tracker"), stop)
+	tracker.interval = 0
+	return tracker
+}
+
+func TestTxTracker_Mined(t *testing.T) {
+	tx := types.NewTransaction(1, common.Address{}, big.NewInt(0), 21000, big.NewInt(10), nil)
+	backend := &fakeTxBackend{receipts: map[common.Hash]*types.Receipt{
+		tx.Hash(): {TxHash: tx.Hash(), Status: types.ReceiptStatusFailed},
+	}}
+
+	receipt, err := newTestTxTracker(backend, 3, make(chan int)).wait(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusFailed {
+		t.Fatalf("expected failed receipt status, got %d", receipt.Status)
+	}
+	if len(backend.replacements) != 0 {
+		t.Fatalf("expected no replacements, got %d", len(backend.replacements))
+	}
+}
+
+func TestTxTracker_ReplaceStuck(t *testing.T) {
+	tx := types.NewTransaction(1, common.Address{}, big.NewInt(0), 21000, big.NewInt(10), nil)
+	backend := &fakeTxBackend{receipts: map[common.Hash]*types.Receipt{}}
+
+	receipt, err := newTestTxTracker(backend, 3, make(chan int)).wait(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.replacements) != 1 {
+		t.Fatalf("expected 1 replacement, got %d", len(backend.replacements))
+	}
+	if receipt.TxHash != backend.replacements[0].Hash() {
+		t.Fatalf("expected receipt of replacement %s, got %s", backend.replacements[0].Hash(), receipt.TxHash)
+	}
+	if backend.replacements[0].Nonce() != tx.Nonce() {
+		t.Fatalf("replacement nonce %d does not match %d", backend.replacements[0].Nonce(), tx.Nonce())
+	}
+}
+
+func TestTxTracker_ReplacementDisabled(t *testing.T) {
+	tx := types.NewTransaction(1, common.Address{}, big.NewInt(0), 21000, big.NewInt(10), nil)
+	backend := &fakeTxBackend{receipts: map[common.Hash]*types.Receipt{}}
+
+	_, err := newTestTxTracker(backend, 0, make(chan int)).wait(tx)
+	if err != ErrTxNotMined {
+		t.Fatalf("expected %s, got %v", ErrTxNotMined, err)
+	}
+	if len(backend.replacements) != 0 {
+		t.Fatalf("expected no replacements, got %d", len(backend.replacements))
+	}
+}
+
+func TestTxTracker_Stop(t *testing.T) {
+	tx := types.NewTransaction(1, common.Address{}, big.NewInt(0), 21000, big.NewInt(10), nil)
+	backend := &fakeTxBackend{receipts: map[common.Hash]*types.Receipt{}}
+
+	stop := make(chan int)
+	close(stop)
+
+	_, err := newTestTxTracker(backend, 3, stop).wait(tx)
+	if err != ErrTrackerStopped {
+		t.Fatalf("expected %s, got %v", ErrTrackerStopped, err)
+	}
+}
+
+func TestTxTracker_Track(t *testing.T) {
+	tx := types.NewTransaction(1, common.Address{}, big.NewInt(0), 21000, big.NewInt(10), nil)
+	backend := &fakeTxBackend{receipts: map[common.Hash]*types.Receipt{}}
+
+	done := make(chan error)
+	newTestTxTracker(backend, 0, make(chan int)).track(tx, func(_ *types.Receipt, err error) {
+		done <- err
+	})
+
+	err := <-done
+	if err != ErrTxNotMined {
+		t.Fatalf("expected %s, got %v", ErrTxNotMined, err)
+	}
+}
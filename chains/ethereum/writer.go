@@ -4,7 +4,16 @@
 package ethereum
 
 import (
+	"time"
+
 	"github.com/ChainSafe/ChainBridge/bindings/Bridge"
+	"github.com/ChainSafe/ChainBridge/chains"
+	"github.com/ChainSafe/ChainBridge/chains/approval"
+	"github.com/ChainSafe/ChainBridge/chains/balance"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/ChainBridge/chains/ratelimit"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
+	"github.com/ChainSafe/ChainBridge/chains/writermetrics"
 	"github.com/ChainSafe/chainbridge-utils/core"
 	metrics "github.com/ChainSafe/chainbridge-utils/metrics/types"
 	"github.com/ChainSafe/chainbridge-utils/msg"
@@ -18,6 +27,9 @@ var PassedStatus uint8 = 2
 var TransferredStatus uint8 = 3
 var CancelledStatus uint8 = 4
 
+// Time between checks for messages released from the quarantine or given an approval decision
+var HeldMessagePollInterval = time.Second * 30
+
 type writer struct {
 	cfg             Config
 	conn            Connection
@@ -27,22 +39,44 @@ type writer struct {
 	sysErr          chan<- error // Reports fatal error to core
 	metrics         *metrics.ChainMetrics
 	forwarderClient ForwarderClient
+	outbox          *outbox.Outbox
+	txTracker       *txTracker
+	proposalMetrics *proposalMetrics
+	scheduler       *executionScheduler
+	rateLimit       *ratelimit.Guard // Holds messages over the volume caps, may be nil
+	approvals       *approval.Store  // Holds high-value transfers until approved, may be nil
+	transfers       *transfers.Tracker
+	writerMetrics   *writermetrics.Metrics
+	balanceMonitor  *balance.Monitor // Checks the relayer balance against the thresholds, may be nil
+	relayers        relayerCache
 }
 
 // NewWriter creates and returns writer
 func NewWriter(conn Connection, cfg *Config, log log15.Logger, stop <-chan int, sysErr chan<- error, m *metrics.ChainMetrics) *writer {
 	return &writer{
-		cfg:     *cfg,
-		conn:    conn,
-		log:     log,
-		stop:    stop,
-		sysErr:  sysErr,
-		metrics: m,
+		cfg:       *cfg,
+		conn:      conn,
+		log:       log,
+		stop:      stop,
+		sysErr:    sysErr,
+		metrics:   m,
+		txTracker: newTxTracker(connTxBackend{conn}, cfg.stuckTxBlocks, log, stop),
+		scheduler: newExecutionScheduler(),
 	}
 }
 
 func (w *writer) start() error {
 	w.log.Debug("Starting ethereum writer...")
+	go w.runScheduler()
+	if w.cfg.cancelExpired {
+		go w.sweepProposals()
+	}
+	if w.rateLimit != nil || w.approvals != nil {
+		go w.watchHeldMessages()
+	}
+	if w.writerMetrics != nil || w.balanceMonitor != nil {
+		go w.reportAccount()
+	}
 	return nil
 }
 
@@ -51,15 +85,120 @@ func (w *writer) setContract(bridge *Bridge.Bridge) {
 	w.bridgeContract = bridge
 }
 
+// setScheduler replaces the in-memory execution scheduler, must be called before start
+func (w *writer) setScheduler(s *executionScheduler) {
+	w.scheduler = s
+}
+
+// setProposalMetrics enables reporting of cancelled proposals
+func (w *writer) setProposalMetrics(m *proposalMetrics) {
+	w.proposalMetrics = m
+}
+
+// setWriterMetrics enables reporting of proposal outcomes, latencies and the relayer account
+func (w *writer) setWriterMetrics(m *writermetrics.Metrics) {
+	w.writerMetrics = m
+}
+
+// setRateLimit sets the guard enforcing the volume caps of outbound transfers
+func (w *writer) setRateLimit(g *ratelimit.Guard) {
+	w.rateLimit = g
+}
+
+// setApprovals sets the store of transfers that require approval before they are voted on
+func (w *writer) setApprovals(s *approval.Store) {
+	w.approvals = s
+}
+
+// setBalanceMonitor sets the monitor the relayer balance is checked with, and voting paused by
+func (w *writer) setBalanceMonitor(m *balance.Monitor) {
+	w.balanceMonitor = m
+}
+
 // setForwarder adds the forwarderClient to the writer
 func (w *writer) setForwarder(forwarderClient ForwarderClient) {
 	w.forwarderClient = forwarderClient
 }
 
+// setOutbox adds the outbox used to acknowledge delivered messages
+func (w *writer) setOutbox(ob *outbox.Outbox) {
+	w.outbox = ob
+}
+
+// setTransfers sets the index votes and executions are recorded in
+func (w *writer) setTransfers(t *transfers.Tracker) {
+	w.transfers = t
+}
+
+// acknowledge marks the message as delivered in the outbox, it will not be replayed after a restart
+func (w *writer) acknowledge(m msg.Message) {
+	if w.outbox == nil {
+		return
+	}
+
+	err := w.outbox.Ack(m)
+	if err != nil {
+		chains.TransferLogger(w.log, m).Error("Failed to acknowledge message in outbox", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+	}
+}
+
+// track applies the update to the transfer of the message in the index. Returns the updated transfer, or nil if
+// transfers are not tracked.
+func (w *writer) track(m msg.Message, update transfers.Update) *transfers.Transfer {
+	if w.transfers == nil {
+		return nil
+	}
+
+	t, err := w.transfers.Update(m, update)
+	if err != nil {
+		chains.TransferLogger(w.log, m).Warn("Failed to update transfer index", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		return nil
+	}
+	return &t
+}
+
+// trackOutcome records whether a finalized proposal was executed or cancelled in the index
+func (w *writer) trackOutcome(m msg.Message, dataHash [32]byte) {
+	if w.transfers == nil {
+		return
+	}
+
+	prop, err := w.bridgeContract.GetProposal(w.conn.CallOpts(), uint8(m.Source), uint64(m.DepositNonce), dataHash)
+	if err != nil {
+		chains.TransferLogger(w.log, m).Warn("Failed to get proposal outcome", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		return
+	}
+	if prop.Status == TransferredStatus {
+		w.track(m, transfers.Execute(""))
+	} else if prop.Status == CancelledStatus {
+		w.track(m, transfers.Cancel())
+	}
+}
+
+// transferLog returns the logger of the writer with the correlation ID of the transfer from src with the nonce
+func (w *writer) transferLog(src msg.ChainId, nonce msg.Nonce) log15.Logger {
+	return w.log.New(chains.TransferKey, chains.TransferId(src, w.cfg.id, nonce))
+}
+
+// isCancelled returns true if the message was cancelled because its deposit was orphaned by a reorg
+func (w *writer) isCancelled(m msg.Message) bool {
+	return w.outbox != nil && w.outbox.IsCancelled(m)
+}
+
 // ResolveMessage handles any given message based on type
 // A bool is returned to indicate failure/success, this should be ignored except for within tests.
 func (w *writer) ResolveMessage(m msg.Message) bool {
-	w.log.Info("Attempting to resolve message", "type", m.Type, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "rId", m.ResourceId.Hex())
+	log := chains.TransferLogger(w.log, m)
+	log.Info("Attempting to resolve message", "type", m.Type, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "rId", m.ResourceId.Hex())
+
+	if w.isCancelled(m) {
+		log.Warn("Message cancelled, deposit was orphaned by a reorg", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		return false
+	}
+
+	if w.votingPaused(m) || !w.approved(m) {
+		return false
+	}
 
 	switch m.Type {
 	case msg.FungibleTransfer:
@@ -69,7 +208,110 @@ func (w *writer) ResolveMessage(m msg.Message) bool {
 	case msg.GenericTransfer:
 		return w.createGenericDepositProposal(m)
 	default:
-		w.log.Error("Unknown message type received", "type", m.Type)
+		log.Error("Unknown message type received", "type", m.Type, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		return false
+	}
+}
+
+// votingPaused returns true if voting is paused because the relayer balance is critical. The message is held and
+// resolved once the account is topped up.
+func (w *writer) votingPaused(m msg.Message) bool {
+	if w.balanceMonitor == nil || !w.balanceMonitor.Hold(m) {
+		return false
+	}
+	chains.TransferLogger(w.log, m).Warn("Voting paused, relayer balance below critical threshold", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+	return true
+}
+
+// withinRateLimit returns true if the message may be voted on. It is only checked once the relayer is about to vote,
+// so proposals that are complete or already voted on do not count towards the volume. Messages over the volume cap
+// are held in the quarantine, and are not acknowledged so they remain in the outbox.
+func (w *writer) withinRateLimit(m msg.Message) bool {
+	log := chains.TransferLogger(w.log, m)
+
+	if w.rateLimit == nil {
+		return true
+	}
+
+	ok, err := w.rateLimit.Check(m)
+	if err != nil {
+		log.Error("Failed to check rate limit, holding message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 		return false
 	}
+	if !ok {
+		log.Warn("Message held in quarantine, rate limit exceeded", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "rId", m.ResourceId.Hex())
+	}
+	return ok
+}
+
+// resolveRateLimit removes the message from the quarantine once the relayer no longer needs to vote on it, otherwise
+// a released message would be resolved again and again.
+func (w *writer) resolveRateLimit(m msg.Message) {
+	if w.rateLimit == nil {
+		return
+	}
+
+	err := w.rateLimit.Resolve(m)
+	if err != nil {
+		chains.TransferLogger(w.log, m).Warn("Failed to remove message from quarantine", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+	}
+}
+
+// approved returns true if the message does not require approval or was approved. A rejected message is
+// acknowledged without voting, so it is not replayed.
+func (w *writer) approved(m msg.Message) bool {
+	log := chains.TransferLogger(w.log, m)
+
+	if w.approvals == nil {
+		return true
+	}
+
+	status, err := w.approvals.Check(m)
+	if err != nil {
+		log.Error("Failed to check approval, holding message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		return false
+	}
+	switch status {
+	case approval.Pending:
+		log.Warn("Transfer awaiting approval", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "rId", m.ResourceId.Hex())
+		return false
+	case approval.Rejected:
+		log.Warn("Transfer rejected by operator, not voting", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		w.acknowledge(m)
+		return false
+	}
+	return true
+}
+
+// watchHeldMessages periodically resolves the messages an operator released from the quarantine, approved or
+// rejected
+func (w *writer) watchHeldMessages() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(HeldMessagePollInterval):
+		}
+
+		var msgs []msg.Message
+		if w.rateLimit != nil {
+			released, err := w.rateLimit.Released()
+			if err != nil {
+				w.log.Error("Failed to read quarantine", "err", err)
+			}
+			msgs = append(msgs, released...)
+		}
+		if w.approvals != nil {
+			decided, err := w.approvals.Decided()
+			if err != nil {
+				w.log.Error("Failed to read approvals", "err", err)
+			}
+			msgs = append(msgs, decided...)
+		}
+
+		for _, m := range msgs {
+			chains.TransferLogger(w.log, m).Info("Resolving held message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+			w.ResolveMessage(m)
+		}
+	}
 }
@@ -4,17 +4,20 @@
 package ethereum
 
 import (
-	"context"
 	"errors"
 	"math/big"
 	"time"
 
+	"github.com/ChainSafe/ChainBridge/chains"
+	"github.com/ChainSafe/ChainBridge/chains/balance"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
+	"github.com/ChainSafe/ChainBridge/chains/writermetrics"
 	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
 	"github.com/ChainSafe/chainbridge-utils/msg"
-	log "github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
-// Number of blocks to wait for an finalization event
+// Number of times to check whether a vote has landed
 const ExecuteBlockWatchLimit = 100
 
 // Time between retrying a failed tx
@@ -33,7 +36,7 @@ var ErrFatalQuery = errors.New("query of chain state failed")
 func (w *writer) proposalIsComplete(srcId msg.ChainId, nonce msg.Nonce, dataHash [32]byte) bool {
 	prop, err := w.bridgeContract.GetProposal(w.conn.CallOpts(), uint8(srcId), uint64(nonce), dataHash)
 	if err != nil {
-		w.log.Error("Failed to check proposal existence", "err", err)
+		w.transferLog(srcId, nonce).Error("Failed to check proposal existence", "src", srcId, "dst", w.cfg.id, "nonce", nonce, "err", err)
 		return false
 	}
 	return prop.Status == PassedStatus || prop.Status == TransferredStatus || prop.Status == CancelledStatus
@@ -43,7 +46,7 @@ func (w *writer) proposalIsComplete(srcId msg.ChainId, nonce msg.Nonce, dataHash
 func (w *writer) proposalIsFinalized(srcId msg.ChainId, nonce msg.Nonce, dataHash [32]byte) bool {
 	prop, err := w.bridgeContract.GetProposal(w.conn.CallOpts(), uint8(srcId), uint64(nonce), dataHash)
 	if err != nil {
-		w.log.Error("Failed to check proposal existence", "err", err)
+		w.transferLog(srcId, nonce).Error("Failed to check proposal existence", "src", srcId, "dst", w.cfg.id, "nonce", nonce, "err", err)
 		return false
 	}
 	return prop.Status == TransferredStatus || prop.Status == CancelledStatus // Transferred (3)
@@ -52,7 +55,7 @@ func (w *writer) proposalIsFinalized(srcId msg.ChainId, nonce msg.Nonce, dataHas
 func (w *writer) proposalIsPassed(srcId msg.ChainId, nonce msg.Nonce, dataHash [32]byte) bool {
 	prop, err := w.bridgeContract.GetProposal(w.conn.CallOpts(), uint8(srcId), uint64(nonce), dataHash)
 	if err != nil {
-		w.log.Error("Failed to check proposal existence", "err", err)
+		w.transferLog(srcId, nonce).Error("Failed to check proposal existence", "src", srcId, "dst", w.cfg.id, "nonce", nonce, "err", err)
 		return false
 	}
 	return prop.Status == PassedStatus
@@ -62,7 +65,7 @@ func (w *writer) proposalIsPassed(srcId msg.ChainId, nonce msg.Nonce, dataHash [
 func (w *writer) hasVoted(srcId msg.ChainId, nonce msg.Nonce, dataHash [32]byte) bool {
 	hasVoted, err := w.bridgeContract.HasVotedOnProposal(w.conn.CallOpts(), utils.IDAndNonce(srcId, nonce), dataHash, w.conn.Opts().From)
 	if err != nil {
-		w.log.Error("Failed to check proposal existence", "err", err)
+		w.transferLog(srcId, nonce).Error("Failed to check proposal existence", "src", srcId, "dst", w.cfg.id, "nonce", nonce, "err", err)
 		return false
 	}
 
@@ -70,15 +73,23 @@ func (w *writer) hasVoted(srcId msg.ChainId, nonce msg.Nonce, dataHash [32]byte)
 }
 
 func (w *writer) shouldVote(m msg.Message, dataHash [32]byte) bool {
+	log := chains.TransferLogger(w.log, m)
+
 	// Check if proposal has passed and skip if Passed or Transferred
 	if w.proposalIsComplete(m.Source, m.DepositNonce, dataHash) {
-		w.log.Info("Proposal complete, not voting", "src", m.Source, "nonce", m.DepositNonce)
+		log.Info("Proposal complete, not voting", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		if w.writerMetrics != nil {
+			w.writerMetrics.Skipped(m.ResourceId, writermetrics.SkippedComplete)
+		}
 		return false
 	}
 
 	// Check if relayer has previously voted
 	if w.hasVoted(m.Source, m.DepositNonce, dataHash) {
-		w.log.Info("Relayer has already voted, not voting", "src", m.Source, "nonce", m.DepositNonce)
+		log.Info("Relayer has already voted, not voting", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		if w.writerMetrics != nil {
+			w.writerMetrics.Skipped(m.ResourceId, writermetrics.SkippedVoted)
+		}
 		return false
 	}
 
@@ -88,73 +99,104 @@ func (w *writer) shouldVote(m msg.Message, dataHash [32]byte) bool {
 // createErc20Proposal creates an Erc20 proposal.
 // Returns true if the proposal is successfully created or is complete
 func (w *writer) createErc20Proposal(m msg.Message) bool {
-	w.log.Info("Creating erc20 proposal", "src", m.Source, "nonce", m.DepositNonce)
+	log := chains.TransferLogger(w.log, m)
+	log.Info("Creating erc20 proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
 
 	data := ConstructErc20ProposalData(m.Payload[0].([]byte), m.Payload[1].([]byte))
 	dataHash := utils.Hash(append(w.cfg.erc20HandlerContract.Bytes(), data...))
 
 	if !w.shouldVote(m, dataHash) {
+		// Our vote, or enough votes from other relayers, have already landed
+		w.acknowledge(m)
+		w.resolveRateLimit(m)
+
 		if w.proposalIsPassed(m.Source, m.DepositNonce, dataHash) {
 			// We should not vote for this proposal but it is ready to be executed
-			w.executeProposal(m, data, dataHash)
-			return true
+			return w.executePassed(m, data, dataHash)
+		} else if !w.proposalIsComplete(m.Source, m.DepositNonce, dataHash) {
+			// Our vote has landed, execute the proposal once it passes
+			w.scheduleExecutionFromLatest(m, data, dataHash)
+			return false
 		} else {
+			w.trackOutcome(m, dataHash)
 			return false
 		}
 	}
 
+	if !w.withinRateLimit(m) {
+		return false
+	}
+
 	// Capture latest block so when know where to watch from
 	latestBlock, err := w.conn.LatestBlock()
 	if err != nil {
-		w.log.Error("Unable to fetch latest block", "err", err)
+		log.Error("Unable to fetch latest block", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 		return false
 	}
 
-	// watch for execution event
-	go w.watchThenExecute(m, data, dataHash, latestBlock)
+	// execute once the proposal passes
+	w.scheduleExecution(m, data, dataHash, latestBlock)
 
 	w.voteProposal(m, dataHash)
 
+	go w.confirmVote(m, dataHash)
+
 	return true
 }
 
 // createErc721Proposal creates an Erc721 proposal.
 // Returns true if the proposal is succesfully created or is complete
 func (w *writer) createErc721Proposal(m msg.Message) bool {
-	w.log.Info("Creating erc721 proposal", "src", m.Source, "nonce", m.DepositNonce)
+	log := chains.TransferLogger(w.log, m)
+	log.Info("Creating erc721 proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
 
 	data := ConstructErc721ProposalData(m.Payload[0].([]byte), m.Payload[1].([]byte), m.Payload[2].([]byte))
 	dataHash := utils.Hash(append(w.cfg.erc721HandlerContract.Bytes(), data...))
 
 	if !w.shouldVote(m, dataHash) {
+		// Our vote, or enough votes from other relayers, have already landed
+		w.acknowledge(m)
+		w.resolveRateLimit(m)
+
 		if w.proposalIsPassed(m.Source, m.DepositNonce, dataHash) {
 			// We should not vote for this proposal but it is ready to be executed
-			w.executeProposal(m, data, dataHash)
-			return true
+			return w.executePassed(m, data, dataHash)
+		} else if !w.proposalIsComplete(m.Source, m.DepositNonce, dataHash) {
+			// Our vote has landed, execute the proposal once it passes
+			w.scheduleExecutionFromLatest(m, data, dataHash)
+			return false
 		} else {
+			w.trackOutcome(m, dataHash)
 			return false
 		}
 	}
 
+	if !w.withinRateLimit(m) {
+		return false
+	}
+
 	// Capture latest block so we know where to watch from
 	latestBlock, err := w.conn.LatestBlock()
 	if err != nil {
-		w.log.Error("Unable to fetch latest block", "err", err)
+		log.Error("Unable to fetch latest block", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 		return false
 	}
 
-	// watch for execution event
-	go w.watchThenExecute(m, data, dataHash, latestBlock)
+	// execute once the proposal passes
+	w.scheduleExecution(m, data, dataHash, latestBlock)
 
 	w.voteProposal(m, dataHash)
 
+	go w.confirmVote(m, dataHash)
+
 	return true
 }
 
 // createGenericDepositProposal creates a generic proposal
 // returns true if the proposal is complete or is succesfully created
 func (w *writer) createGenericDepositProposal(m msg.Message) bool {
-	w.log.Info("Creating generic proposal", "src", m.Source, "nonce", m.DepositNonce)
+	log := chains.TransferLogger(w.log, m)
+	log.Info("Creating generic proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
 
 	metadata := m.Payload[0].([]byte)
 	data := ConstructGenericProposalData(metadata)
@@ -162,97 +204,123 @@ func (w *writer) createGenericDepositProposal(m msg.Message) bool {
 	dataHash := utils.Hash(toHash)
 
 	if !w.shouldVote(m, dataHash) {
+		// Our vote, or enough votes from other relayers, have already landed
+		w.acknowledge(m)
+		w.resolveRateLimit(m)
+
 		if w.proposalIsPassed(m.Source, m.DepositNonce, dataHash) {
 			// We should not vote for this proposal but it is ready to be executed
-			w.executeProposal(m, data, dataHash)
-			return true
+			return w.executePassed(m, data, dataHash)
+		} else if !w.proposalIsComplete(m.Source, m.DepositNonce, dataHash) {
+			// Our vote has landed, execute the proposal once it passes
+			w.scheduleExecutionFromLatest(m, data, dataHash)
+			return false
 		} else {
+			w.trackOutcome(m, dataHash)
 			return false
 		}
 	}
 
+	if !w.withinRateLimit(m) {
+		return false
+	}
+
 	// Capture latest block so when know where to watch from
 	latestBlock, err := w.conn.LatestBlock()
 	if err != nil {
-		w.log.Error("Unable to fetch latest block", "err", err)
+		log.Error("Unable to fetch latest block", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 		return false
 	}
 
-	// watch for execution event
-	go w.watchThenExecute(m, data, dataHash, latestBlock)
+	// execute once the proposal passes
+	w.scheduleExecution(m, data, dataHash, latestBlock)
 
 	w.voteProposal(m, dataHash)
 
+	go w.confirmVote(m, dataHash)
+
 	return true
 }
 
-// watchThenExecute watches for the latest block and executes once the matching finalized event is found
-func (w *writer) watchThenExecute(m msg.Message, data []byte, dataHash [32]byte, latestBlock *big.Int) {
-	w.log.Info("Watching for finalization event", "src", m.Source, "nonce", m.DepositNonce)
+// confirmVote waits until the relayer's vote is recorded on chain, or the proposal is complete, then
+// acknowledges the message. Unconfirmed messages remain in the outbox and are replayed on restart.
+func (w *writer) confirmVote(m msg.Message, dataHash [32]byte) {
+	if w.outbox == nil {
+		return
+	}
 
-	// watching for the latest block, querying and matching the finalized event will be retried up to ExecuteBlockWatchLimit times
 	for i := 0; i < ExecuteBlockWatchLimit; i++ {
 		select {
 		case <-w.stop:
 			return
 		default:
-			// watch for the lastest block, retry up to BlockRetryLimit times
-			for waitRetrys := 0; waitRetrys < BlockRetryLimit; waitRetrys++ {
-				err := w.conn.WaitForBlock(latestBlock, w.cfg.blockConfirmations)
-				if err != nil {
-					w.log.Error("Waiting for block failed", "err", err)
-					// Exit if retries exceeded
-					if waitRetrys+1 == BlockRetryLimit {
-						w.log.Error("Waiting for block retries exceeded, shutting down")
-						w.sysErr <- ErrFatalQuery
-						return
-					}
-				} else {
-					break
-				}
-			}
-
-			// query for logs
-			query := buildQuery(w.cfg.bridgeContract, utils.ProposalEvent, latestBlock, latestBlock)
-			evts, err := w.conn.Client().FilterLogs(context.Background(), query)
-			if err != nil {
-				w.log.Error("Failed to fetch logs", "err", err)
+			if w.hasVoted(m.Source, m.DepositNonce, dataHash) || w.proposalIsComplete(m.Source, m.DepositNonce, dataHash) {
+				w.acknowledge(m)
 				return
 			}
+			time.Sleep(BlockRetryInterval)
+		}
+	}
+	chains.TransferLogger(w.log, m).Warn("Vote not confirmed on chain, message will be replayed on restart", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+}
 
-			// execute the proposal once we find the matching finalized event
-			for _, evt := range evts {
-				sourceId := evt.Topics[1].Big().Uint64()
-				depositNonce := evt.Topics[2].Big().Uint64()
-				status := evt.Topics[3].Big().Uint64()
+// trackTx watches the transaction, or a replacement of it, in the background so the writer can move on to the next
+// message. The gas used is reported for the kind of transaction and mined is called with the receipt, which may have
+// reverted. If the transaction is not mined the transfer is recorded as failed and mined is called with nil.
+func (w *writer) trackTx(tx *types.Transaction, m msg.Message, kind string, mined func(*types.Receipt)) {
+	log := chains.TransferLogger(w.log, m)
+
+	w.txTracker.track(tx, func(receipt *types.Receipt, err error) {
+		if err != nil {
+			log.Warn("Unable to confirm transaction", "tx", tx.Hash(), "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+			w.failed(m)
+			mined(nil)
+			return
+		}
 
-				if m.Source == msg.ChainId(sourceId) &&
-					m.DepositNonce.Big().Uint64() == depositNonce &&
-					utils.IsFinalized(uint8(status)) {
-					w.executeProposal(m, data, dataHash)
-					return
-				} else {
-					w.log.Trace("Ignoring event", "src",
-						sourceId, "msrc",
-						m.Source, "nonce",
-						depositNonce,
-						"mNonce",
-						m.DepositNonce.Big().Uint64(),
-						"status", status,
-						"mstatus", utils.IsFinalized(uint8(status)),
-					)
-				}
-			}
-			w.log.Trace("No finalization event found in current block", "block", latestBlock, "src", m.Source, "nonce", m.DepositNonce)
-			latestBlock = latestBlock.Add(latestBlock, big.NewInt(1))
+		log.Info("Transaction mined", "tx", receipt.TxHash, "status", receipt.Status, "block", receipt.BlockNumber, "gasUsed", receipt.GasUsed, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		if w.writerMetrics != nil {
+			w.writerMetrics.GasUsed(kind, receipt.GasUsed)
 		}
+		mined(receipt)
+	})
+}
+
+// voted records the vote of this relayer in the transfer index and reports its latency
+func (w *writer) voted(m msg.Message, tx string) {
+	t := w.track(m, transfers.Vote(tx))
+	if w.writerMetrics != nil {
+		w.writerMetrics.Voted(t)
+	}
+}
+
+// executed records the execution of the proposal by this relayer in the transfer index and metrics
+func (w *writer) executed(m msg.Message, tx string) {
+	t := w.track(m, transfers.Execute(tx))
+	if w.writerMetrics != nil {
+		w.writerMetrics.Executed(m.ResourceId, t)
+	}
+}
+
+// failed records that the proposal could not be voted on or executed in the transfer index and metrics
+func (w *writer) failed(m msg.Message) {
+	w.track(m, transfers.Fail())
+	if w.writerMetrics != nil {
+		w.writerMetrics.Failed(m.ResourceId)
 	}
-	log.Warn("Block watch limit exceeded, skipping execution", "source", m.Source, "dest", m.Destination, "nonce", m.DepositNonce)
 }
 
 // voteProposal submits a vote proposal
 // a vote proposal will try to be submitted up to the TxRetryLimit times
 func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
+	w.submitVote(m, dataHash, 0)
+}
+
+// submitVote submits a vote proposal, reverted is the number of previous votes on the proposal that reverted. A
+// reverted vote is submitted again by retryVote.
+func (w *writer) submitVote(m msg.Message, dataHash [32]byte, reverted int) {
+	log := chains.TransferLogger(w.log, m)
+
 	if w.conn.ItxClient() != nil && w.forwarderClient != nil {
 
 		for i := 0; i < ItxRetryLimit; i++ {
@@ -262,7 +330,7 @@ func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
 			default:
 				forwarderNonce, err := w.forwarderClient.LockAndNextNonce()
 				if err != nil {
-					w.log.Warn("Failed to get and lock forwarder nonce for vote proposal", "itxFailures", i, "err", err)
+					log.Warn("Failed to get and lock forwarder nonce for vote proposal", "itxFailures", i, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 					time.Sleep(TxRetryInterval)
 					i = ItxRetryLimit
 					continue
@@ -275,7 +343,7 @@ func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
 					dataHash,
 				)
 				if err != nil {
-					w.log.Error("Failed to pack data for vote proposal", "err", err)
+					log.Error("Failed to pack data for vote proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 					w.forwarderClient.UnlockAndSetNonce(nil)
 					i = ItxRetryLimit
 					continue
@@ -290,7 +358,7 @@ func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
 					*w.conn.Keypair(),
 				)
 				if err != nil {
-					w.log.Error("Failed to sign forwarder data for vote proposal", "err", err)
+					log.Error("Failed to sign forwarder data for vote proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 					w.forwarderClient.UnlockAndSetNonce(nil)
 					i = ItxRetryLimit
 					continue
@@ -300,7 +368,7 @@ func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
 				forwarderGas := uint(w.conn.Opts().GasLimit*64/63 + 100000)
 				signedTx, err := toSignedRelayTx(w.forwarderClient.ForwarderAddress().String(), signedData, forwarderGas, uint(w.forwarderClient.ChainId().Uint64()), w.conn.Keypair(), w.conn.ItxSchedule())
 				if err != nil {
-					w.log.Error("Failed to sign relay tx for vote proposal", "err", err)
+					log.Error("Failed to sign relay tx for vote proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 					w.forwarderClient.UnlockAndSetNonce(nil)
 					i = ItxRetryLimit
 					continue
@@ -309,9 +377,9 @@ func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
 				res, err := sendRelayTransaction(w.conn.ItxClient(), w.conn.Opts().Context, signedTx.tx, signedTx.sig)
 				if err != nil {
 					w.forwarderClient.UnlockAndSetNonce(nil)
-					w.log.Warn("Failed to send vote proposal to itx", "itxFailures", i, "err", err)
-					if err.Error() == "Insufficient funds." {
-						w.log.Error("Insufficient funds to send via ITX.")
+					log.Warn("Failed to send vote proposal to itx", "itxFailures", i, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+					if balance.IsInsufficientFunds(err) {
+						w.insufficientFunds(m)
 						i = ItxRetryLimit
 						continue
 					} else {
@@ -320,13 +388,14 @@ func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
 					}
 				} else {
 					w.forwarderClient.UnlockAndSetNonce(forwarderNonce)
-					w.log.Info("Submitted proposal vote to ITX", "relayTx", *res, "src", m.Source, "depositNonce", m.DepositNonce)
+					log.Info("Submitted proposal vote to ITX", "relayTx", *res, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+					w.voted(m, *res)
 					return
 				}
 			}
 		}
 
-		w.log.Error("ITX send failed, falling back to standard tx send", "itxFailures", ItxRetryLimit)
+		log.Error("ITX send failed, falling back to standard tx send", "itxFailures", ItxRetryLimit, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
 	}
 
 	for i := 0; i < TxRetryLimit; i++ {
@@ -334,10 +403,14 @@ func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
 		case <-w.stop:
 			return
 		default:
+			if w.isCancelled(m) {
+				log.Warn("Message cancelled, deposit was orphaned by a reorg, not voting", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+				return
+			}
 
 			err := w.conn.LockAndUpdateOpts()
 			if err != nil {
-				w.log.Error("Failed to update tx opts", "err", err)
+				log.Error("Failed to update tx opts", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 				continue
 			}
 			// These store the gas limit and price before a transaction is sent for logging in case of a failure
@@ -354,36 +427,71 @@ func (w *writer) voteProposal(m msg.Message, dataHash [32]byte) {
 				m.ResourceId,
 				dataHash,
 			)
-			w.conn.UnlockOpts()
+			w.conn.UnlockOptsAfterSend(err)
 
 			if err == nil {
-				w.log.Info("Submitted proposal vote", "tx", tx.Hash(), "src", m.Source, "depositNonce", m.DepositNonce, "gasPrice", tx.GasPrice().String())
+				log.Info("Submitted proposal vote", "tx", tx.Hash(), "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "gasPrice", tx.GasPrice().String())
 				if w.metrics != nil {
 					w.metrics.VotesSubmitted.Inc()
 				}
+				w.trackTx(tx, m, writermetrics.TxVote, func(receipt *types.Receipt) {
+					if receipt == nil {
+						return
+					} else if receipt.Status == types.ReceiptStatusFailed {
+						log.Warn("Vote transaction reverted", "tx", receipt.TxHash, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+						w.retryVote(m, dataHash, reverted+1)
+						return
+					}
+					w.voted(m, receipt.TxHash.Hex())
+				})
 				return
 			} else if err.Error() == ErrNonceTooLow.Error() || err.Error() == ErrTxUnderpriced.Error() {
-				w.log.Debug("Nonce too low, will retry")
+				log.Debug("Nonce too low, will retry", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+				time.Sleep(TxRetryInterval)
+			} else if balance.IsInsufficientFunds(err) {
+				w.insufficientFunds(m)
 				time.Sleep(TxRetryInterval)
 			} else {
-				w.log.Warn("Voting failed", "source", m.Source, "dest", m.Destination, "depositNonce", m.DepositNonce, "gasLimit", gasLimit, "gasPrice", gasPrice, "err", err)
+				log.Warn("Voting failed", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "gasLimit", gasLimit, "gasPrice", gasPrice, "err", err)
 				time.Sleep(TxRetryInterval)
 			}
 
 			// Verify proposal is still open for voting, otherwise no need to retry
 			if w.proposalIsComplete(m.Source, m.DepositNonce, dataHash) {
-				w.log.Info("Proposal voting complete on chain", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+				log.Info("Proposal voting complete on chain", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
 				return
 			}
 
 		}
 	}
-	w.log.Error("Submission of Vote transaction failed", "source", m.Source, "dest", m.Destination, "depositNonce", m.DepositNonce)
+	log.Error("Submission of Vote transaction failed", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+	w.failed(m)
 	w.sysErr <- ErrFatalTx
 }
 
-// executeProposal executes the proposal
-func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte) {
+// retryVote votes again after a vote reverted, unless the proposal is complete or the vote of this relayer was
+// counted. The transfer is recorded as failed once TxRetryLimit votes have reverted.
+func (w *writer) retryVote(m msg.Message, dataHash [32]byte, reverted int) {
+	log := chains.TransferLogger(w.log, m)
+	if w.proposalIsComplete(m.Source, m.DepositNonce, dataHash) || w.hasVoted(m.Source, m.DepositNonce, dataHash) {
+		log.Info("Proposal complete or vote counted, not voting again", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		return
+	}
+	if reverted >= TxRetryLimit {
+		log.Error("Vote reverted, retries exceeded", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "reverted", reverted)
+		w.failed(m)
+		return
+	}
+
+	log.Info("Voting again after reverted vote", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "reverted", reverted)
+	w.submitVote(m, dataHash, reverted)
+}
+
+// executeProposal executes the proposal. done is called with true once the execution is mined or the proposal was
+// finalized by another relayer, or false if the execution failed or could not be confirmed.
+func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte, done func(finalized bool)) {
+	log := chains.TransferLogger(w.log, m)
+
 	if w.conn.ItxClient() != nil && w.forwarderClient != nil {
 		for i := 0; i < ItxRetryLimit; i++ {
 			select {
@@ -392,7 +500,7 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 			default:
 				forwarderNonce, err := w.forwarderClient.LockAndNextNonce()
 				if err != nil {
-					w.log.Warn("Failed to get and lock forwarder nonce for proposal execution", "itxFailures", i, "err", err)
+					log.Warn("Failed to get and lock forwarder nonce for proposal execution", "itxFailures", i, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 					time.Sleep(TxRetryInterval)
 					continue
 				}
@@ -404,7 +512,7 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 					m.ResourceId,
 				)
 				if err != nil {
-					w.log.Error("Failed to pack data for proposal execution", "err", err)
+					log.Error("Failed to pack data for proposal execution", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 					w.forwarderClient.UnlockAndSetNonce(nil)
 					i = ItxRetryLimit
 					continue
@@ -419,7 +527,7 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 					*w.conn.Keypair(),
 				)
 				if err != nil {
-					w.log.Error("Failed to sign forwarder data for proposal execution", "err", err)
+					log.Error("Failed to sign forwarder data for proposal execution", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 					w.forwarderClient.UnlockAndSetNonce(nil)
 					i = ItxRetryLimit
 					continue
@@ -429,7 +537,7 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 				forwarderGas := uint(w.conn.Opts().GasLimit*64/63 + 100000)
 				signedTx, err := toSignedRelayTx(w.forwarderClient.ForwarderAddress().String(), signedData, forwarderGas, uint(w.forwarderClient.ChainId().Uint64()), w.conn.Keypair(), w.conn.ItxSchedule())
 				if err != nil {
-					w.log.Error("Failed to sign relay tx for proposal execution", "err", err)
+					log.Error("Failed to sign relay tx for proposal execution", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 					w.forwarderClient.UnlockAndSetNonce(nil)
 					i = ItxRetryLimit
 					continue
@@ -437,9 +545,9 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 				res, err := sendRelayTransaction(w.conn.ItxClient(), w.conn.Opts().Context, signedTx.tx, signedTx.sig)
 				if err != nil {
 					w.forwarderClient.UnlockAndSetNonce(nil)
-					w.log.Warn("Failed to send proposal execution to itx", "itxFailures", i, "err", err)
-					if err.Error() == "Insufficient funds." {
-						w.log.Error("Insufficient funds to send via ITX.")
+					log.Warn("Failed to send proposal execution to itx", "itxFailures", i, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+					if balance.IsInsufficientFunds(err) {
+						w.insufficientFunds(m)
 						i = ItxRetryLimit
 						continue
 					} else {
@@ -448,13 +556,16 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 					}
 				} else {
 					w.forwarderClient.UnlockAndSetNonce(forwarderNonce)
-					w.log.Info("Submitted proposal execution to ITX", "relayTx", *res, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+					log.Info("Submitted proposal execution to ITX", "relayTx", *res, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+					w.executed(m, *res)
+					// The relayed transaction is not tracked, the proposal status is checked again later
+					done(false)
 					return
 				}
 			}
 		}
 
-		w.log.Error("ITX send failed, falling back to standard send.", "itxFailures", ItxRetryLimit)
+		log.Error("ITX send failed, falling back to standard send.", "itxFailures", ItxRetryLimit, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
 
 	}
 
@@ -466,7 +577,8 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 
 			err := w.conn.LockAndUpdateOpts()
 			if err != nil {
-				w.log.Error("Failed to update nonce", "err", err)
+				log.Error("Failed to update nonce", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+				done(false)
 				return
 			}
 			// These store the gas limit and price before a transaction is sent for logging in case of a failure
@@ -481,27 +593,50 @@ func (w *writer) executeProposal(m msg.Message, data []byte, dataHash [32]byte)
 				data,
 				m.ResourceId,
 			)
-			w.conn.UnlockOpts()
+			w.conn.UnlockOptsAfterSend(err)
 
 			if err == nil {
-				w.log.Info("Submitted proposal execution", "tx", tx.Hash(), "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "gasPrice", tx.GasPrice().String())
+				log.Info("Submitted proposal execution", "tx", tx.Hash(), "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "gasPrice", tx.GasPrice().String())
+				w.trackTx(tx, m, writermetrics.TxExecute, func(receipt *types.Receipt) {
+					if receipt == nil {
+						done(false)
+					} else if receipt.Status == types.ReceiptStatusSuccessful {
+						w.executed(m, receipt.TxHash.Hex())
+						done(true)
+					} else if w.proposalIsFinalized(m.Source, m.DepositNonce, dataHash) {
+						log.Info("Execution transaction reverted, proposal already finalized", "tx", receipt.TxHash, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+						w.trackOutcome(m, dataHash)
+						done(true)
+					} else {
+						log.Warn("Execution transaction reverted", "tx", receipt.TxHash, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+						w.failed(m)
+						done(false)
+					}
+				})
 				return
 			} else if err.Error() == ErrNonceTooLow.Error() || err.Error() == ErrTxUnderpriced.Error() {
-				w.log.Error("Nonce too low, will retry")
+				log.Error("Nonce too low, will retry", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+				time.Sleep(TxRetryInterval)
+			} else if balance.IsInsufficientFunds(err) {
+				w.insufficientFunds(m)
 				time.Sleep(TxRetryInterval)
 			} else {
-				w.log.Warn("Execution failed, proposal may already be complete", "gasLimit", gasLimit, "gasPrice", gasPrice, "err", err)
+				log.Warn("Execution failed, proposal may already be complete", "gasLimit", gasLimit, "gasPrice", gasPrice, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
 				time.Sleep(TxRetryInterval)
 			}
 
 			// Verify proposal is still open for execution, tx will fail if we aren't the first to execute,
 			// but there is no need to retry
 			if w.proposalIsFinalized(m.Source, m.DepositNonce, dataHash) {
-				w.log.Info("Proposal finalized on chain", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+				log.Info("Proposal finalized on chain", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+				w.trackOutcome(m, dataHash)
+				done(true)
 				return
 			}
 		}
 	}
-	w.log.Error("Submission of Execute transaction failed", "source", m.Source, "dest", m.Destination, "depositNonce", m.DepositNonce)
+	log.Error("Submission of Execute transaction failed", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+	w.failed(m)
+	done(false)
 	w.sysErr <- ErrFatalTx
 }
@@ -0,0 +1,173 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/bindings/Bridge"
+	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// fakeBridgeBackend serves an active proposal the relayer has not voted on. The receipts of the sent transactions
+// are failed until reverts transactions were sent.
+type fakeBridgeBackend struct {
+	bind.ContractBackend
+	abi     abi.ABI
+	reverts int
+
+	lock     sync.Mutex
+	block    int64
+	sent     []*types.Transaction
+	receipts map[common.Hash]*types.Receipt
+}
+
+func newFakeBridgeBackend(t *testing.T, reverts int) *fakeBridgeBackend {
+	bridgeAbi, err := abi.JSON(strings.NewReader(Bridge.BridgeABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeBridgeBackend{abi: bridgeAbi, reverts: reverts, receipts: make(map[common.Hash]*types.Receipt)}
+}
+
+func (b *fakeBridgeBackend) HeaderByNumber(_ context.Context, _ *big.Int) (*types.Header, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return &types.Header{Number: big.NewInt(b.block)}, nil
+}
+
+func (b *fakeBridgeBackend) CallContract(_ context.Context, call eth.CallMsg, _ *big.Int) ([]byte, error) {
+	method, err := b.abi.MethodById(call.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	switch method.Name {
+	case "getProposal":
+		return method.Outputs.Pack(Bridge.BridgeProposal{Status: uint8(utils.Active), ProposedBlock: big.NewInt(1)})
+	case "_hasVotedOnProposal":
+		return method.Outputs.Pack(false)
+	}
+	return nil, fmt.Errorf("unexpected call to %s", method.Name)
+}
+
+func (b *fakeBridgeBackend) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	status := types.ReceiptStatusSuccessful
+	if len(b.sent) < b.reverts {
+		status = types.ReceiptStatusFailed
+	}
+	b.sent = append(b.sent, tx)
+	b.receipts[tx.Hash()] = &types.Receipt{TxHash: tx.Hash(), Status: status, BlockNumber: big.NewInt(b.block)}
+	return nil
+}
+
+func (b *fakeBridgeBackend) LatestBlock() (*big.Int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.block++
+	return big.NewInt(b.block), nil
+}
+
+func (b *fakeBridgeBackend) TransactionReceipt(_ context.Context, txHash common.Hash) (*types.Receipt, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if receipt, ok := b.receipts[txHash]; ok {
+		return receipt, nil
+	}
+	return nil, eth.NotFound
+}
+
+func (b *fakeBridgeBackend) ReplaceTransaction(tx *types.Transaction) (*types.Transaction, error) {
+	return nil, fmt.Errorf("unexpected replacement of %s", tx.Hash())
+}
+
+func (b *fakeBridgeBackend) sentCount() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.sent)
+}
+
+// fakeConnection hands out a new nonce for each transaction, transactions are not signed
+type fakeConnection struct {
+	Connection
+	opts *bind.TransactOpts
+}
+
+func newFakeConnection() *fakeConnection {
+	return &fakeConnection{opts: &bind.TransactOpts{
+		From:     AliceKp.CommonAddress(),
+		Nonce:    big.NewInt(0),
+		GasPrice: big.NewInt(1),
+		GasLimit: 100000,
+		Signer: func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			return tx, nil
+		},
+	}}
+}
+
+func (c *fakeConnection) Opts() *bind.TransactOpts { return c.opts }
+
+func (c *fakeConnection) CallOpts() *bind.CallOpts { return &bind.CallOpts{From: c.opts.From} }
+
+func (c *fakeConnection) ItxClient() *rpc.Client { return nil }
+
+func (c *fakeConnection) LockAndUpdateOpts() error {
+	c.opts.Nonce = new(big.Int).Add(c.opts.Nonce, big.NewInt(1))
+	return nil
+}
+
+func (c *fakeConnection) UnlockOptsAfterSend(_ error) {}
+
+func TestWriter_VoteRetriedAfterRevert(t *testing.T) {
+	backend := newFakeBridgeBackend(t, 1)
+	bridge, err := Bridge.NewBridge(common.Address{}, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan int)
+	defer close(stop)
+	w := &writer{
+		cfg:            *aliceTestConfig,
+		conn:           newFakeConnection(),
+		bridgeContract: bridge,
+		log:            newTestLogger("writer"),
+		stop:           stop,
+		sysErr:         make(chan error, 1),
+		txTracker:      newTestTxTracker(backend, 3, stop),
+	}
+
+	m := msg.NewFungibleTransfer(1, 0, 1, big.NewInt(10), msg.ResourceId{1}, common.Address{}.Bytes())
+	w.voteProposal(m, [32]byte{1})
+
+	deadline := time.Now().Add(TestTimeout)
+	for backend.sentCount() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a second vote after the first reverted, got %d votes", backend.sentCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+	for _, tx := range backend.sent {
+		if !bytes.Equal(tx.Data()[:4], backend.abi.Methods["voteProposal"].ID) {
+			t.Fatalf("expected vote transaction, got data %x", tx.Data())
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package chains
+
+import (
+	"fmt"
+
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ChainSafe/log15"
+)
+
+// TransferKey is the log key of the correlation ID of a transfer
+const TransferKey = "transfer"
+
+// TransferId returns the correlation ID of the transfer with the source, destination and deposit nonce. It is the
+// same on every relayer.
+func TransferId(src, dst msg.ChainId, nonce msg.Nonce) string {
+	return fmt.Sprintf("%d-%d-%d", src, dst, nonce)
+}
+
+// TransferLogger returns a child of log that adds the correlation ID of the transfer of the message to every record
+func TransferLogger(log log15.Logger, m msg.Message) log15.Logger {
+	return log.New(TransferKey, TransferId(m.Source, m.Destination, m.DepositNonce))
+}
@@ -0,0 +1,29 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package chains
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ChainSafe/log15"
+)
+
+func TestTransferLogger(t *testing.T) {
+	var got []interface{}
+	logger := log15.New("chain", "test")
+	logger.SetHandler(log15.FuncHandler(func(r *log15.Record) error {
+		got = r.Ctx
+		return nil
+	}))
+
+	m := msg.Message{Source: 1, Destination: 2, DepositNonce: 3}
+	TransferLogger(logger, m).Info("test", "err", "failed")
+
+	expected := []interface{}{"chain", "test", TransferKey, "1-2-3", "err", "failed"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
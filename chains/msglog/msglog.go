@@ -0,0 +1,186 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+/*
+The msglog package contains the encoding shared by the append-only logs of messages kept next to the blockstore.
+
+Each line of a log is a JSON record with an operation and the key of the message it applies to. Records that add a
+message also hold its contents, the payload of which may only contain byte slices. A partially written record can
+only be the last one of a log, so replaying stops at the first record that cannot be decoded.
+*/
+package msglog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ChainSafe/chainbridge-utils/blockstore"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+// Key uniquely identifies a message
+type Key struct {
+	Source      msg.ChainId `json:"src"`
+	Destination msg.ChainId `json:"dst"`
+	Nonce       msg.Nonce   `json:"nonce"`
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%d-%d-%d", k.Source, k.Destination, k.Nonce)
+}
+
+// KeyOf returns the key of the message
+func KeyOf(m msg.Message) Key {
+	return Key{Source: m.Source, Destination: m.Destination, Nonce: m.DepositNonce}
+}
+
+// SortKeys orders the keys by source, destination and nonce
+func SortKeys(keys []Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Source != keys[j].Source {
+			return keys[i].Source < keys[j].Source
+		}
+		if keys[i].Destination != keys[j].Destination {
+			return keys[i].Destination < keys[j].Destination
+		}
+		return keys[i].Nonce < keys[j].Nonce
+	})
+}
+
+// Record is the part of a log record common to all logs, Msg is only set by operations that add a message. Logs
+// embed it in their own record type to add fields.
+type Record struct {
+	Op  string `json:"op"`
+	Key Key    `json:"key"`
+	Msg *Entry `json:"msg,omitempty"`
+}
+
+// Entry contains the fields of a message that are not part of its key
+type Entry struct {
+	Type       msg.TransferType `json:"type"`
+	ResourceId msg.ResourceId   `json:"resourceId"`
+	Payload    [][]byte         `json:"payload"`
+}
+
+// NewRecord returns a record of the operation holding the message
+func NewRecord(op string, m msg.Message) (Record, error) {
+	payload := make([][]byte, len(m.Payload))
+	for i, p := range m.Payload {
+		bz, ok := p.([]byte)
+		if !ok {
+			return Record{}, fmt.Errorf("unsupported payload type %T at index %d", p, i)
+		}
+		payload[i] = bz
+	}
+
+	return Record{
+		Op:  op,
+		Key: KeyOf(m),
+		Msg: &Entry{
+			Type:       m.Type,
+			ResourceId: m.ResourceId,
+			Payload:    payload,
+		},
+	}, nil
+}
+
+// Message returns the message of the record, only the key fields are set if the record holds no message
+func (r Record) Message() msg.Message {
+	m := msg.Message{
+		Source:       r.Key.Source,
+		Destination:  r.Key.Destination,
+		DepositNonce: r.Key.Nonce,
+	}
+	if r.Msg == nil {
+		return m
+	}
+
+	m.Type = r.Msg.Type
+	m.ResourceId = r.Msg.ResourceId
+	m.Payload = make([]interface{}, len(r.Msg.Payload))
+	for i, p := range r.Msg.Payload {
+		m.Payload[i] = p
+	}
+	return m
+}
+
+// Replay calls apply with each line of the log at path, until apply returns an error. A missing log is empty.
+func Replay(path string, apply func(line []byte) error) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if apply(scanner.Bytes()) != nil {
+			// A partially written record can only be the last one, anything prior to it is intact
+			break
+		}
+	}
+	return scanner.Err()
+}
+
+// Write writes the record to f as a single line
+func Write(f *os.File, rec interface{}) error {
+	bz, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(bz, '\n'))
+	return err
+}
+
+// Append appends the record to the log at path and syncs it. The log is opened for each record, so that it may be
+// appended to by several processes.
+func Append(path string, rec interface{}) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	err = Write(f, rec)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DataDir returns dir, or the default blockstore location in the home directory if dir is empty. The directory is
+// created if it does not exist.
+func DataDir(dir string) (string, error) {
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, blockstore.PathPostfix)
+	}
+	return dir, os.MkdirAll(dir, os.ModePerm)
+}
+
+// AmountOf returns the amount of a fungible transfer, nil for other messages
+func AmountOf(m msg.Message) *big.Int {
+	if m.Type != msg.FungibleTransfer || len(m.Payload) == 0 {
+		return nil
+	}
+	bz, ok := m.Payload[0].([]byte)
+	if !ok {
+		return nil
+	}
+	return new(big.Int).SetBytes(bz)
+}
@@ -0,0 +1,361 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+/*
+The outbox package provides a persistent queue of messages between the listeners and the writers.
+
+Listeners append every message to the outbox before routing it, and writers acknowledge a message once its vote
+or execution has landed on the destination chain. Messages that were never acknowledged, for instance due to a
+crash or a fatal transaction error, remain pending and are replayed by the listeners on startup.
+
+Listeners cancel messages whose deposits were orphaned by a reorg. Writers must not vote on a cancelled message. As a
+deposit nonce may be reused by the canonical chain, a cancellation only applies to a message with the same contents.
+Cancellations are pruned once the listener has processed blocks beyond the reorg depth of the orphaned deposits.
+
+The outbox is stored as an append-only log of JSON records. The log is compacted when it is opened, so that only
+pending messages and cancellations are carried over.
+*/
+package outbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+const FileName = "outbox.wal"
+
+const (
+	opAppend = "append"
+	opAck    = "ack"
+	opCancel = "cancel"
+	opPrune  = "prune"
+)
+
+// Key uniquely identifies a message within the outbox
+type Key = msglog.Key
+
+// KeyOf returns the outbox key for the message
+func KeyOf(m msg.Message) Key {
+	return msglog.KeyOf(m)
+}
+
+// record is a single entry in the log, Msg is only set for appended and cancelled messages. Block is the block of a
+// cancellation, or the block before which cancellations of the source chain are pruned.
+type record struct {
+	msglog.Record
+	Block uint64 `json:"block,omitempty"`
+}
+
+// cancellation is a cancelled message, and the block of the source chain it was cancelled at
+type cancellation struct {
+	msg   msg.Message
+	block uint64
+}
+
+type Outbox struct {
+	path      string
+	file      *os.File
+	pending   map[Key]msg.Message
+	cancelled map[Key]cancellation
+	lock      sync.Mutex
+}
+
+// Open loads the outbox stored in dir, creating it if it does not exist. The log is compacted to only contain
+// messages that have not been acknowledged.
+func Open(dir string) (*Outbox, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &Outbox{
+		path:      filepath.Join(dir, FileName),
+		pending:   make(map[Key]msg.Message),
+		cancelled: make(map[Key]cancellation),
+	}
+
+	err = o.load()
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.compact()
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// load replays the log into the pending set
+func (o *Outbox) load() error {
+	return msglog.Replay(o.path, func(line []byte) error {
+		var rec record
+		err := json.Unmarshal(line, &rec)
+		if err != nil {
+			return err
+		}
+
+		switch rec.Op {
+		case opAppend:
+			o.appended(rec.Message())
+		case opAck:
+			delete(o.pending, rec.Key)
+		case opCancel:
+			o.cancel(rec.Message(), rec.Block)
+		case opPrune:
+			o.prune(rec.Key.Source, rec.Block)
+		}
+		return nil
+	})
+}
+
+// compact rewrites the log to only contain the pending messages and opens it for appending
+func (o *Outbox) compact() error {
+	tmp := o.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	var recs []record
+	for _, m := range o.sorted(nil) {
+		rec, err := newRecord(opAppend, m)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		recs = append(recs, rec)
+	}
+	for _, c := range o.cancelled {
+		rec, err := newRecord(opCancel, c.msg)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		rec.Block = c.block
+		recs = append(recs, rec)
+	}
+
+	for _, rec := range recs {
+		err = msglog.Write(f, rec)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+
+	err = f.Sync()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, o.path)
+	if err != nil {
+		return err
+	}
+
+	o.file, err = os.OpenFile(o.path, os.O_APPEND|os.O_WRONLY, 0600)
+	return err
+}
+
+// Append persists the message. It must be called before the message is routed to the writer.
+func (o *Outbox) Append(m msg.Message) error {
+	rec, err := newRecord(opAppend, m)
+	if err != nil {
+		return err
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	err = o.write(rec)
+	if err != nil {
+		return err
+	}
+	o.appended(m)
+	return nil
+}
+
+// appended adds the message to the pending set. A cancellation of a message with the same contents is lifted,
+// as the deposit is part of the canonical chain again.
+func (o *Outbox) appended(m msg.Message) {
+	key := KeyOf(m)
+	o.pending[key] = m
+	if c, ok := o.cancelled[key]; ok && sameContents(c.msg, m) {
+		delete(o.cancelled, key)
+	}
+}
+
+// Ack marks the message as delivered, it will no longer be replayed.
+func (o *Outbox) Ack(m msg.Message) error {
+	key := KeyOf(m)
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if _, ok := o.pending[key]; !ok {
+		return nil
+	}
+
+	err := o.write(record{Record: msglog.Record{Op: opAck, Key: key}})
+	if err != nil {
+		return err
+	}
+	delete(o.pending, key)
+	return nil
+}
+
+// Cancel marks the message as cancelled, as its deposit is no longer part of the canonical chain. The message
+// will not be replayed, and IsCancelled reports true for any message with the same contents until the cancellation
+// is pruned. Block is the latest block of the source chain the deposit may have been in.
+func (o *Outbox) Cancel(m msg.Message, block uint64) error {
+	rec, err := newRecord(opCancel, m)
+	if err != nil {
+		return err
+	}
+	rec.Block = block
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	err = o.write(rec)
+	if err != nil {
+		return err
+	}
+	o.cancel(m, block)
+	return nil
+}
+
+func (o *Outbox) cancel(m msg.Message, block uint64) {
+	key := KeyOf(m)
+	o.cancelled[key] = cancellation{msg: m, block: block}
+	if p, ok := o.pending[key]; ok && sameContents(p, m) {
+		delete(o.pending, key)
+	}
+}
+
+// Prune drops the cancellations of messages from src cancelled before block. It is called by the listener once the
+// blocks of the orphaned deposits are deeper than the reorg depth, so they cannot become canonical again.
+func (o *Outbox) Prune(src msg.ChainId, block uint64) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if !o.hasPrunable(src, block) {
+		return nil
+	}
+	err := o.write(record{Record: msglog.Record{Op: opPrune, Key: Key{Source: src}}, Block: block})
+	if err != nil {
+		return err
+	}
+	o.prune(src, block)
+	return nil
+}
+
+func (o *Outbox) hasPrunable(src msg.ChainId, block uint64) bool {
+	for k, c := range o.cancelled {
+		if k.Source == src && c.block < block {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Outbox) prune(src msg.ChainId, block uint64) {
+	for k, c := range o.cancelled {
+		if k.Source == src && c.block < block {
+			delete(o.cancelled, k)
+		}
+	}
+}
+
+// IsCancelled returns true if the message has been cancelled
+func (o *Outbox) IsCancelled(m msg.Message) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	c, ok := o.cancelled[KeyOf(m)]
+	return ok && sameContents(c.msg, m)
+}
+
+// Pending returns all unacknowledged messages originating from src, ordered by destination and nonce.
+func (o *Outbox) Pending(src msg.ChainId) []msg.Message {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return o.sorted(func(k Key) bool { return k.Source == src })
+}
+
+// Close closes the underlying log file
+func (o *Outbox) Close() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
+
+// write appends the record to the log and flushes it to disk
+func (o *Outbox) write(rec record) error {
+	if o.file == nil {
+		return fmt.Errorf("outbox %s is closed", o.path)
+	}
+	err := msglog.Write(o.file, rec)
+	if err != nil {
+		return err
+	}
+	return o.file.Sync()
+}
+
+// sorted returns the pending messages matching filter, ordered by source, destination and nonce
+func (o *Outbox) sorted(filter func(Key) bool) []msg.Message {
+	keys := make([]Key, 0, len(o.pending))
+	for k := range o.pending {
+		if filter == nil || filter(k) {
+			keys = append(keys, k)
+		}
+	}
+	msglog.SortKeys(keys)
+
+	msgs := make([]msg.Message, len(keys))
+	for i, k := range keys {
+		msgs[i] = o.pending[k]
+	}
+	return msgs
+}
+
+func newRecord(op string, m msg.Message) (record, error) {
+	rec, err := msglog.NewRecord(op, m)
+	return record{Record: rec}, err
+}
+
+// sameContents returns true if both messages have the same type, resource ID and payload
+func sameContents(a, b msg.Message) bool {
+	if a.Type != b.Type || a.ResourceId != b.ResourceId || len(a.Payload) != len(b.Payload) {
+		return false
+	}
+	for i := range a.Payload {
+		pa, okA := a.Payload[i].([]byte)
+		pb, okB := b.Payload[i].([]byte)
+		if !okA || !okB || !bytes.Equal(pa, pb) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,253 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package outbox
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+func newTestOutbox(t *testing.T) (*Outbox, string) {
+	dir, err := ioutil.TempDir(os.TempDir(), "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	o, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o, dir
+}
+
+func TestOutbox_ReplayPending(t *testing.T) {
+	o, dir := newTestOutbox(t)
+
+	rId := msg.ResourceIdFromSlice([]byte{1, 2, 3})
+	m1 := msg.NewFungibleTransfer(0, 1, 1, big.NewInt(100), rId, []byte{0xab})
+	m2 := msg.NewNonFungibleTransfer(0, 1, 2, rId, big.NewInt(5), []byte{0xcd}, []byte("metadata"))
+	m3 := msg.NewGenericTransfer(1, 0, 1, rId, []byte{0xef})
+
+	for _, m := range []msg.Message{m2, m1, m3} {
+		err := o.Append(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := o.Ack(m1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = o.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen to simulate a restart
+	o, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	if pending := o.Pending(0); !reflect.DeepEqual(pending, []msg.Message{m2}) {
+		t.Fatalf("unexpected pending messages for chain 0.\n\tExpected: %#v\n\tGot: %#v", []msg.Message{m2}, pending)
+	}
+	if pending := o.Pending(1); !reflect.DeepEqual(pending, []msg.Message{m3}) {
+		t.Fatalf("unexpected pending messages for chain 1.\n\tExpected: %#v\n\tGot: %#v", []msg.Message{m3}, pending)
+	}
+}
+
+func TestOutbox_Ordering(t *testing.T) {
+	o, _ := newTestOutbox(t)
+	defer o.Close()
+
+	rId := msg.ResourceIdFromSlice([]byte{1})
+	var expected []msg.Message
+	for _, nonce := range []msg.Nonce{3, 1, 2} {
+		err := o.Append(msg.NewGenericTransfer(0, 1, nonce, rId, []byte{byte(nonce)}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, nonce := range []msg.Nonce{1, 2, 3} {
+		expected = append(expected, msg.NewGenericTransfer(0, 1, nonce, rId, []byte{byte(nonce)}))
+	}
+
+	if pending := o.Pending(0); !reflect.DeepEqual(pending, expected) {
+		t.Fatalf("pending messages not ordered by nonce.\n\tExpected: %#v\n\tGot: %#v", expected, pending)
+	}
+}
+
+func TestOutbox_Compaction(t *testing.T) {
+	o, dir := newTestOutbox(t)
+
+	rId := msg.ResourceIdFromSlice([]byte{1})
+	m := msg.NewGenericTransfer(0, 1, 1, rId, []byte{1})
+	err := o.Append(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Ack(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	info, err := os.Stat(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Fatalf("expected empty log after compaction, got %d bytes", info.Size())
+	}
+}
+
+func TestOutbox_TruncatedRecord(t *testing.T) {
+	o, dir := newTestOutbox(t)
+
+	rId := msg.ResourceIdFromSlice([]byte{1})
+	m := msg.NewGenericTransfer(0, 1, 1, rId, []byte{1})
+	err := o.Append(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash in the middle of writing a record
+	f, err := os.OpenFile(filepath.Join(dir, FileName), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString(`{"op":"ack","key":{"src":0,`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	o, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	if pending := o.Pending(0); !reflect.DeepEqual(pending, []msg.Message{m}) {
+		t.Fatalf("unexpected pending messages.\n\tExpected: %#v\n\tGot: %#v", []msg.Message{m}, pending)
+	}
+}
+
+func TestOutbox_Cancel(t *testing.T) {
+	o, dir := newTestOutbox(t)
+
+	rId := msg.ResourceIdFromSlice([]byte{1})
+	orphaned := msg.NewGenericTransfer(0, 1, 1, rId, []byte{1})
+	canonical := msg.NewGenericTransfer(0, 1, 1, rId, []byte{2})
+
+	err := o.Append(orphaned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Cancel(orphaned, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The nonce is reused by a different deposit on the canonical chain
+	err = o.Append(canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	if !o.IsCancelled(orphaned) {
+		t.Fatal("expected orphaned message to be cancelled")
+	}
+	if o.IsCancelled(canonical) {
+		t.Fatal("canonical message should not be cancelled")
+	}
+	if pending := o.Pending(0); !reflect.DeepEqual(pending, []msg.Message{canonical}) {
+		t.Fatalf("unexpected pending messages.\n\tExpected: %#v\n\tGot: %#v", []msg.Message{canonical}, pending)
+	}
+
+	// The orphaned deposit is included again
+	err = o.Append(orphaned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.IsCancelled(orphaned) {
+		t.Fatal("cancellation should be lifted when the message is appended again")
+	}
+}
+
+func TestOutbox_Prune(t *testing.T) {
+	o, dir := newTestOutbox(t)
+
+	rId := msg.ResourceIdFromSlice([]byte{1})
+	old := msg.NewGenericTransfer(0, 1, 1, rId, []byte{1})
+	recent := msg.NewGenericTransfer(0, 1, 2, rId, []byte{2})
+	other := msg.NewGenericTransfer(2, 1, 1, rId, []byte{3})
+
+	for _, c := range []struct {
+		m     msg.Message
+		block uint64
+	}{{old, 10}, {recent, 300}, {other, 10}} {
+		err := o.Cancel(c.m, c.block)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := o.Prune(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.IsCancelled(old) || !o.IsCancelled(recent) || !o.IsCancelled(other) {
+		t.Fatal("expected only the old cancellation of the source chain to be pruned")
+	}
+
+	// Pruning is persisted
+	err = o.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	if o.IsCancelled(old) || !o.IsCancelled(recent) || !o.IsCancelled(other) {
+		t.Fatal("expected pruned cancellation not to be reloaded")
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+/*
+The policy package restricts the deposits a listener relays with a per-resource policy file.
+
+The file maps resource IDs to the amounts and destination chains allowed for deposits of that resource. It is
+reloaded when it changes, an invalid file is rejected and the previous rules are kept. Listeners of all chain
+types check each deposit before it is persisted and routed, deposits that violate a rule are logged and skipped.
+*/
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains"
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Time between checks of the deposit policy file for changes
+var ReloadInterval = time.Second * 10
+
+// Rules a deposit may violate, used as the metric label
+const (
+	MinAmountRule    = "minAmount"
+	MaxAmountRule    = "maxAmount"
+	DestinationsRule = "destinations"
+)
+
+// resourceRule is the policy for deposits of a single resource as written in the policy file. Amounts are decimal
+// strings in the token's base unit.
+type resourceRule struct {
+	MinAmount    string        `json:"minAmount,omitempty"`
+	MaxAmount    string        `json:"maxAmount,omitempty"`
+	Destinations []msg.ChainId `json:"destinations,omitempty"`
+}
+
+// depositRule is a parsed resourceRule. Nil bounds and an empty destination set are not enforced.
+type depositRule struct {
+	minAmount    *big.Int
+	maxAmount    *big.Int
+	destinations map[msg.ChainId]bool
+}
+
+// violation describes why a deposit was rejected by the policy
+type violation struct {
+	rule   string
+	detail string
+}
+
+// Policy holds the rules for deposits from a chain, keyed by resource ID. Resources without a rule are
+// not restricted. The rules are reloaded when the file at path changes.
+type Policy struct {
+	path    string
+	modTime time.Time
+	rules   map[msg.ResourceId]*depositRule
+	lock    sync.RWMutex
+	log     log15.Logger
+	skipped *prometheus.CounterVec // Deposits skipped by rule, may be nil
+}
+
+// Load reads the policy file at path
+func Load(path string, log log15.Logger) (*Policy, error) {
+	p := &Policy{path: path, log: log}
+	_, err := p.reload()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// EnableMetrics registers the metric counting the deposits of the chain skipped by the policy
+func (p *Policy) EnableMetrics(chain string) {
+	p.skipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: fmt.Sprintf("%s_deposits_skipped", chain),
+		Help: "Number of deposits not relayed because they violate the deposit policy, by rule",
+	}, []string{"rule"})
+	prometheus.MustRegister(p.skipped)
+}
+
+// parseDepositRules parses the contents of a policy file, a JSON object mapping hex resource IDs to rules
+func parseDepositRules(bz []byte) (map[msg.ResourceId]*depositRule, error) {
+	var raw map[string]resourceRule
+	err := json.Unmarshal(bz, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[msg.ResourceId]*depositRule)
+	for id, r := range raw {
+		rId, err := parseResourceId(id)
+		if err != nil {
+			return nil, err
+		}
+
+		rule := &depositRule{}
+		if r.MinAmount != "" {
+			rule.minAmount, err = parseAmount(r.MinAmount)
+			if err != nil {
+				return nil, fmt.Errorf("resource %s: invalid %s: %w", id, MinAmountRule, err)
+			}
+		}
+		if r.MaxAmount != "" {
+			rule.maxAmount, err = parseAmount(r.MaxAmount)
+			if err != nil {
+				return nil, fmt.Errorf("resource %s: invalid %s: %w", id, MaxAmountRule, err)
+			}
+		}
+		if rule.minAmount != nil && rule.maxAmount != nil && rule.minAmount.Cmp(rule.maxAmount) == 1 {
+			return nil, fmt.Errorf("resource %s: %s is greater than %s", id, MinAmountRule, MaxAmountRule)
+		}
+		if len(r.Destinations) > 0 {
+			rule.destinations = make(map[msg.ChainId]bool)
+			for _, dest := range r.Destinations {
+				rule.destinations[dest] = true
+			}
+		}
+		rules[rId] = rule
+	}
+	return rules, nil
+}
+
+func parseResourceId(id string) (msg.ResourceId, error) {
+	bz, err := hexutil.Decode(id)
+	if err != nil || len(bz) != 32 {
+		return msg.ResourceId{}, fmt.Errorf("invalid resource ID %s", id)
+	}
+	return msg.ResourceIdFromSlice(bz), nil
+}
+
+func parseAmount(amount string) (*big.Int, error) {
+	val, ok := new(big.Int).SetString(amount, 10)
+	if !ok || val.Sign() < 0 {
+		return nil, fmt.Errorf("%s is not a non-negative integer", amount)
+	}
+	return val, nil
+}
+
+// reload reads the policy file if it was modified since it was last read. Returns true if the rules were replaced.
+// The current rules are kept if the file is invalid.
+func (p *Policy) reload() (bool, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(p.modTime) {
+		return false, nil
+	}
+
+	bz, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return false, err
+	}
+	rules, err := parseDepositRules(bz)
+	if err != nil {
+		return false, fmt.Errorf("failed to load deposit policy %s: %w", p.path, err)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.rules = rules
+	p.modTime = info.ModTime()
+	return true, nil
+}
+
+// check returns the violation if the message does not conform to the rule for its resource, nil otherwise
+func (p *Policy) check(m msg.Message) *violation {
+	p.lock.RLock()
+	rule, ok := p.rules[m.ResourceId]
+	p.lock.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	if rule.destinations != nil && !rule.destinations[m.Destination] {
+		return &violation{rule: DestinationsRule, detail: fmt.Sprintf("destination %d is not allowed", m.Destination)}
+	}
+
+	amount := msglog.AmountOf(m)
+	if amount == nil {
+		return nil
+	}
+	if rule.minAmount != nil && amount.Cmp(rule.minAmount) == -1 {
+		return &violation{rule: MinAmountRule, detail: fmt.Sprintf("amount %s is below the minimum %s", amount, rule.minAmount)}
+	}
+	if rule.maxAmount != nil && amount.Cmp(rule.maxAmount) == 1 {
+		return &violation{rule: MaxAmountRule, detail: fmt.Sprintf("amount %s is above the maximum %s", amount, rule.maxAmount)}
+	}
+	return nil
+}
+
+// Watch reloads the policy whenever the file changes, until stop is closed
+func (p *Policy) Watch(stop <-chan int) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(ReloadInterval):
+		}
+
+		reloaded, err := p.reload()
+		if err != nil {
+			p.log.Error("Failed to reload deposit policy, keeping current rules", "path", p.path, "err", err)
+		} else if reloaded {
+			p.log.Info("Reloaded deposit policy", "path", p.path)
+		}
+	}
+}
+
+// Allowed returns false if the message violates the policy, it should then not be relayed
+func (p *Policy) Allowed(m msg.Message) bool {
+	v := p.check(m)
+	if v == nil {
+		return true
+	}
+	chains.TransferLogger(p.log, m).Warn("Deposit skipped by policy", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "resourceId", m.ResourceId.Hex(), "type", m.Type, "rule", v.rule, "reason", v.detail)
+	if p.skipped != nil {
+		p.skipped.WithLabelValues(v.rule).Inc()
+	}
+	return false
+}
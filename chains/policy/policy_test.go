@@ -0,0 +1,118 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package policy
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const testPolicyResource = "0x000000000000000000000000000000c76ebe4a02bbc34786d860b355f5a5ce00"
+
+func writePolicy(t *testing.T, path, policy string, modTime time.Time) {
+	err := ioutil.WriteFile(path, []byte(policy), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDepositPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.json")
+	now := time.Now()
+	writePolicy(t, path, `{"`+testPolicyResource+`": {"minAmount": "100", "maxAmount": "1000", "destinations": [1, 2]}}`, now)
+
+	p, err := Load(path, log15.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rId := msg.ResourceIdFromSlice(hexutil.MustDecode(testPolicyResource))
+	other := msg.ResourceId{1}
+	testCases := []struct {
+		name string
+		m    msg.Message
+		rule string
+	}{
+		{"allowed", msg.NewFungibleTransfer(0, 1, 1, big.NewInt(100), rId, nil), ""},
+		{"below minimum", msg.NewFungibleTransfer(0, 1, 2, big.NewInt(99), rId, nil), MinAmountRule},
+		{"above maximum", msg.NewFungibleTransfer(0, 2, 3, big.NewInt(1001), rId, nil), MaxAmountRule},
+		{"destination", msg.NewFungibleTransfer(0, 3, 4, big.NewInt(500), rId, nil), DestinationsRule},
+		{"nonfungible destination", msg.NewNonFungibleTransfer(0, 3, 5, rId, big.NewInt(1), nil, nil), DestinationsRule},
+		{"nonfungible", msg.NewNonFungibleTransfer(0, 1, 6, rId, big.NewInt(1), nil, nil), ""},
+		{"unrestricted resource", msg.NewFungibleTransfer(0, 3, 7, big.NewInt(1), other, nil), ""},
+	}
+	for _, tc := range testCases {
+		v := p.check(tc.m)
+		if tc.rule == "" && v != nil {
+			t.Errorf("%s: expected deposit to be allowed, got %s", tc.name, v.detail)
+		} else if tc.rule != "" && (v == nil || v.rule != tc.rule) {
+			t.Errorf("%s: expected violation of %s, got %v", tc.name, tc.rule, v)
+		}
+	}
+
+	// An invalid file is rejected and the current rules are kept
+	writePolicy(t, path, `{"`+testPolicyResource+`": {"minAmount": "1000", "maxAmount": "100"}}`, now.Add(time.Second))
+	_, err = p.reload()
+	if err == nil {
+		t.Fatal("expected invalid policy to be rejected")
+	}
+	if v := p.check(msg.NewFungibleTransfer(0, 1, 8, big.NewInt(99), rId, nil)); v == nil || v.rule != MinAmountRule {
+		t.Fatalf("expected previous rules to be kept, got %v", v)
+	}
+
+	// A valid change replaces the rules
+	writePolicy(t, path, `{"`+testPolicyResource+`": {"minAmount": "10"}}`, now.Add(2*time.Second))
+	reloaded, err := p.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded {
+		t.Fatal("expected policy to be reloaded")
+	}
+	if v := p.check(msg.NewFungibleTransfer(0, 3, 9, big.NewInt(99), rId, nil)); v != nil {
+		t.Fatalf("expected deposit to be allowed after reload, got %s", v.detail)
+	}
+
+	// The file is not read again if it is unchanged
+	reloaded, err = p.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded {
+		t.Fatal("expected unchanged policy not to be reloaded")
+	}
+}
+
+func TestParseDepositRules_Invalid(t *testing.T) {
+	invalid := []string{
+		`[]`,
+		`{"0x1234": {}}`,
+		`{"` + testPolicyResource + `": {"minAmount": "-1"}}`,
+		`{"` + testPolicyResource + `": {"maxAmount": "1e18"}}`,
+	}
+	for _, policy := range invalid {
+		_, err := parseDepositRules([]byte(policy))
+		if err == nil {
+			t.Errorf("expected policy %s to be rejected", policy)
+		}
+	}
+}
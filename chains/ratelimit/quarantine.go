@@ -0,0 +1,232 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+// Status is the state of a message in the quarantine
+type Status int
+
+const (
+	NotHeld  Status = iota // Never held, or released and resolved
+	Held                   // Waiting to be released
+	Released               // Released by an operator, to be resolved by the writer
+)
+
+const (
+	opHold    = "hold"
+	opRelease = "release"
+	opDone    = "done"
+)
+
+// record is a single entry in the quarantine log, Msg and Reason are only set when a message is held
+type record struct {
+	msglog.Record
+	Reason string    `json:"reason,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Entry is a message in the quarantine
+type Entry struct {
+	Message  msg.Message
+	Reason   string
+	HeldAt   time.Time
+	Released bool
+}
+
+// Quarantine holds the messages that exceeded a limit. It is stored as an append-only log of JSON records, so that
+// the CLI can release messages while the relayer is running. The log is read again whenever released messages are
+// requested.
+type Quarantine struct {
+	path    string
+	entries map[outbox.Key]*Entry
+	lock    sync.Mutex
+}
+
+// QuarantineFileName returns the name of the quarantine log for the chain
+func QuarantineFileName(chain msg.ChainId) string {
+	return fmt.Sprintf("quarantine-%d.log", chain)
+}
+
+// OpenQuarantine loads the quarantine of messages to chain stored in dir. An empty dir uses the default blockstore
+// location in the home directory.
+func OpenQuarantine(dir string, chain msg.ChainId) (*Quarantine, error) {
+	dir, err := msglog.DataDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Quarantine{path: filepath.Join(dir, QuarantineFileName(chain))}
+	err = q.load()
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// load replays the log into the set of entries, the lock must be held or the quarantine not yet shared
+func (q *Quarantine) load() error {
+	entries := make(map[outbox.Key]*Entry)
+
+	err := msglog.Replay(q.path, func(line []byte) error {
+		var rec record
+		err := json.Unmarshal(line, &rec)
+		if err != nil {
+			return err
+		}
+
+		switch rec.Op {
+		case opHold:
+			entries[rec.Key] = &Entry{Message: rec.Message(), Reason: rec.Reason, HeldAt: rec.Time}
+		case opRelease:
+			if e, ok := entries[rec.Key]; ok {
+				e.Released = true
+			}
+		case opDone:
+			delete(entries, rec.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	q.entries = entries
+	return nil
+}
+
+// Status returns the state of the message in the quarantine
+func (q *Quarantine) Status(m msg.Message) Status {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	e, ok := q.entries[outbox.KeyOf(m)]
+	if !ok {
+		return NotHeld
+	} else if e.Released {
+		return Released
+	}
+	return Held
+}
+
+// Hold adds the message to the quarantine, if it is not already held
+func (q *Quarantine) Hold(m msg.Message, reason string) error {
+	r, err := msglog.NewRecord(opHold, m)
+	if err != nil {
+		return err
+	}
+	rec := record{Record: r, Reason: reason, Time: time.Now()}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if _, ok := q.entries[rec.Key]; ok {
+		return nil
+	}
+	err = q.write(rec)
+	if err != nil {
+		return err
+	}
+	q.entries[rec.Key] = &Entry{Message: m, Reason: reason, HeldAt: rec.Time}
+	return nil
+}
+
+// Release marks the held message with the key as released. Returns an error if no such message is held.
+func (q *Quarantine) Release(key outbox.Key) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	e, ok := q.entries[key]
+	if !ok {
+		return fmt.Errorf("no message %s in quarantine", key)
+	} else if e.Released {
+		return nil
+	}
+
+	err := q.write(record{Record: msglog.Record{Op: opRelease, Key: key}, Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	e.Released = true
+	return nil
+}
+
+// Done removes a held or released message from the quarantine once it is resolved
+func (q *Quarantine) Done(m msg.Message) error {
+	key := outbox.KeyOf(m)
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if _, ok := q.entries[key]; !ok {
+		return nil
+	}
+	err := q.write(record{Record: msglog.Record{Op: opDone, Key: key}, Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	delete(q.entries, key)
+	return nil
+}
+
+// Released reads the log again and returns the released messages that have not been resolved
+func (q *Quarantine) Released() ([]msg.Message, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	err := q.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var released []msg.Message
+	for _, e := range q.sorted() {
+		if e.Released {
+			released = append(released, e.Message)
+		}
+	}
+	return released, nil
+}
+
+// List returns all entries, ordered by source, destination and nonce
+func (q *Quarantine) List() []Entry {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var entries []Entry
+	for _, e := range q.sorted() {
+		entries = append(entries, *e)
+	}
+	return entries
+}
+
+// sorted returns the entries ordered by key, the lock must be held
+func (q *Quarantine) sorted() []*Entry {
+	keys := make([]outbox.Key, 0, len(q.entries))
+	for k := range q.entries {
+		keys = append(keys, k)
+	}
+	msglog.SortKeys(keys)
+
+	entries := make([]*Entry, len(keys))
+	for i, k := range keys {
+		entries[i] = q.entries[k]
+	}
+	return entries
+}
+
+// write appends the record to the log, the lock must be held
+func (q *Quarantine) write(rec record) error {
+	return msglog.Append(q.path, rec)
+}
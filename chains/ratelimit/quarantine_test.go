@@ -0,0 +1,68 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+func TestQuarantine_Persistence(t *testing.T) {
+	dir := newTestDir(t)
+	rId := msg.ResourceId{1}
+
+	q, err := OpenQuarantine(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m1 := transferOf(2, 10, rId)
+	m2 := transferOf(1, 20, rId)
+	for _, m := range []msg.Message{m1, m2, m1} {
+		err = q.Hold(m, "over cap")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = q.Release(outbox.KeyOf(m1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Release(outbox.Key{Source: 0, Destination: 1, Nonce: 3}); err == nil {
+		t.Fatal("expected release of unknown message to fail")
+	}
+
+	q, err = OpenQuarantine(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := q.List()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if !reflect.DeepEqual(entries[0].Message, m2) || entries[0].Released || entries[0].Reason != "over cap" {
+		t.Fatalf("unexpected entry %#v", entries[0])
+	}
+	if !reflect.DeepEqual(entries[1].Message, m1) || !entries[1].Released {
+		t.Fatalf("unexpected entry %#v", entries[1])
+	}
+	if q.Status(m1) != Released || q.Status(m2) != Held {
+		t.Fatalf("unexpected status %d %d", q.Status(m1), q.Status(m2))
+	}
+
+	err = q.Done(m1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err = OpenQuarantine(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Status(m1) != NotHeld {
+		t.Fatal("expected resolved message to be removed")
+	}
+}
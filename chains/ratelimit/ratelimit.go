@@ -0,0 +1,291 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+/*
+The ratelimit package caps the volume of fungible transfers a writer votes for within a rolling window.
+
+Limits are configured per resource ID for the writer's chain. The volume of each resource and destination chain is
+tracked over the window and persisted to disk, so a restart does not reset it. Messages that would exceed the cap are
+held in the quarantine until an operator releases them with the chainbridge CLI. Released messages are resolved
+regardless of the cap, and are included in the volume of the window.
+
+Non-fungible and generic transfers, and resources without a limit, are not restricted.
+*/
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DefaultWindow is the window of a limit that does not specify one
+const DefaultWindow = 24 * time.Hour
+
+// Limit caps the volume of a resource transferred within a rolling window
+type Limit struct {
+	Cap    *big.Int
+	Window time.Duration
+}
+
+// rawLimit is a Limit as written in the limits file
+type rawLimit struct {
+	Cap    string `json:"cap"`
+	Window string `json:"window,omitempty"`
+}
+
+// LoadLimits reads the limits file at path, a JSON object mapping hex resource IDs to a cap in the token's base
+// unit and a window such as "24h".
+func LoadLimits(path string) (map[msg.ResourceId]Limit, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := parseLimits(bz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate limits %s: %w", path, err)
+	}
+	return limits, nil
+}
+
+func parseLimits(bz []byte) (map[msg.ResourceId]Limit, error) {
+	var raw map[string]rawLimit
+	err := json.Unmarshal(bz, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[msg.ResourceId]Limit)
+	for id, r := range raw {
+		rId, err := hexutil.Decode(id)
+		if err != nil || len(rId) != 32 {
+			return nil, fmt.Errorf("invalid resource ID %s", id)
+		}
+
+		max, ok := new(big.Int).SetString(r.Cap, 10)
+		if !ok || max.Sign() < 0 {
+			return nil, fmt.Errorf("resource %s: invalid cap %q", id, r.Cap)
+		}
+
+		window := DefaultWindow
+		if r.Window != "" {
+			window, err = time.ParseDuration(r.Window)
+			if err != nil || window <= 0 {
+				return nil, fmt.Errorf("resource %s: invalid window %q", id, r.Window)
+			}
+		}
+		limits[msg.ResourceIdFromSlice(rId)] = Limit{Cap: max, Window: window}
+	}
+	return limits, nil
+}
+
+// Key identifies the volume tracked by the limiter
+type Key struct {
+	ResourceId  msg.ResourceId `json:"resourceId"`
+	Destination msg.ChainId    `json:"dst"`
+}
+
+// transfer is a message included in the volume of a window
+type transfer struct {
+	Source msg.ChainId `json:"src"`
+	Nonce  msg.Nonce   `json:"nonce"`
+	Amount *big.Int    `json:"amount"`
+	Time   time.Time   `json:"time"`
+}
+
+// window is the persisted form of the transfers for a key
+type window struct {
+	Key       Key        `json:"key"`
+	Transfers []transfer `json:"transfers"`
+}
+
+// Limiter tracks the transferred volume of each resource and destination, and admits messages within the limits
+type Limiter struct {
+	path      string
+	limits    map[msg.ResourceId]Limit
+	transfers map[Key][]transfer
+	now       func() time.Time
+	lock      sync.Mutex
+}
+
+// OpenLimiter loads the volume of the windows for chain stored in dir. An empty dir uses the default blockstore
+// location in the home directory.
+func OpenLimiter(dir string, chain msg.ChainId, limits map[msg.ResourceId]Limit) (*Limiter, error) {
+	dir, err := msglog.DataDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Limiter{
+		path:      filepath.Join(dir, fmt.Sprintf("ratelimit-%d.json", chain)),
+		limits:    limits,
+		transfers: make(map[Key][]transfer),
+		now:       time.Now,
+	}
+
+	bz, err := ioutil.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var windows []window
+	err = json.Unmarshal(bz, &windows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", l.path, err)
+	}
+	for _, w := range windows {
+		l.transfers[w.Key] = w.Transfers
+	}
+	return l, nil
+}
+
+// Admit records the message and returns true if it is within the limit for its resource. Returns false if it would
+// exceed the cap, the message is then not recorded. Messages that were already recorded are admitted again.
+func (l *Limiter) Admit(m msg.Message) (bool, error) {
+	return l.admit(m, false)
+}
+
+// Record includes the message in the volume of its window regardless of the cap
+func (l *Limiter) Record(m msg.Message) error {
+	_, err := l.admit(m, true)
+	return err
+}
+
+func (l *Limiter) admit(m msg.Message, force bool) (bool, error) {
+	limit, ok := l.limits[m.ResourceId]
+	amount := msglog.AmountOf(m)
+	if !ok || amount == nil {
+		return true, nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	key := Key{ResourceId: m.ResourceId, Destination: m.Destination}
+	now := l.now()
+	start := now.Add(-limit.Window)
+
+	total := new(big.Int)
+	var current []transfer
+	for _, t := range l.transfers[key] {
+		if t.Time.Before(start) {
+			continue
+		}
+		if t.Source == m.Source && t.Nonce == m.DepositNonce {
+			return true, nil
+		}
+		total.Add(total, t.Amount)
+		current = append(current, t)
+	}
+
+	if !force && new(big.Int).Add(total, amount).Cmp(limit.Cap) == 1 {
+		return false, nil
+	}
+
+	l.transfers[key] = append(current, transfer{Source: m.Source, Nonce: m.DepositNonce, Amount: amount, Time: now})
+	return true, l.save()
+}
+
+// Volume returns the volume transferred within the window of the resource to dest
+func (l *Limiter) Volume(rId msg.ResourceId, dest msg.ChainId) *big.Int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	total := new(big.Int)
+	limit, ok := l.limits[rId]
+	if !ok {
+		return total
+	}
+	start := l.now().Add(-limit.Window)
+	for _, t := range l.transfers[Key{ResourceId: rId, Destination: dest}] {
+		if !t.Time.Before(start) {
+			total.Add(total, t.Amount)
+		}
+	}
+	return total
+}
+
+// save persists the windows, the lock must be held
+func (l *Limiter) save() error {
+	windows := make([]window, 0, len(l.transfers))
+	for key, transfers := range l.transfers {
+		windows = append(windows, window{Key: key, Transfers: transfers})
+	}
+	bz, err := json.Marshal(windows)
+	if err != nil {
+		return err
+	}
+
+	tmp := l.path + ".tmp"
+	err = ioutil.WriteFile(tmp, bz, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// Guard combines the limiter and the quarantine holding the messages it does not admit
+type Guard struct {
+	limiter    *Limiter
+	quarantine *Quarantine
+}
+
+// Open opens the limiter and quarantine for chain stored in dir. An empty dir uses the default blockstore location
+// in the home directory.
+func Open(dir string, chain msg.ChainId, limits map[msg.ResourceId]Limit) (*Guard, error) {
+	limiter, err := OpenLimiter(dir, chain, limits)
+	if err != nil {
+		return nil, err
+	}
+	quarantine, err := OpenQuarantine(dir, chain)
+	if err != nil {
+		return nil, err
+	}
+	return &Guard{limiter: limiter, quarantine: quarantine}, nil
+}
+
+// Check returns true if the message may be resolved. A message over the cap is held in the quarantine and false is
+// returned until it is released.
+func (g *Guard) Check(m msg.Message) (bool, error) {
+	switch g.quarantine.Status(m) {
+	case Held:
+		return false, nil
+	case Released:
+		err := g.limiter.Record(m)
+		if err != nil {
+			return false, err
+		}
+		return true, g.quarantine.Done(m)
+	}
+
+	ok, err := g.limiter.Admit(m)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	limit := g.limiter.limits[m.ResourceId]
+	reason := fmt.Sprintf("amount %s exceeds the remaining volume of cap %s over %s", msglog.AmountOf(m), limit.Cap, limit.Window)
+	return false, g.quarantine.Hold(m, reason)
+}
+
+// Resolve removes the message from the quarantine if it is held or released. It is called once the proposal of the
+// message was completed without a vote of this relayer, so the message is not resolved again.
+func (g *Guard) Resolve(m msg.Message) error {
+	return g.quarantine.Done(m)
+}
+
+// Released returns the messages that were released from the quarantine but not yet resolved
+func (g *Guard) Released() ([]msg.Message, error) {
+	return g.quarantine.Released()
+}
@@ -0,0 +1,219 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ratelimit
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+func newTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir(os.TempDir(), "ratelimit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func transferOf(nonce msg.Nonce, amount int64, rId msg.ResourceId) msg.Message {
+	return msg.NewFungibleTransfer(0, 1, nonce, big.NewInt(amount), rId, []byte{0xab})
+}
+
+func TestLimiter_RollingWindow(t *testing.T) {
+	dir := newTestDir(t)
+	rId := msg.ResourceId{1}
+	limits := map[msg.ResourceId]Limit{rId: {Cap: big.NewInt(100), Window: time.Hour}}
+
+	l, err := OpenLimiter(dir, 1, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	admit := func(l *Limiter, m msg.Message, expected bool) {
+		ok, err := l.Admit(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != expected {
+			t.Fatalf("nonce %d: expected admitted %t, got %t", m.DepositNonce, expected, ok)
+		}
+	}
+
+	admit(l, transferOf(1, 60, rId), true)
+	admit(l, transferOf(2, 50, rId), false)
+	admit(l, transferOf(3, 40, rId), true)
+	// A message that was already admitted is not counted twice
+	admit(l, transferOf(1, 60, rId), true)
+	if v := l.Volume(rId, 1); v.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("expected volume 100, got %s", v)
+	}
+
+	// Other resources and non-fungible transfers are not limited
+	admit(l, transferOf(4, 1000, msg.ResourceId{2}), true)
+	admit(l, msg.NewNonFungibleTransfer(0, 1, 5, rId, big.NewInt(1000), nil, nil), true)
+
+	// The volume is persisted
+	l, err = OpenLimiter(dir, 1, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = func() time.Time { return now.Add(30 * time.Minute) }
+	admit(l, transferOf(6, 1, rId), false)
+
+	// Transfers leave the window once it has passed
+	l.now = func() time.Time { return now.Add(61 * time.Minute) }
+	admit(l, transferOf(6, 100, rId), true)
+}
+
+func TestGuard_QuarantineAndRelease(t *testing.T) {
+	dir := newTestDir(t)
+	rId := msg.ResourceId{1}
+	limits := map[msg.ResourceId]Limit{rId: {Cap: big.NewInt(100), Window: time.Hour}}
+
+	g, err := Open(dir, 1, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := transferOf(1, 150, rId)
+	ok, err := g.Check(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("expected message over the cap to be held")
+	}
+
+	// The message stays held when it is replayed, even if the window has room
+	g.limiter.limits = map[msg.ResourceId]Limit{rId: {Cap: big.NewInt(1000), Window: time.Hour}}
+	ok, err = g.Check(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("expected held message to remain held")
+	}
+
+	// Release from another instance, as done by the CLI
+	q, err := OpenQuarantine(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = q.Release(outbox.KeyOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released, err := g.Released()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 1 || released[0].DepositNonce != m.DepositNonce {
+		t.Fatalf("expected message %d to be released, got %v", m.DepositNonce, released)
+	}
+
+	ok, err = g.Check(released[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected released message to be admitted")
+	}
+	if v := g.limiter.Volume(rId, 1); v.Cmp(big.NewInt(150)) != 0 {
+		t.Fatalf("expected released message to be included in the volume, got %s", v)
+	}
+
+	released, err = g.Released()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 0 {
+		t.Fatalf("expected no released messages, got %v", released)
+	}
+}
+
+func TestGuard_ResolveReleased(t *testing.T) {
+	dir := newTestDir(t)
+	rId := msg.ResourceId{1}
+	limits := map[msg.ResourceId]Limit{rId: {Cap: big.NewInt(100), Window: time.Hour}}
+
+	g, err := Open(dir, 1, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := transferOf(1, 150, rId)
+	ok, err := g.Check(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("expected message over the cap to be held")
+	}
+
+	err = g.quarantine.Release(outbox.KeyOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The proposal completed without this relayer, the released message is resolved without being checked
+	err = g.Resolve(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released, err := g.Released()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 0 {
+		t.Fatalf("expected no released messages, got %v", released)
+	}
+	if v := g.limiter.Volume(rId, 1); v.Sign() != 0 {
+		t.Fatalf("expected resolved message not to be included in the volume, got %s", v)
+	}
+
+	// The message is no longer quarantined after a restart
+	g, err = Open(dir, 1, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := g.quarantine.Status(m); status != NotHeld {
+		t.Fatalf("expected message to be removed from the quarantine, got status %d", status)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	rId := "0x000000000000000000000000000000c76ebe4a02bbc34786d860b355f5a5ce00"
+	limits, err := parseLimits([]byte(`{"` + rId + `": {"cap": "1000"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, limit := range limits {
+		if limit.Cap.Cmp(big.NewInt(1000)) != 0 || limit.Window != DefaultWindow {
+			t.Fatalf("unexpected limit %v", limit)
+		}
+	}
+
+	invalid := []string{
+		`{"0x1234": {"cap": "1"}}`,
+		`{"` + rId + `": {"cap": "-1"}}`,
+		`{"` + rId + `": {"cap": "1", "window": "1 day"}}`,
+		`{"` + rId + `": {"cap": "1", "window": "-1h"}}`,
+	}
+	for _, limits := range invalid {
+		_, err = parseLimits([]byte(limits))
+		if err == nil {
+			t.Errorf("expected limits %s to be rejected", limits)
+		}
+	}
+}
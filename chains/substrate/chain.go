@@ -24,6 +24,13 @@ As the writer receives messages from the router, it constructs proposals. If a p
 package substrate
 
 import (
+	"github.com/ChainSafe/ChainBridge/chains/approval"
+	"github.com/ChainSafe/ChainBridge/chains/balance"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/ChainBridge/chains/policy"
+	"github.com/ChainSafe/ChainBridge/chains/ratelimit"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
+	"github.com/ChainSafe/ChainBridge/chains/writermetrics"
 	"github.com/ChainSafe/chainbridge-utils/blockstore"
 	"github.com/ChainSafe/chainbridge-utils/core"
 	"github.com/ChainSafe/chainbridge-utils/crypto/sr25519"
@@ -58,7 +65,10 @@ func checkBlockstore(bs *blockstore.Blockstore, startBlock uint64) (uint64, erro
 	}
 }
 
-func InitializeChain(cfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, m *metrics.ChainMetrics) (*Chain, error) {
+// InitializeChain constructs the connection, listener and writer for the chain. If ob is provided, messages are
+// persisted to it by the listener and acknowledged by the writer. If tr is provided, deposits and votes are
+// recorded in it.
+func InitializeChain(cfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, m *metrics.ChainMetrics, ob *outbox.Outbox, tr *transfers.Tracker) (*Chain, error) {
 	kp, err := keystore.KeypairFromAddress(cfg.From, keystore.SubChain, cfg.KeystorePath, cfg.Insecure)
 	if err != nil {
 		return nil, err
@@ -82,6 +92,12 @@ func InitializeChain(cfg *core.ChainConfig, logger log15.Logger, sysErr chan<- e
 	stop := make(chan int)
 	// Setup connection
 	conn := NewConnection(cfg.Endpoint, cfg.Name, krp, logger, stop, sysErr)
+	if m != nil {
+		conn.setMetrics(newConnectionMetrics(cfg.Name))
+	}
+	conn.setEraPeriod(parseEraPeriod(cfg))
+	conn.setTip(parseTip(cfg))
+	conn.setFinalityTimeout(parseFinalityTimeout(cfg))
 	err = conn.Connect()
 	if err != nil {
 		return nil, err
@@ -93,7 +109,7 @@ func InitializeChain(cfg *core.ChainConfig, logger log15.Logger, sysErr chan<- e
 	}
 
 	if cfg.LatestBlock {
-		curr, err := conn.api.RPC.Chain.GetHeaderLatest()
+		curr, err := conn.getApi().RPC.Chain.GetHeaderLatest()
 		if err != nil {
 			return nil, err
 		}
@@ -105,6 +121,57 @@ func InitializeChain(cfg *core.ChainConfig, logger log15.Logger, sysErr chan<- e
 	// Setup listener & writer
 	l := NewListener(conn, cfg.Name, cfg.Id, startBlock, logger, bs, stop, sysErr, m)
 	w := NewWriter(conn, logger, sysErr, m, ue)
+	l.setOutbox(ob)
+	w.setOutbox(ob)
+	l.setTransfers(tr)
+	w.setTransfers(tr)
+	if path := parseDepositPolicy(cfg); path != "" {
+		p, err := policy.Load(path, logger)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			p.EnableMetrics(cfg.Name)
+		}
+		l.setDepositPolicy(p)
+	}
+	rejections, err := newRejectionReport(cfg.BlockstorePath, cfg.Id)
+	if err != nil {
+		return nil, err
+	}
+	w.setRejectionReport(rejections)
+	if path := parseRateLimits(cfg); path != "" {
+		limits, err := ratelimit.LoadLimits(path)
+		if err != nil {
+			return nil, err
+		}
+		guard, err := ratelimit.Open(cfg.BlockstorePath, cfg.Id, limits)
+		if err != nil {
+			return nil, err
+		}
+		w.setRateLimit(guard)
+	}
+	if path := parseApprovalThresholds(cfg); path != "" {
+		thresholds, err := approval.LoadThresholds(path)
+		if err != nil {
+			return nil, err
+		}
+		approvals, err := approval.Open(cfg.BlockstorePath, cfg.Id, thresholds)
+		if err != nil {
+			return nil, err
+		}
+		w.setApprovals(approvals)
+	}
+	thresholds, pause, err := parseBalanceThresholds(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if thresholds.Enabled() {
+		w.setBalanceMonitor(balance.NewMonitor(cfg.Id, thresholds, pause, logger))
+	}
+	if m != nil {
+		w.setWriterMetrics(writermetrics.New(cfg.Name))
+	}
 	return &Chain{
 		cfg:      cfg,
 		conn:     conn,
@@ -119,6 +186,7 @@ func (c *Chain) Start() error {
 	if err != nil {
 		return err
 	}
+	c.writer.start()
 	c.conn.log.Debug("Successfully started chain", "chainId", c.cfg.Id)
 	return nil
 }
@@ -132,6 +200,11 @@ func (c *Chain) LatestBlock() metrics.LatestBlock {
 	return c.listener.latestBlock
 }
 
+// BalanceMonitor returns the monitor of the relayer balance, nil if no thresholds are configured
+func (c *Chain) BalanceMonitor() *balance.Monitor {
+	return c.writer.balanceMonitor
+}
+
 func (c *Chain) Id() msg.ChainId {
 	return c.cfg.Id
 }
@@ -142,4 +215,5 @@ func (c *Chain) Name() string {
 
 func (c *Chain) Stop() {
 	close(c.stop)
+	c.conn.Close()
 }
@@ -4,11 +4,20 @@
 package substrate
 
 import (
+	"fmt"
 	"strconv"
+	"time"
 
+	"github.com/ChainSafe/ChainBridge/chains/balance"
 	"github.com/ChainSafe/chainbridge-utils/core"
 )
 
+// Default number of blocks extrinsics are valid for
+const DefaultEraPeriod = 64
+
+// Default time to wait for extrinsics to be finalized, if waitForFinality is enabled
+const DefaultFinalityTimeout = time.Minute * 5
+
 func parseStartBlock(cfg *core.ChainConfig) uint64 {
 	if blk, ok := cfg.Opts["startBlock"]; ok {
 		res, err := strconv.ParseUint(blk, 10, 32)
@@ -30,3 +39,109 @@ func parseUseExtended(cfg *core.ChainConfig) bool {
 	}
 	return false
 }
+
+func parseEraPeriod(cfg *core.ChainConfig) uint64 {
+	if period, ok := cfg.Opts["eraPeriod"]; ok {
+		res, err := strconv.ParseUint(period, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		return res
+	}
+	return DefaultEraPeriod
+}
+
+// parseTip returns the tip and max tip, the max tip defaults to the tip
+func parseTip(cfg *core.ChainConfig) (uint64, uint64) {
+	var tip, maxTip uint64
+	var err error
+	if t, ok := cfg.Opts["tip"]; ok {
+		tip, err = strconv.ParseUint(t, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+	}
+	maxTip = tip
+	if t, ok := cfg.Opts["maxTip"]; ok {
+		maxTip, err = strconv.ParseUint(t, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if maxTip < tip {
+		maxTip = tip
+	}
+	return tip, maxTip
+}
+
+// parseFinalityTimeout returns the time to wait for extrinsics to be finalized, 0 if waitForFinality is disabled
+func parseFinalityTimeout(cfg *core.ChainConfig) time.Duration {
+	if wait, ok := cfg.Opts["waitForFinality"]; ok {
+		res, err := strconv.ParseBool(wait)
+		if err != nil {
+			panic(err)
+		}
+		if !res {
+			return 0
+		}
+	} else {
+		return 0
+	}
+
+	if timeout, ok := cfg.Opts["finalityTimeout"]; ok {
+		res, err := strconv.ParseUint(timeout, 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		if res > 0 {
+			return time.Duration(res) * time.Second
+		}
+	}
+	return DefaultFinalityTimeout
+}
+
+// parseDepositPolicy returns the path to the rules deposits from this chain must conform to, empty if none are set
+func parseDepositPolicy(cfg *core.ChainConfig) string {
+	if path, ok := cfg.Opts["depositPolicy"]; ok {
+		return path
+	}
+	return ""
+}
+
+// parseRateLimits returns the path to the volume caps of transfers to this chain, empty if none are set
+func parseRateLimits(cfg *core.ChainConfig) string {
+	if path, ok := cfg.Opts["rateLimits"]; ok {
+		return path
+	}
+	return ""
+}
+
+// parseApprovalThresholds returns the path to the amounts above which transfers require approval, empty if none are
+// set
+func parseApprovalThresholds(cfg *core.ChainConfig) string {
+	if path, ok := cfg.Opts["approvalThresholds"]; ok {
+		return path
+	}
+	return ""
+}
+
+// parseBalanceThresholds returns the balances below which the relayer balance is reported, and whether voting is
+// paused below the critical threshold
+func parseBalanceThresholds(cfg *core.ChainConfig) (balance.Thresholds, bool, error) {
+	thresholds, err := balance.ParseThresholds(cfg.Opts["balanceWarning"], cfg.Opts["balanceCritical"])
+	if err != nil {
+		return thresholds, false, fmt.Errorf("unable to parse balance thresholds: %w", err)
+	}
+
+	var pause bool
+	if p, ok := cfg.Opts["pauseOnLowBalance"]; ok && p != "" {
+		pause, err = strconv.ParseBool(p)
+		if err != nil {
+			return thresholds, false, fmt.Errorf("unable to parse pauseOnLowBalance")
+		}
+		if pause && thresholds.Critical == nil {
+			return thresholds, false, fmt.Errorf("pauseOnLowBalance requires balanceCritical")
+		}
+	}
+	return thresholds, pause, nil
+}
@@ -5,6 +5,7 @@ package substrate
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ChainSafe/chainbridge-utils/core"
 )
@@ -28,3 +29,80 @@ func TestParseStartBlock(t *testing.T) {
 		t.Fatalf("Got: %d Expected: %d", blk, 0)
 	}
 }
+
+func TestParseEraPeriod(t *testing.T) {
+	cfg := &core.ChainConfig{Opts: map[string]string{"eraPeriod": "128"}}
+	if period := parseEraPeriod(cfg); period != 128 {
+		t.Fatalf("Got: %d Expected: %d", period, 128)
+	}
+
+	cfg = &core.ChainConfig{Opts: map[string]string{}}
+	if period := parseEraPeriod(cfg); period != DefaultEraPeriod {
+		t.Fatalf("Got: %d Expected: %d", period, DefaultEraPeriod)
+	}
+}
+
+func TestParseTip(t *testing.T) {
+	cfg := &core.ChainConfig{Opts: map[string]string{"tip": "100", "maxTip": "500"}}
+	tip, maxTip := parseTip(cfg)
+	if tip != 100 || maxTip != 500 {
+		t.Fatalf("Got: %d, %d Expected: %d, %d", tip, maxTip, 100, 500)
+	}
+
+	// Max tip defaults to the tip
+	cfg = &core.ChainConfig{Opts: map[string]string{"tip": "100"}}
+	tip, maxTip = parseTip(cfg)
+	if tip != 100 || maxTip != 100 {
+		t.Fatalf("Got: %d, %d Expected: %d, %d", tip, maxTip, 100, 100)
+	}
+
+	cfg = &core.ChainConfig{Opts: map[string]string{}}
+	tip, maxTip = parseTip(cfg)
+	if tip != 0 || maxTip != 0 {
+		t.Fatalf("Got: %d, %d Expected: %d, %d", tip, maxTip, 0, 0)
+	}
+}
+
+func TestParseFinalityTimeout(t *testing.T) {
+	cfg := &core.ChainConfig{Opts: map[string]string{"waitForFinality": "true", "finalityTimeout": "60"}}
+	if timeout := parseFinalityTimeout(cfg); timeout != time.Minute {
+		t.Fatalf("Got: %s Expected: %s", timeout, time.Minute)
+	}
+
+	cfg = &core.ChainConfig{Opts: map[string]string{"waitForFinality": "true"}}
+	if timeout := parseFinalityTimeout(cfg); timeout != DefaultFinalityTimeout {
+		t.Fatalf("Got: %s Expected: %s", timeout, DefaultFinalityTimeout)
+	}
+
+	// Timeout is ignored unless waiting for finality
+	cfg = &core.ChainConfig{Opts: map[string]string{"finalityTimeout": "60"}}
+	if timeout := parseFinalityTimeout(cfg); timeout != 0 {
+		t.Fatalf("Got: %s Expected: %d", timeout, 0)
+	}
+}
+
+func TestParseBalanceThresholds(t *testing.T) {
+	cfg := &core.ChainConfig{Opts: map[string]string{"balanceWarning": "1000", "balanceCritical": "100", "pauseOnLowBalance": "true"}}
+	thresholds, pause, err := parseBalanceThresholds(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thresholds.Warning.Int64() != 1000 || thresholds.Critical.Int64() != 100 || !pause {
+		t.Fatalf("Got: %v, %v, %t Expected: %d, %d, %t", thresholds.Warning, thresholds.Critical, pause, 1000, 100, true)
+	}
+
+	cfg = &core.ChainConfig{Opts: map[string]string{}}
+	thresholds, pause, err = parseBalanceThresholds(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thresholds.Enabled() || pause {
+		t.Fatalf("Got: %v, %t Expected no thresholds", thresholds, pause)
+	}
+
+	// Voting can only be paused below a critical threshold
+	cfg = &core.ChainConfig{Opts: map[string]string{"balanceWarning": "1000", "pauseOnLowBalance": "true"}}
+	if _, _, err = parseBalanceThresholds(cfg); err == nil {
+		t.Fatal("Expected error for pauseOnLowBalance without balanceCritical")
+	}
+}
@@ -4,8 +4,11 @@
 package substrate
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"sync"
+	"time"
 
 	utils "github.com/ChainSafe/ChainBridge/shared/substrate"
 	"github.com/ChainSafe/chainbridge-utils/msg"
@@ -16,6 +19,16 @@ import (
 	"github.com/centrifuge/go-substrate-rpc-client/types"
 )
 
+// Number of times an expired extrinsic is rebuilt before giving up
+const ExtrinsicRebuildLimit = 3
+
+// Increase of the tip, in percent, each time an expired extrinsic is rebuilt
+const TipBumpPercent = 20
+
+var ErrExtrinsicExpired = errors.New("extrinsic expired before inclusion")
+var ErrExtrinsicRetracted = errors.New("extrinsic retracted before finalization")
+var ErrFinalityTimeout = errors.New("extrinsic not finalized before timeout")
+
 type Connection struct {
 	api         *gsrpc.SubstrateAPI
 	log         log15.Logger
@@ -27,12 +40,56 @@ type Connection struct {
 	key         *signature.KeyringPair // Keyring used for signing
 	nonce       types.U32              // Latest account nonce
 	nonceLock   sync.Mutex             // Locks nonce for updates
+	state       ConnectionState        // Current state of the connection
+	stateLock   sync.Mutex             // Locks state, api and genesisHash, which are replaced when reconnecting
+	reconnected chan struct{}          // Closed when the latest reconnection attempt has finished
+	metrics     *connectionMetrics     // Reports the state of the connection, may be nil
+	eraPeriod   uint64                 // Number of blocks extrinsics are valid for, 0 for immortal extrinsics
+	tip         uint64                 // Tip included with extrinsics
+	maxTip      uint64                 // Maximum tip when rebuilding expired extrinsics
+	finality    time.Duration          // Time to wait for extrinsics to be finalized, 0 only waits for inclusion
 	stop        <-chan int             // Signals system shutdown, should be observed in all selects and loops
 	sysErr      chan<- error           // Propagates fatal errors to core
 }
 
 func NewConnection(url string, name string, key *signature.KeyringPair, log log15.Logger, stop <-chan int, sysErr chan<- error) *Connection {
-	return &Connection{url: url, name: name, key: key, log: log, stop: stop, sysErr: sysErr}
+	return &Connection{url: url, name: name, key: key, log: log, eraPeriod: DefaultEraPeriod, stop: stop, sysErr: sysErr}
+}
+
+// setEraPeriod sets the number of blocks extrinsics are valid for, 0 submits immortal extrinsics
+func (c *Connection) setEraPeriod(period uint64) {
+	c.eraPeriod = period
+}
+
+// setTip sets the tip included with extrinsics. The tip of an expired extrinsic is increased by
+// TipBumpPercent each time it is rebuilt, up to maxTip.
+func (c *Connection) setTip(tip, maxTip uint64) {
+	c.tip = tip
+	c.maxTip = maxTip
+}
+
+// setFinalityTimeout makes SubmitTx wait for extrinsics to be finalized, rather than included in a block, for
+// up to the timeout. A timeout of 0 only waits for inclusion.
+func (c *Connection) setFinalityTimeout(timeout time.Duration) {
+	c.finality = timeout
+}
+
+// setMetrics enables reporting of the connection state, must be called before Connect
+func (c *Connection) setMetrics(m *connectionMetrics) {
+	c.metrics = m
+}
+
+// getApi returns the API of the current connection
+func (c *Connection) getApi() *gsrpc.SubstrateAPI {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	return c.api
+}
+
+func (c *Connection) getGenesisHash() types.Hash {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	return c.genesisHash
 }
 
 func (c *Connection) getMetadata() (meta types.Metadata) {
@@ -44,7 +101,7 @@ func (c *Connection) getMetadata() (meta types.Metadata) {
 
 func (c *Connection) updateMetatdata() error {
 	c.metaLock.Lock()
-	meta, err := c.api.RPC.State.GetMetadataLatest()
+	meta, err := c.getApi().RPC.State.GetMetadataLatest()
 	if err != nil {
 		c.metaLock.Unlock()
 		return err
@@ -56,38 +113,76 @@ func (c *Connection) updateMetatdata() error {
 
 func (c *Connection) Connect() error {
 	c.log.Info("Connecting to substrate chain...", "url", c.url)
+	err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	c.stateLock.Lock()
+	c.setState(Connected)
+	c.reconnected = make(chan struct{})
+	close(c.reconnected)
+	c.stateLock.Unlock()
+	return nil
+}
+
+// connect establishes a new connection to the node and fetches the metadata and genesis hash,
+// then replaces the current connection
+func (c *Connection) connect() error {
 	api, err := gsrpc.NewSubstrateAPI(c.url)
 	if err != nil {
 		return err
 	}
-	c.api = api
 
 	// Fetch metadata
 	meta, err := api.RPC.State.GetMetadataLatest()
 	if err != nil {
+		closeApi(api)
 		return err
 	}
-	c.meta = *meta
 	c.log.Debug("Fetched substrate metadata")
 
 	// Fetch genesis hash
-	genesisHash, err := c.api.RPC.Chain.GetBlockHash(0)
+	genesisHash, err := api.RPC.Chain.GetBlockHash(0)
 	if err != nil {
+		closeApi(api)
 		return err
 	}
-	c.genesisHash = genesisHash
 	c.log.Debug("Fetched substrate genesis hash", "hash", genesisHash.Hex())
+
+	c.metaLock.Lock()
+	c.meta = *meta
+	c.metaLock.Unlock()
+
+	c.stateLock.Lock()
+	previous := c.api
+	c.api = api
+	c.genesisHash = genesisHash
+	c.stateLock.Unlock()
+
+	if previous != nil {
+		closeApi(previous)
+	}
 	return nil
 }
 
+// closeApi closes the websocket connection of the API, GSRPC does not expose this directly
+func closeApi(api *gsrpc.SubstrateAPI) {
+	if closer, ok := api.Client.(interface{ Close() }); ok {
+		closer.Close()
+	}
+}
+
 // SubmitTx constructs and submits an extrinsic to call the method with the given arguments.
 // All args are passed directly into GSRPC. GSRPC types are recommended to avoid serialization inconsistencies.
+// If a mortal extrinsic expires before it is included, it is rebuilt with a fresh era and a higher tip, up to
+// ExtrinsicRebuildLimit times.
 func (c *Connection) SubmitTx(method utils.Method, args ...interface{}) error {
 	c.log.Debug("Submitting substrate call...", "method", method, "sender", c.key.Address)
 
 	meta := c.getMetadata()
 
-	// Create call and extrinsic
+	// Create call
 	call, err := types.NewCall(
 		&meta,
 		string(method),
@@ -96,14 +191,36 @@ func (c *Connection) SubmitTx(method utils.Method, args ...interface{}) error {
 	if err != nil {
 		return fmt.Errorf("failed to construct call: %w", err)
 	}
+
+	tip := c.tip
+	for attempt := 1; ; attempt++ {
+		err = c.signAndSubmit(call, tip)
+		if err != ErrExtrinsicExpired || attempt == ExtrinsicRebuildLimit {
+			return err
+		}
+		tip = nextTip(tip, c.maxTip)
+		c.log.Warn("Extrinsic expired, rebuilding with a fresh era", "method", method, "attempt", attempt, "tip", tip)
+	}
+}
+
+// signAndSubmit signs the call with the next nonce, an era starting at the latest finalized block and the tip,
+// then submits the extrinsic and waits for it to be included in a block
+func (c *Connection) signAndSubmit(call types.Call, tip uint64) error {
 	ext := types.NewExtrinsic(call)
 
 	// Get latest runtime version
-	rv, err := c.api.RPC.State.GetRuntimeVersionLatest()
+	api := c.getApi()
+	genesisHash := c.getGenesisHash()
+	rv, err := api.RPC.State.GetRuntimeVersionLatest()
 	if err != nil {
 		return err
 	}
 
+	era, blockHash, m, err := c.newEra(api, genesisHash)
+	if err != nil {
+		return fmt.Errorf("failed to construct era: %w", err)
+	}
+
 	c.nonceLock.Lock()
 	latestNonce, err := c.getLatestNonce()
 	if err != nil {
@@ -113,15 +230,16 @@ func (c *Connection) SubmitTx(method utils.Method, args ...interface{}) error {
 	if latestNonce > c.nonce {
 		c.nonce = latestNonce
 	}
+	nonce := c.nonce
 
 	// Sign the extrinsic
 	o := types.SignatureOptions{
-		BlockHash:          c.genesisHash,
-		Era:                types.ExtrinsicEra{IsMortalEra: false},
-		GenesisHash:        c.genesisHash,
-		Nonce:              types.NewUCompactFromUInt(uint64(c.nonce)),
+		BlockHash:          blockHash,
+		Era:                era,
+		GenesisHash:        genesisHash,
+		Nonce:              types.NewUCompactFromUInt(uint64(nonce)),
 		SpecVersion:        rv.SpecVersion,
-		Tip:                types.NewUCompactFromUInt(0),
+		Tip:                types.NewUCompactFromUInt(tip),
 		TransactionVersion: rv.TransactionVersion,
 	}
 
@@ -132,33 +250,123 @@ func (c *Connection) SubmitTx(method utils.Method, args ...interface{}) error {
 	}
 
 	// Submit and watch the extrinsic
-	sub, err := c.api.RPC.Author.SubmitAndWatchExtrinsic(ext)
+	sub, err := api.RPC.Author.SubmitAndWatchExtrinsic(ext)
 	c.nonce++
 	c.nonceLock.Unlock()
 	if err != nil {
 		return fmt.Errorf("submission of extrinsic failed: %w", err)
 	}
-	c.log.Trace("Extrinsic submission succeeded")
+	c.log.Trace("Extrinsic submission succeeded", "nonce", nonce, "tip", tip)
 	defer sub.Unsubscribe()
 
-	return c.watchSubmission(sub)
+	err = c.watchSubmission(sub, m)
+	if err == ErrExtrinsicExpired || err == ErrExtrinsicRetracted {
+		c.releaseNonce(nonce)
+	}
+	return err
+}
+
+// newEra returns the era for a new extrinsic and the hash of the block it starts at. Mortal eras start at
+// the latest finalized block, so they can't be invalidated by a reorg.
+func (c *Connection) newEra(api *gsrpc.SubstrateAPI, genesisHash types.Hash) (types.ExtrinsicEra, types.Hash, *mortality, error) {
+	if c.eraPeriod == 0 {
+		return types.ExtrinsicEra{IsImmortalEra: true}, genesisHash, nil, nil
+	}
+
+	finalizedHash, err := api.RPC.Chain.GetFinalizedHead()
+	if err != nil {
+		return types.ExtrinsicEra{}, types.Hash{}, nil, err
+	}
+	finalized, err := api.RPC.Chain.GetHeader(finalizedHash)
+	if err != nil {
+		return types.ExtrinsicEra{}, types.Hash{}, nil, err
+	}
+
+	era, m := mortalEra(uint64(finalized.Number), c.eraPeriod)
+	if m.birth == uint64(finalized.Number) {
+		return era, finalizedHash, m, nil
+	}
+	birthHash, err := api.RPC.Chain.GetBlockHash(m.birth)
+	if err != nil {
+		return types.ExtrinsicEra{}, types.Hash{}, nil, err
+	}
+	return era, birthHash, m, nil
+}
+
+// releaseNonce allows the nonce of an expired extrinsic to be used again, unless a later nonce was already used
+func (c *Connection) releaseNonce(nonce types.U32) {
+	c.nonceLock.Lock()
+	defer c.nonceLock.Unlock()
+
+	if c.nonce == nonce+1 {
+		c.nonce = nonce
+	}
+}
+
+// isExpired returns true if the latest block is past the end of the era
+func (c *Connection) isExpired(m *mortality) bool {
+	header, err := c.getApi().RPC.Chain.GetHeaderLatest()
+	if err != nil {
+		c.log.Debug("Failed to fetch latest header", "err", err)
+		return false
+	}
+	return m.expired(uint64(header.Number))
 }
 
-func (c *Connection) watchSubmission(sub *author.ExtrinsicStatusSubscription) error {
+// watchSubmission waits for the extrinsic to be included in a block, or finalized if a finality timeout is set.
+// If it has a mortal era, ErrExtrinsicExpired is returned once the era has ended before inclusion.
+// ErrExtrinsicRetracted is returned if the block including it is retracted before it is finalized.
+func (c *Connection) watchSubmission(sub *author.ExtrinsicStatusSubscription, m *mortality) error {
+	var expiry <-chan time.Time
+	if m != nil {
+		ticker := time.NewTicker(BlockRetryInterval)
+		defer ticker.Stop()
+		expiry = ticker.C
+	}
+
+	var inBlock *types.Hash
+	var timeout <-chan time.Time
 	for {
 		select {
 		case <-c.stop:
 			return TerminatedError
+		case <-expiry:
+			if inBlock == nil && c.isExpired(m) {
+				return ErrExtrinsicExpired
+			}
+		case <-timeout:
+			c.log.Warn("Extrinsic not finalized before timeout", "block", inBlock.Hex(), "timeout", c.finality)
+			return ErrFinalityTimeout
 		case status := <-sub.Chan():
 			switch {
 			case status.IsInBlock:
 				c.log.Trace("Extrinsic included in block", "block", status.AsInBlock.Hex())
+				if c.finality == 0 {
+					return nil
+				}
+				if inBlock == nil {
+					timeout = time.After(c.finality)
+				}
+				block := status.AsInBlock
+				inBlock = &block
+			case status.IsFinalized:
+				c.log.Trace("Extrinsic finalized", "block", status.AsFinalized.Hex())
 				return nil
 			case status.IsRetracted:
-				return fmt.Errorf("extrinsic retracted: %s", status.AsRetracted.Hex())
-			case status.IsDropped:
-				return fmt.Errorf("extrinsic dropped from network")
-			case status.IsInvalid:
+				c.log.Warn("Block including extrinsic was retracted", "block", status.AsRetracted.Hex())
+				return ErrExtrinsicRetracted
+			case status.IsFinalityTimeout:
+				return ErrFinalityTimeout
+			case status.IsUsurped:
+				return fmt.Errorf("extrinsic usurped by %s", status.AsUsurped.Hex())
+			case status.IsDropped, status.IsInvalid:
+				// Expired extrinsics are removed from the pool
+				if m != nil && c.isExpired(m) {
+					return ErrExtrinsicExpired
+				}
+				if status.IsDropped {
+					return fmt.Errorf("extrinsic dropped from network")
+				}
 				return fmt.Errorf("extrinsic invalid")
 			}
 		case err := <-sub.Err():
@@ -176,7 +384,7 @@ func (c *Connection) queryStorage(prefix, method string, arg1, arg2 []byte, resu
 	if err != nil {
 		return false, err
 	}
-	return c.api.RPC.State.GetStorageLatest(key, result)
+	return c.getApi().RPC.State.GetStorageLatest(key, result)
 }
 
 // TODO: Add this to GSRPC
@@ -224,6 +432,31 @@ func (c *Connection) getLatestNonce() (types.U32, error) {
 
 	return acct.Nonce, nil
 }
+
+// getBalance returns the free balance of the relayer account
+func (c *Connection) getBalance() (*big.Int, error) {
+	var acct types.AccountInfo
+	exists, err := c.queryStorage("System", "Account", c.key.PublicKey, nil, &acct)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return big.NewInt(0), nil
+	}
+
+	return acct.Data.Free.Int, nil
+}
+
+// Close closes the connection to the node, it can't be used afterwards
 func (c *Connection) Close() {
-	// TODO: Anything required to shutdown GRPC?
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.state == Closed {
+		return
+	}
+	c.setState(Closed)
+	if c.api != nil {
+		closeApi(c.api)
+	}
 }
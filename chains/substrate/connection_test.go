@@ -5,6 +5,7 @@ package substrate
 
 import (
 	"testing"
+	"time"
 
 	"github.com/centrifuge/go-substrate-rpc-client/types"
 )
@@ -87,3 +88,81 @@ func TestConnect_SubmitTx(t *testing.T) {
 		return
 	}
 }
+
+func TestConnect_Reconnect(t *testing.T) {
+	backoff := ReconnectInitialBackoff
+	ReconnectInitialBackoff = time.Millisecond
+	defer func() { ReconnectInitialBackoff = backoff }()
+
+	conn := NewConnection(TestEndpoint, "Alice", AliceKey, AliceTestLogger, make(chan int), make(chan error))
+	err := conn.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Drop the connection to the node
+	api := conn.getApi()
+	closeApi(api)
+
+	var data types.AccountInfo
+	_, err = conn.queryStorage("System", "Account", conn.key.PublicKey, nil, &data)
+	if err == nil {
+		t.Fatal("expected query to fail after losing the connection")
+	}
+
+	if !conn.ensureConnected() {
+		t.Fatal("expected connection to be re-established")
+	}
+	if conn.State() != Connected {
+		t.Fatalf("expected state %s, got %s", Connected, conn.State())
+	}
+	if conn.getApi() == api {
+		t.Fatal("expected connection to be replaced")
+	}
+
+	_, err = conn.queryStorage("System", "Account", conn.key.PublicKey, nil, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failure unrelated to the connection does not cause a reconnect
+	if conn.ensureConnected() {
+		t.Fatal("expected healthy connection not to be re-established")
+	}
+}
+
+func TestConnect_Close(t *testing.T) {
+	conn := NewConnection(TestEndpoint, "Alice", AliceKey, AliceTestLogger, make(chan int), make(chan error))
+	err := conn.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Close()
+	if conn.State() != Closed {
+		t.Fatalf("expected state %s, got %s", Closed, conn.State())
+	}
+	if conn.ensureConnected() {
+		t.Fatal("closed connection should not be re-established")
+	}
+	// Closing again has no effect
+	conn.Close()
+}
+
+func TestNextBackoff(t *testing.T) {
+	backoff := ReconnectInitialBackoff
+	for i := 0; i < 10; i++ {
+		next := nextBackoff(backoff)
+		if next > ReconnectMaxBackoff {
+			t.Fatalf("backoff %s exceeds max %s", next, ReconnectMaxBackoff)
+		}
+		if next < backoff {
+			t.Fatalf("backoff decreased from %s to %s", backoff, next)
+		}
+		backoff = next
+	}
+	if backoff != ReconnectMaxBackoff {
+		t.Fatalf("expected backoff to reach %s, got %s", ReconnectMaxBackoff, backoff)
+	}
+}
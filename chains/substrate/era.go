@@ -0,0 +1,78 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"math/bits"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// Bounds of the period of a mortal era, as enforced by substrate
+const minEraPeriod = 4
+const maxEraPeriod = 1 << 16
+
+// mortality describes the blocks in which a mortal extrinsic is valid
+type mortality struct {
+	birth  uint64 // Block the era starts at, its hash is included in the signature
+	period uint64
+}
+
+// expired returns true if the extrinsic can no longer be included after the given block
+func (m *mortality) expired(block uint64) bool {
+	return block >= m.birth+m.period
+}
+
+// mortalEra returns an era valid for at least the given number of blocks, starting at or shortly before
+// current. The encoding follows sp_runtime::generic::Era::mortal.
+func mortalEra(current, period uint64) (types.ExtrinsicEra, *mortality) {
+	// The period is rounded up to a power of two
+	if period > maxEraPeriod {
+		period = maxEraPeriod
+	}
+	if period < minEraPeriod {
+		period = minEraPeriod
+	}
+	if period&(period-1) != 0 {
+		period = 1 << bits.Len64(period)
+	}
+
+	phase := current % period
+	quantizeFactor := period >> 12
+	if quantizeFactor < 1 {
+		quantizeFactor = 1
+	}
+	quantizedPhase := phase / quantizeFactor * quantizeFactor
+
+	periodBits := uint64(bits.TrailingZeros64(period)) - 1
+	if periodBits > 15 {
+		periodBits = 15
+	}
+	if periodBits < 1 {
+		periodBits = 1
+	}
+	encoded := uint16(periodBits) | uint16(quantizedPhase/quantizeFactor)<<4
+
+	era := types.ExtrinsicEra{
+		IsMortalEra: true,
+		AsMortalEra: types.MortalEra{First: byte(encoded), Second: byte(encoded >> 8)},
+	}
+	birth := (current-quantizedPhase)/period*period + quantizedPhase
+	return era, &mortality{birth: birth, period: period}
+}
+
+// nextTip increases the tip by TipBumpPercent, up to maxTip
+func nextTip(tip, maxTip uint64) uint64 {
+	if tip >= maxTip {
+		return tip
+	}
+	bump := tip * TipBumpPercent / 100
+	if bump == 0 {
+		bump = 1
+	}
+	if tip+bump > maxTip {
+		return maxTip
+	}
+	return tip + bump
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"testing"
+)
+
+func TestMortalEra(t *testing.T) {
+	testCases := []struct {
+		current  uint64
+		period   uint64
+		encoded  [2]byte
+		birth    uint64
+		expected uint64
+	}{
+		{current: 42, period: 64, encoded: [2]byte{0xa5, 0x02}, birth: 42, expected: 64},
+		{current: 20000, period: 32768, encoded: [2]byte{0x4e, 0x9c}, birth: 20000, expected: 32768},
+		// Period is rounded up to a power of two
+		{current: 100, period: 50, encoded: [2]byte{0x45, 0x02}, birth: 100, expected: 64},
+		{current: 5, period: 1, encoded: [2]byte{0x11, 0x00}, birth: 5, expected: 4},
+	}
+
+	for _, tc := range testCases {
+		era, m := mortalEra(tc.current, tc.period)
+		if !era.IsMortalEra {
+			t.Fatalf("expected mortal era for block %d", tc.current)
+		}
+		if era.AsMortalEra.First != tc.encoded[0] || era.AsMortalEra.Second != tc.encoded[1] {
+			t.Fatalf("block %d: expected encoding %x, got %x", tc.current, tc.encoded, []byte{era.AsMortalEra.First, era.AsMortalEra.Second})
+		}
+		if m.birth != tc.birth || m.period != tc.expected {
+			t.Fatalf("block %d: expected birth %d period %d, got %d %d", tc.current, tc.birth, tc.expected, m.birth, m.period)
+		}
+		if m.expired(tc.current) {
+			t.Fatalf("block %d: era expired at its start", tc.current)
+		}
+		if !m.expired(m.birth + m.period) {
+			t.Fatalf("block %d: era not expired after its period", tc.current)
+		}
+	}
+}
+
+func TestNextTip(t *testing.T) {
+	if tip := nextTip(0, 10); tip != 1 {
+		t.Fatalf("Got: %d Expected: %d", tip, 1)
+	}
+	if tip := nextTip(100, 1000); tip != 120 {
+		t.Fatalf("Got: %d Expected: %d", tip, 120)
+	}
+	if tip := nextTip(100, 110); tip != 110 {
+		t.Fatalf("Got: %d Expected: %d", tip, 110)
+	}
+	if tip := nextTip(100, 100); tip != 100 {
+		t.Fatalf("Got: %d Expected: %d", tip, 100)
+	}
+}
@@ -7,13 +7,14 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/ChainSafe/ChainBridge/chains"
 	events "github.com/ChainSafe/chainbridge-substrate-events"
 	"github.com/ChainSafe/chainbridge-utils/msg"
 	"github.com/ChainSafe/log15"
 )
 
 type eventName string
-type eventHandler func(interface{}, log15.Logger) (msg.Message, error)
+type eventHandler func(interface{}, msg.ChainId, log15.Logger) (msg.Message, error)
 
 const FungibleTransfer eventName = "FungibleTransfer"
 const NonFungibleTransfer eventName = "NonFungibleTransfer"
@@ -28,57 +29,57 @@ var Subscriptions = []struct {
 	{GenericTransfer, genericTransferHandler},
 }
 
-func fungibleTransferHandler(evtI interface{}, log log15.Logger) (msg.Message, error) {
+func fungibleTransferHandler(evtI interface{}, src msg.ChainId, log log15.Logger) (msg.Message, error) {
 	evt, ok := evtI.(events.EventFungibleTransfer)
 	if !ok {
 		return msg.Message{}, fmt.Errorf("failed to cast EventFungibleTransfer type")
 	}
 
 	resourceId := msg.ResourceId(evt.ResourceId)
-	log.Info("Got fungible transfer event!", "destination", evt.Destination, "resourceId", resourceId.Hex(), "amount", evt.Amount)
-
-	return msg.NewFungibleTransfer(
-		0, // Unset
+	m := msg.NewFungibleTransfer(
+		src,
 		msg.ChainId(evt.Destination),
 		msg.Nonce(evt.DepositNonce),
 		evt.Amount.Int,
 		resourceId,
 		evt.Recipient,
-	), nil
+	)
+	chains.TransferLogger(log, m).Info("Got fungible transfer event!", "src", src, "dst", evt.Destination, "nonce", evt.DepositNonce, "resourceId", resourceId.Hex(), "amount", evt.Amount)
+	return m, nil
 }
 
-func nonFungibleTransferHandler(evtI interface{}, log log15.Logger) (msg.Message, error) {
+func nonFungibleTransferHandler(evtI interface{}, src msg.ChainId, log log15.Logger) (msg.Message, error) {
 	evt, ok := evtI.(events.EventNonFungibleTransfer)
 	if !ok {
 		return msg.Message{}, fmt.Errorf("failed to cast EventNonFungibleTransfer type")
 	}
 
-	log.Info("Got non-fungible transfer event!", "destination", evt.Destination, "resourceId", evt.ResourceId)
-
-	return msg.NewNonFungibleTransfer(
-		0, // Unset
+	m := msg.NewNonFungibleTransfer(
+		src,
 		msg.ChainId(evt.Destination),
 		msg.Nonce(evt.DepositNonce),
 		msg.ResourceId(evt.ResourceId),
 		big.NewInt(0).SetBytes(evt.TokenId[:]),
 		evt.Recipient,
 		evt.Metadata,
-	), nil
+	)
+	chains.TransferLogger(log, m).Info("Got non-fungible transfer event!", "src", src, "dst", evt.Destination, "nonce", evt.DepositNonce, "resourceId", evt.ResourceId)
+	return m, nil
 }
 
-func genericTransferHandler(evtI interface{}, log log15.Logger) (msg.Message, error) {
+func genericTransferHandler(evtI interface{}, src msg.ChainId, log log15.Logger) (msg.Message, error) {
 	evt, ok := evtI.(events.EventGenericTransfer)
 	if !ok {
 		return msg.Message{}, fmt.Errorf("failed to cast EventGenericTransfer type")
 	}
 
-	log.Info("Got generic transfer event!", "destination", evt.Destination, "resourceId", evt.ResourceId)
-
-	return msg.NewGenericTransfer(
-		0, // Unset
+	m := msg.NewGenericTransfer(
+		src,
 		msg.ChainId(evt.Destination),
 		msg.Nonce(evt.DepositNonce),
 		msg.ResourceId(evt.ResourceId),
 		evt.Metadata,
-	), nil
+	)
+	chains.TransferLogger(log, m).Info("Got generic transfer event!", "src", src, "dst", evt.Destination, "nonce", evt.DepositNonce, "resourceId", evt.ResourceId)
+	return m, nil
 }
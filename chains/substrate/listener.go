@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/ChainSafe/ChainBridge/chains"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/ChainBridge/chains/policy"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
 	utils "github.com/ChainSafe/ChainBridge/shared/substrate"
 	"github.com/ChainSafe/chainbridge-utils/blockstore"
 	metrics "github.com/ChainSafe/chainbridge-utils/metrics/types"
@@ -26,6 +29,9 @@ type listener struct {
 	conn          *Connection
 	subscriptions map[eventName]eventHandler // Handlers for specific events
 	router        chains.Router
+	outbox        *outbox.Outbox
+	transfers     *transfers.Tracker
+	policy        *policy.Policy // Deposits violating the policy are skipped, may be nil
 	log           log15.Logger
 	stop          <-chan int
 	sysErr        chan<- error
@@ -57,10 +63,25 @@ func (l *listener) setRouter(r chains.Router) {
 	l.router = r
 }
 
+// setOutbox sets the outbox messages are persisted to before being routed
+func (l *listener) setOutbox(ob *outbox.Outbox) {
+	l.outbox = ob
+}
+
+// setTransfers sets the index deposits are recorded in
+func (l *listener) setTransfers(t *transfers.Tracker) {
+	l.transfers = t
+}
+
+// setDepositPolicy sets the policy deposits must conform to before they are relayed
+func (l *listener) setDepositPolicy(p *policy.Policy) {
+	l.policy = p
+}
+
 // start creates the initial subscription for all events
 func (l *listener) start() error {
 	// Check whether latest is less than starting block
-	header, err := l.conn.api.RPC.Chain.GetHeaderLatest()
+	header, err := l.conn.getApi().RPC.Chain.GetHeaderLatest()
 	if err != nil {
 		return err
 	}
@@ -75,6 +96,12 @@ func (l *listener) start() error {
 		}
 	}
 
+	l.replayOutbox()
+
+	if l.policy != nil {
+		go l.policy.Watch(l.stop)
+	}
+
 	go func() {
 		err := l.pollBlocks()
 		if err != nil {
@@ -98,7 +125,8 @@ var ErrBlockNotReady = errors.New("required result to be 32 bytes, but got 0")
 
 // pollBlocks will poll for the latest block and proceed to parse the associated events as it sees new blocks.
 // Polling begins at the block defined in `l.startBlock`. Failed attempts to fetch the latest block or parse
-// a block will be retried up to BlockRetryLimit times before returning with an error.
+// a block will be retried up to BlockRetryLimit times before returning with an error. Failures caused by a
+// lost connection are retried once it has been re-established, and do not count towards the limit.
 func (l *listener) pollBlocks() error {
 	var currentBlock = l.startBlock
 	var retry = BlockRetryLimit
@@ -114,20 +142,24 @@ func (l *listener) pollBlocks() error {
 			}
 
 			// Get finalized block hash
-			finalizedHash, err := l.conn.api.RPC.Chain.GetFinalizedHead()
+			finalizedHash, err := l.conn.getApi().RPC.Chain.GetFinalizedHead()
 			if err != nil {
 				l.log.Error("Failed to fetch finalized hash", "err", err)
-				retry--
-				time.Sleep(BlockRetryInterval)
+				if !l.conn.ensureConnected() {
+					retry--
+					time.Sleep(BlockRetryInterval)
+				}
 				continue
 			}
 
 			// Get finalized block header
-			finalizedHeader, err := l.conn.api.RPC.Chain.GetHeader(finalizedHash)
+			finalizedHeader, err := l.conn.getApi().RPC.Chain.GetHeader(finalizedHash)
 			if err != nil {
 				l.log.Error("Failed to fetch finalized header", "err", err)
-				retry--
-				time.Sleep(BlockRetryInterval)
+				if !l.conn.ensureConnected() {
+					retry--
+					time.Sleep(BlockRetryInterval)
+				}
 				continue
 			}
 
@@ -143,21 +175,25 @@ func (l *listener) pollBlocks() error {
 			}
 
 			// Get hash for latest block, sleep and retry if not ready
-			hash, err := l.conn.api.RPC.Chain.GetBlockHash(currentBlock)
+			hash, err := l.conn.getApi().RPC.Chain.GetBlockHash(currentBlock)
 			if err != nil && err.Error() == ErrBlockNotReady.Error() {
 				time.Sleep(BlockRetryInterval)
 				continue
 			} else if err != nil {
 				l.log.Error("Failed to query latest block", "block", currentBlock, "err", err)
-				retry--
-				time.Sleep(BlockRetryInterval)
+				if !l.conn.ensureConnected() {
+					retry--
+					time.Sleep(BlockRetryInterval)
+				}
 				continue
 			}
 
-			err = l.processEvents(hash)
+			err = l.processEvents(hash, currentBlock)
 			if err != nil {
 				l.log.Error("Failed to process events in block", "block", currentBlock, "err", err)
-				retry--
+				if !l.conn.ensureConnected() {
+					retry--
+				}
 				continue
 			}
 
@@ -181,7 +217,7 @@ func (l *listener) pollBlocks() error {
 }
 
 // processEvents fetches a block and parses out the events, calling Listener.handleEvents()
-func (l *listener) processEvents(hash types.Hash) error {
+func (l *listener) processEvents(hash types.Hash, block uint64) error {
 	l.log.Trace("Fetching block for events", "hash", hash.Hex())
 	meta := l.conn.getMetadata()
 	key, err := types.CreateStorageKey(&meta, "System", "Events", nil, nil)
@@ -190,7 +226,7 @@ func (l *listener) processEvents(hash types.Hash) error {
 	}
 
 	var records types.EventRecordsRaw
-	_, err = l.conn.api.RPC.State.GetStorage(key, &records, hash)
+	_, err = l.conn.getApi().RPC.State.GetStorage(key, &records, hash)
 	if err != nil {
 		return err
 	}
@@ -201,30 +237,46 @@ func (l *listener) processEvents(hash types.Hash) error {
 		return err
 	}
 
-	l.handleEvents(e)
+	err = l.handleEvents(e, block)
+	if err != nil {
+		return err
+	}
 	l.log.Trace("Finished processing events", "block", hash.Hex())
 
 	return nil
 }
 
-// handleEvents calls the associated handler for all registered event types
-func (l *listener) handleEvents(evts utils.Events) {
+// handleEvents calls the associated handler for all registered event types. An error is returned if a message could
+// not be persisted, so the block is processed again.
+func (l *listener) handleEvents(evts utils.Events, block uint64) error {
 	if l.subscriptions[FungibleTransfer] != nil {
 		for _, evt := range evts.ChainBridge_FungibleTransfer {
 			l.log.Trace("Handling FungibleTransfer event")
-			l.submitMessage(l.subscriptions[FungibleTransfer](evt, l.log))
+			m, err := l.subscriptions[FungibleTransfer](evt, l.chainId, l.log)
+			err = l.submitMessage(m, err, block)
+			if err != nil {
+				return err
+			}
 		}
 	}
 	if l.subscriptions[NonFungibleTransfer] != nil {
 		for _, evt := range evts.ChainBridge_NonFungibleTransfer {
 			l.log.Trace("Handling NonFungibleTransfer event")
-			l.submitMessage(l.subscriptions[NonFungibleTransfer](evt, l.log))
+			m, err := l.subscriptions[NonFungibleTransfer](evt, l.chainId, l.log)
+			err = l.submitMessage(m, err, block)
+			if err != nil {
+				return err
+			}
 		}
 	}
 	if l.subscriptions[GenericTransfer] != nil {
 		for _, evt := range evts.ChainBridge_GenericTransfer {
 			l.log.Trace("Handling GenericTransfer event")
-			l.submitMessage(l.subscriptions[GenericTransfer](evt, l.log))
+			m, err := l.subscriptions[GenericTransfer](evt, l.chainId, l.log)
+			err = l.submitMessage(m, err, block)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -235,17 +287,56 @@ func (l *listener) handleEvents(evts utils.Events) {
 			l.log.Error("Unable to update Metadata", "error", err)
 		}
 	}
+	return nil
 }
 
-// submitMessage inserts the chainId into the msg and sends it to the router
-func (l *listener) submitMessage(m msg.Message, err error) {
+// submitMessage sends the msg to the router, unless it violates the deposit policy. Returns an error if the message
+// could not be persisted to the outbox.
+func (l *listener) submitMessage(m msg.Message, err error, block uint64) error {
 	if err != nil {
 		log15.Error("Critical error processing event", "err", err)
-		return
+		return nil
+	}
+	log := chains.TransferLogger(l.log, m)
+
+	if l.policy != nil && !l.policy.Allowed(m) {
+		return nil
+	}
+
+	// Persist the message before routing, the block will not be processed again once it is stored
+	if l.outbox != nil {
+		err = l.outbox.Append(m)
+		if err != nil {
+			return fmt.Errorf("failed to persist message to outbox: %w", err)
+		}
+	}
+
+	if l.transfers != nil {
+		_, err = l.transfers.Update(m, transfers.Deposit(block, ""))
+		if err != nil {
+			log.Warn("Failed to update transfer index", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		}
 	}
-	m.Source = l.chainId
+
 	err = l.router.Send(m)
 	if err != nil {
-		log15.Error("failed to process event", "err", err)
+		log.Error("Failed to route message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+	}
+	return nil
+}
+
+// replayOutbox routes all messages from this chain that were not acknowledged by a writer before the last shutdown
+func (l *listener) replayOutbox() {
+	if l.outbox == nil {
+		return
+	}
+
+	for _, m := range l.outbox.Pending(l.chainId) {
+		log := chains.TransferLogger(l.log, m)
+		log.Info("Replaying pending message from outbox", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		err := l.router.Send(m)
+		if err != nil {
+			log.Error("Failed to route pending message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		}
 	}
 }
@@ -0,0 +1,40 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// connectionMetrics report the state of the connection to the substrate node
+type connectionMetrics struct {
+	connected         prometheus.Gauge
+	reconnects        prometheus.Counter
+	reconnectFailures prometheus.Counter
+}
+
+func newConnectionMetrics(chain string) *connectionMetrics {
+	m := &connectionMetrics{
+		connected: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_connected", chain),
+			Help: "1 if the connection to the node is established, 0 while reconnecting",
+		}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_reconnects", chain),
+			Help: "Number of times the connection to the node was re-established",
+		}),
+		reconnectFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_reconnect_failures", chain),
+			Help: "Number of failed attempts to reconnect to the node",
+		}),
+	}
+
+	prometheus.MustRegister(m.connected)
+	prometheus.MustRegister(m.reconnects)
+	prometheus.MustRegister(m.reconnectFailures)
+
+	return m
+}
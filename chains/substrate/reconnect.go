@@ -0,0 +1,150 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"time"
+)
+
+// Delay before the first reconnection attempt, doubled after each failed attempt up to ReconnectMaxBackoff
+var ReconnectInitialBackoff = time.Second
+
+// Maximum delay between reconnection attempts
+var ReconnectMaxBackoff = time.Minute
+
+// ConnectionState is the state of the connection to the substrate node
+type ConnectionState int
+
+const (
+	Disconnected ConnectionState = iota // Connect has not been called
+	Connected                           // Requests can be made
+	Reconnecting                        // The node could not be reached, a new connection is being established
+	Closed                              // Close has been called, the connection will not be used again
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Disconnected:
+		return "disconnected"
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	case Closed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// nextBackoff doubles the backoff, up to ReconnectMaxBackoff
+func nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > ReconnectMaxBackoff {
+		return ReconnectMaxBackoff
+	}
+	return backoff
+}
+
+// setState must be called with stateLock held
+func (c *Connection) setState(state ConnectionState) {
+	c.log.Debug("Substrate connection state changed", "from", c.state, "to", state)
+	c.state = state
+	if c.metrics != nil {
+		if state == Connected {
+			c.metrics.connected.Set(1)
+		} else {
+			c.metrics.connected.Set(0)
+		}
+	}
+}
+
+// State returns the current state of the connection
+func (c *Connection) State() ConnectionState {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	return c.state
+}
+
+// ensureConnected should be called after a failed request. If the node can't be reached, the connection is
+// re-established, blocking until it succeeds or the system is shut down. Returns true if the connection was
+// re-established, in which case the failure was caused by the connection and the request may be retried.
+func (c *Connection) ensureConnected() bool {
+	c.stateLock.Lock()
+	if c.state == Closed || c.state == Disconnected {
+		c.stateLock.Unlock()
+		return false
+	}
+	api := c.api
+	c.stateLock.Unlock()
+
+	// Only the first caller to notice a failure starts reconnecting, others wait for it to finish
+	if c.State() == Connected {
+		_, err := api.RPC.System.Health()
+		if err == nil {
+			return false
+		}
+
+		c.stateLock.Lock()
+		if c.state == Connected && c.api == api {
+			c.log.Warn("Lost connection to substrate node, reconnecting", "url", c.url, "err", err)
+			c.setState(Reconnecting)
+			c.reconnected = make(chan struct{})
+			go c.reconnect()
+		}
+		c.stateLock.Unlock()
+	}
+
+	c.stateLock.Lock()
+	reconnected := c.reconnected
+	c.stateLock.Unlock()
+
+	select {
+	case <-reconnected:
+		return c.State() == Connected
+	case <-c.stop:
+		return false
+	}
+}
+
+// reconnect replaces the connection, retrying with exponential backoff until it succeeds or the system is shut down
+func (c *Connection) reconnect() {
+	backoff := ReconnectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.stop:
+			c.stateLock.Lock()
+			close(c.reconnected)
+			c.stateLock.Unlock()
+			return
+		case <-time.After(backoff):
+		}
+
+		err := c.connect()
+		if err != nil {
+			c.log.Warn("Failed to reconnect to substrate node", "url", c.url, "attempt", attempt, "retryIn", nextBackoff(backoff), "err", err)
+			if c.metrics != nil {
+				c.metrics.reconnectFailures.Inc()
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+
+		c.stateLock.Lock()
+		// The connection may have been closed while reconnecting
+		if c.state == Reconnecting {
+			c.setState(Connected)
+		} else {
+			closeApi(c.api)
+		}
+		close(c.reconnected)
+		c.stateLock.Unlock()
+
+		c.log.Info("Reconnected to substrate node", "url", c.url, "attempts", attempt)
+		if c.metrics != nil {
+			c.metrics.reconnects.Inc()
+		}
+		return
+	}
+}
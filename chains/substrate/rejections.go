@@ -0,0 +1,54 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+// Rejection is an entry in the rejected-proposal report
+type Rejection struct {
+	Source       msg.ChainId `json:"src"`
+	Destination  msg.ChainId `json:"dst"`
+	DepositNonce msg.Nonce   `json:"nonce"`
+	ResourceId   string      `json:"resourceId"`
+	Reason       string      `json:"reason"`
+	Voted        bool        `json:"voted"` // Whether a reject_proposal vote was cast
+	Time         time.Time   `json:"time"`
+}
+
+// rejectionReport records the proposals the writer rejected. It is stored as a log of JSON records, one per line.
+type rejectionReport struct {
+	path string
+	lock sync.Mutex
+}
+
+// rejectionsFileName returns the name of the report file for the chain
+func rejectionsFileName(chain msg.ChainId) string {
+	return fmt.Sprintf("rejected-proposals-%d.json", chain)
+}
+
+// newRejectionReport returns the report for the chain stored in dir, it is created by the first rejection. An empty
+// dir uses the default blockstore location in the home directory.
+func newRejectionReport(dir string, chain msg.ChainId) (*rejectionReport, error) {
+	dir, err := msglog.DataDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &rejectionReport{path: filepath.Join(dir, rejectionsFileName(chain))}, nil
+}
+
+// add appends the rejection to the report
+func (r *rejectionReport) add(rej Rejection) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return msglog.Append(r.path, rej)
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package substrate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+// list reads the rejections of the report in the order they were recorded
+func (r *rejectionReport) list(t *testing.T) []Rejection {
+	var rejections []Rejection
+	err := msglog.Replay(r.path, func(line []byte) error {
+		var rej Rejection
+		err := json.Unmarshal(line, &rej)
+		if err == nil {
+			rejections = append(rejections, rej)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rejections
+}
+
+func TestRejectionReport(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "rejections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	report, err := newRejectionReport(dir, msg.ChainId(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Rejection{
+		{Source: 0, Destination: 1, DepositNonce: 1, ResourceId: "ff", Reason: "resource not found on chain", Time: time.Unix(1, 0).UTC()},
+		{Source: 0, Destination: 1, DepositNonce: 2, ResourceId: "00", Reason: "invalid recipient length 20", Voted: true, Time: time.Unix(2, 0).UTC()},
+	}
+	for _, rej := range expected {
+		err = report.add(rej)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(report.list(t), expected) {
+		t.Fatalf("Got: %+v Expected: %+v", report.list(t), expected)
+	}
+
+	// Rejections are loaded from disk
+	report, err = newRejectionReport(dir, msg.ChainId(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.list(t), expected) {
+		t.Fatalf("Got: %+v Expected: %+v", report.list(t), expected)
+	}
+
+	// Reports are kept per chain
+	report, err = newRejectionReport(dir, msg.ChainId(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.list(t)) != 0 {
+		t.Fatalf("expected empty report, got %+v", report.list(t))
+	}
+}
@@ -4,6 +4,8 @@
 package substrate
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ChainSafe/chainbridge-utils/msg"
@@ -58,36 +60,53 @@ func (p *proposal) encode() ([]byte, error) {
 	}{p.depositNonce, p.call})
 }
 
-func (w *writer) createFungibleProposal(m msg.Message) (*proposal, error) {
-	bigAmt := big.NewInt(0).SetBytes(m.Payload[0].([]byte))
-	amount := types.NewU128(*bigAmt)
-	recipient := types.NewAccountID(m.Payload[1].([]byte))
-	depositNonce := types.U64(m.DepositNonce)
+// invalidProposal is returned when a proposal can't be constructed from a message or is not valid. If prop is
+// set, the proposal can be rejected on chain.
+type invalidProposal struct {
+	reason string
+	prop   *proposal
+}
 
-	meta := w.conn.getMetadata()
+func (e *invalidProposal) Error() string {
+	return e.reason
+}
+
+// payloadBytes returns the payload field at index i
+func payloadBytes(m msg.Message, i int) ([]byte, error) {
+	if len(m.Payload) <= i {
+		return nil, &invalidProposal{reason: fmt.Sprintf("missing payload field %d", i)}
+	}
+	bz, ok := m.Payload[i].([]byte)
+	if !ok {
+		return nil, &invalidProposal{reason: fmt.Sprintf("payload field %d is %T, expected bytes", i, m.Payload[i])}
+	}
+	return bz, nil
+}
+
+// newProposal constructs a proposal calling the method registered for the resource with args
+func (w *writer) newProposal(m msg.Message, args ...interface{}) (*proposal, error) {
 	method, err := w.resolveResourceId(m.ResourceId)
-	if err != nil {
+	if errors.Is(err, errResourceNotFound) {
+		return nil, &invalidProposal{reason: err.Error()}
+	} else if err != nil {
 		return nil, err
 	}
-	call, err := types.NewCall(
-		&meta,
-		method,
-		recipient,
-		amount,
-	)
+
+	meta := w.conn.getMetadata()
+	call, err := types.NewCall(&meta, method, args...)
 	if err != nil {
-		return nil, err
+		return nil, &invalidProposal{reason: fmt.Sprintf("failed to construct call %s: %s", method, err)}
 	}
 	if w.extendCall {
 		eRID, err := types.EncodeToBytes(m.ResourceId)
 		if err != nil {
-			return nil, err
+			return nil, &invalidProposal{reason: fmt.Sprintf("failed to encode resource ID: %s", err)}
 		}
 		call.Args = append(call.Args, eRID...)
 	}
 
 	return &proposal{
-		depositNonce: depositNonce,
+		depositNonce: types.U64(m.DepositNonce),
 		call:         call,
 		sourceId:     types.U8(m.Source),
 		resourceId:   types.NewBytes32(m.ResourceId),
@@ -95,73 +114,77 @@ func (w *writer) createFungibleProposal(m msg.Message) (*proposal, error) {
 	}, nil
 }
 
-func (w *writer) createNonFungibleProposal(m msg.Message) (*proposal, error) {
-	tokenId := types.NewU256(*big.NewInt(0).SetBytes(m.Payload[0].([]byte)))
-	recipient := types.NewAccountID(m.Payload[1].([]byte))
-	metadata := types.Bytes(m.Payload[2].([]byte))
-	depositNonce := types.U64(m.DepositNonce)
+// checkRecipient returns an invalidProposal if the recipient is not an account ID
+func checkRecipient(prop *proposal, recipient []byte) error {
+	if len(recipient) != len(types.AccountID{}) {
+		return &invalidProposal{reason: fmt.Sprintf("invalid recipient length %d", len(recipient)), prop: prop}
+	}
+	return nil
+}
 
-	meta := w.conn.getMetadata()
-	method, err := w.resolveResourceId(m.ResourceId)
+func (w *writer) createFungibleProposal(m msg.Message) (*proposal, error) {
+	amountBz, err := payloadBytes(m, 0)
 	if err != nil {
 		return nil, err
 	}
+	recipientBz, err := payloadBytes(m, 1)
+	if err != nil {
+		return nil, err
+	}
+	bigAmt := big.NewInt(0).SetBytes(amountBz)
+	if bigAmt.BitLen() > 128 {
+		return nil, &invalidProposal{reason: fmt.Sprintf("amount %s exceeds 128 bits", bigAmt)}
+	}
 
-	call, err := types.NewCall(
-		&meta,
-		method,
-		recipient,
-		tokenId,
-		metadata,
-	)
+	amount := types.NewU128(*bigAmt)
+	recipient := types.NewAccountID(recipientBz)
+	prop, err := w.newProposal(m, recipient, amount)
 	if err != nil {
 		return nil, err
 	}
-	if w.extendCall {
-		eRID, err := types.EncodeToBytes(m.ResourceId)
-		if err != nil {
-			return nil, err
-		}
-		call.Args = append(call.Args, eRID...)
+	return prop, checkRecipient(prop, recipientBz)
+}
+
+func (w *writer) createNonFungibleProposal(m msg.Message) (*proposal, error) {
+	tokenIdBz, err := payloadBytes(m, 0)
+	if err != nil {
+		return nil, err
+	}
+	recipientBz, err := payloadBytes(m, 1)
+	if err != nil {
+		return nil, err
+	}
+	metadataBz, err := payloadBytes(m, 2)
+	if err != nil {
+		return nil, err
+	}
+	bigId := big.NewInt(0).SetBytes(tokenIdBz)
+	if bigId.BitLen() > 256 {
+		return nil, &invalidProposal{reason: fmt.Sprintf("token ID %s exceeds 256 bits", bigId)}
 	}
 
-	return &proposal{
-		depositNonce: depositNonce,
-		call:         call,
-		sourceId:     types.U8(m.Source),
-		resourceId:   types.NewBytes32(m.ResourceId),
-		method:       method,
-	}, nil
+	tokenId := types.NewU256(*bigId)
+	recipient := types.NewAccountID(recipientBz)
+	metadata := types.Bytes(metadataBz)
+	prop, err := w.newProposal(m, recipient, tokenId, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return prop, checkRecipient(prop, recipientBz)
 }
 
 func (w *writer) createGenericProposal(m msg.Message) (*proposal, error) {
-	meta := w.conn.getMetadata()
-	method, err := w.resolveResourceId(m.ResourceId)
+	hashBz, err := payloadBytes(m, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	call, err := types.NewCall(
-		&meta,
-		method,
-		types.NewHash(m.Payload[0].([]byte)),
-	)
+	prop, err := w.newProposal(m, types.NewHash(hashBz))
 	if err != nil {
 		return nil, err
 	}
-	if w.extendCall {
-		eRID, err := types.EncodeToBytes(m.ResourceId)
-		if err != nil {
-			return nil, err
-		}
-
-		call.Args = append(call.Args, eRID...)
+	if len(hashBz) != len(types.Hash{}) {
+		return prop, &invalidProposal{reason: fmt.Sprintf("invalid metadata hash length %d", len(hashBz)), prop: prop}
 	}
-	return &proposal{
-		depositNonce: types.U64(m.DepositNonce),
-		call:         call,
-		sourceId:     types.U8(m.Source),
-		resourceId:   types.NewBytes32(m.ResourceId),
-		method:       method,
-	}, nil
+	return prop, nil
 }
@@ -11,6 +11,13 @@ import (
 
 	"github.com/ChainSafe/chainbridge-utils/core"
 
+	"github.com/ChainSafe/ChainBridge/chains"
+	"github.com/ChainSafe/ChainBridge/chains/approval"
+	"github.com/ChainSafe/ChainBridge/chains/balance"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/ChainBridge/chains/ratelimit"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
+	"github.com/ChainSafe/ChainBridge/chains/writermetrics"
 	utils "github.com/ChainSafe/ChainBridge/shared/substrate"
 	metrics "github.com/ChainSafe/chainbridge-utils/metrics/types"
 	"github.com/ChainSafe/chainbridge-utils/msg"
@@ -21,14 +28,29 @@ import (
 var _ core.Writer = &writer{}
 
 var AcknowledgeProposal utils.Method = utils.BridgePalletName + ".acknowledge_proposal"
+var RejectProposal utils.Method = utils.BridgePalletName + ".reject_proposal"
 var TerminatedError = errors.New("terminated")
+var errResourceNotFound = errors.New("resource not found on chain")
+
+// Time between checks for messages released from the quarantine or given an approval decision
+var HeldMessagePollInterval = time.Second * 30
+
+// Time between checks of the relayer account balance
+var AccountPollInterval = time.Minute
 
 type writer struct {
-	conn       *Connection
-	log        log15.Logger
-	sysErr     chan<- error
-	metrics    *metrics.ChainMetrics
-	extendCall bool // Extend extrinsic calls to substrate with ResourceID.Used for backward compatibility with example pallet.
+	conn           *Connection
+	log            log15.Logger
+	sysErr         chan<- error
+	metrics        *metrics.ChainMetrics
+	extendCall     bool // Extend extrinsic calls to substrate with ResourceID.Used for backward compatibility with example pallet.
+	outbox         *outbox.Outbox
+	rejections     *rejectionReport // Records rejected proposals, may be nil
+	rateLimit      *ratelimit.Guard // Holds messages over the volume caps, may be nil
+	approvals      *approval.Store  // Holds high-value transfers until approved, may be nil
+	transfers      *transfers.Tracker
+	writerMetrics  *writermetrics.Metrics // Reports proposal outcomes, latencies and the relayer balance, may be nil
+	balanceMonitor *balance.Monitor       // Checks the relayer balance against the thresholds, may be nil
 }
 
 func NewWriter(conn *Connection, log log15.Logger, sysErr chan<- error, m *metrics.ChainMetrics, extendCall bool) *writer {
@@ -41,61 +63,407 @@ func NewWriter(conn *Connection, log log15.Logger, sysErr chan<- error, m *metri
 	}
 }
 
+// setOutbox adds the outbox used to acknowledge delivered messages
+func (w *writer) setOutbox(ob *outbox.Outbox) {
+	w.outbox = ob
+}
+
+// setRejectionReport adds the report rejected proposals are recorded in
+func (w *writer) setRejectionReport(r *rejectionReport) {
+	w.rejections = r
+}
+
+// setRateLimit sets the guard enforcing the volume caps of outbound transfers
+func (w *writer) setRateLimit(g *ratelimit.Guard) {
+	w.rateLimit = g
+}
+
+// setApprovals sets the store of transfers that require approval before they are voted on
+func (w *writer) setApprovals(s *approval.Store) {
+	w.approvals = s
+}
+
+// setTransfers sets the index votes are recorded in
+func (w *writer) setTransfers(t *transfers.Tracker) {
+	w.transfers = t
+}
+
+// setWriterMetrics enables reporting of proposal outcomes, latencies and the relayer balance
+func (w *writer) setWriterMetrics(m *writermetrics.Metrics) {
+	w.writerMetrics = m
+}
+
+// setBalanceMonitor sets the monitor the relayer balance is checked with, and voting paused by
+func (w *writer) setBalanceMonitor(m *balance.Monitor) {
+	w.balanceMonitor = m
+}
+
+func (w *writer) start() {
+	if w.rateLimit != nil || w.approvals != nil {
+		go w.watchHeldMessages()
+	}
+	if w.writerMetrics != nil || w.balanceMonitor != nil {
+		go w.reportAccount()
+	}
+}
+
+// reportAccount periodically checks the balance of the relayer account
+func (w *writer) reportAccount() {
+	for {
+		w.checkBalance()
+
+		select {
+		case <-w.conn.stop:
+			return
+		case <-time.After(AccountPollInterval):
+		}
+	}
+}
+
+// checkBalance fetches the balance of the relayer account and checks it against the thresholds. Messages held while
+// voting was paused are resolved once the account is topped up.
+func (w *writer) checkBalance() {
+	balance, err := w.conn.getBalance()
+	if err != nil {
+		w.log.Warn("Failed to fetch relayer balance", "err", err)
+		return
+	}
+	if w.writerMetrics != nil {
+		w.writerMetrics.SetBalance(balance)
+	}
+	if w.balanceMonitor == nil {
+		return
+	}
+
+	resumed := w.balanceMonitor.Update(balance)
+	if w.writerMetrics != nil {
+		w.writerMetrics.SetBalanceLevel(w.balanceMonitor.Level(), w.balanceMonitor.Paused())
+	}
+	for _, m := range resumed {
+		chains.TransferLogger(w.log, m).Info("Resolving held message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		w.ResolveMessage(m)
+	}
+}
+
+// acknowledge marks the message as delivered in the outbox, it will not be replayed after a restart
+func (w *writer) acknowledge(m msg.Message) {
+	if w.outbox == nil {
+		return
+	}
+
+	err := w.outbox.Ack(m)
+	if err != nil {
+		chains.TransferLogger(w.log, m).Error("Failed to acknowledge message in outbox", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+	}
+}
+
+// track applies the update to the transfer of the message in the index. Returns the updated transfer, or nil if
+// transfers are not tracked.
+func (w *writer) track(m msg.Message, update transfers.Update) *transfers.Transfer {
+	if w.transfers == nil {
+		return nil
+	}
+
+	t, err := w.transfers.Update(m, update)
+	if err != nil {
+		chains.TransferLogger(w.log, m).Warn("Failed to update transfer index", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		return nil
+	}
+	return &t
+}
+
+// isCancelled returns true if the message was cancelled because its deposit was orphaned by a reorg
+func (w *writer) isCancelled(m msg.Message) bool {
+	return w.outbox != nil && w.outbox.IsCancelled(m)
+}
+
 func (w *writer) ResolveMessage(m msg.Message) bool {
-	var prop *proposal
-	var err error
+	log := chains.TransferLogger(w.log, m)
 
-	// Construct the proposal
-	switch m.Type {
-	case msg.FungibleTransfer:
-		prop, err = w.createFungibleProposal(m)
-	case msg.NonFungibleTransfer:
-		prop, err = w.createNonFungibleProposal(m)
-	case msg.GenericTransfer:
-		prop, err = w.createGenericProposal(m)
-	default:
-		w.sysErr <- fmt.Errorf("unrecognized message type received (chain=%d, name=%s)", m.Destination, w.conn.name)
+	if w.isCancelled(m) {
+		log.Warn("Message cancelled, deposit was orphaned by a reorg", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
 		return false
 	}
 
+	if w.votingPaused(m) || !w.approved(m) {
+		return false
+	}
+
+	prop, err := w.createProposal(m)
+	var invalid *invalidProposal
+	if errors.As(err, &invalid) {
+		return w.rejectProposal(m, invalid)
+	} else if err != nil {
+		// The message remains in the outbox, it is replayed after a restart
+		log.Error("Failed to construct proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		return false
+	}
+
+	return w.vote(m, prop, AcknowledgeProposal)
+}
+
+// votingPaused returns true if voting is paused because the relayer balance is critical. The message is held and
+// resolved once the account is topped up.
+func (w *writer) votingPaused(m msg.Message) bool {
+	if w.balanceMonitor == nil || !w.balanceMonitor.Hold(m) {
+		return false
+	}
+	chains.TransferLogger(w.log, m).Warn("Voting paused, relayer balance below critical threshold", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+	return true
+}
+
+// withinRateLimit returns true if the message may be voted on. It is only checked once the relayer is about to vote,
+// so proposals that are complete or already voted on do not count towards the volume. Messages over the volume cap
+// are held in the quarantine, and are not acknowledged so they remain in the outbox.
+func (w *writer) withinRateLimit(m msg.Message) bool {
+	log := chains.TransferLogger(w.log, m)
+
+	if w.rateLimit == nil {
+		return true
+	}
+
+	ok, err := w.rateLimit.Check(m)
+	if err != nil {
+		log.Error("Failed to check rate limit, holding message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		return false
+	}
+	if !ok {
+		log.Warn("Message held in quarantine, rate limit exceeded", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "resource", fmt.Sprintf("%x", m.ResourceId))
+	}
+	return ok
+}
+
+// resolveRateLimit removes the message from the quarantine once the relayer no longer needs to vote on it, otherwise
+// a released message would be resolved again and again.
+func (w *writer) resolveRateLimit(m msg.Message) {
+	if w.rateLimit == nil {
+		return
+	}
+
+	err := w.rateLimit.Resolve(m)
+	if err != nil {
+		chains.TransferLogger(w.log, m).Warn("Failed to remove message from quarantine", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+	}
+}
+
+// approved returns true if the message does not require approval or was approved. A rejected message is
+// acknowledged without voting, so it is not replayed.
+func (w *writer) approved(m msg.Message) bool {
+	log := chains.TransferLogger(w.log, m)
+
+	if w.approvals == nil {
+		return true
+	}
+
+	status, err := w.approvals.Check(m)
 	if err != nil {
-		w.sysErr <- fmt.Errorf("failed to construct proposal (chain=%d, name=%s) Error: %w", m.Destination, w.conn.name, err)
+		log.Error("Failed to check approval, holding message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		return false
+	}
+	switch status {
+	case approval.Pending:
+		log.Warn("Transfer awaiting approval", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "resource", fmt.Sprintf("%x", m.ResourceId))
 		return false
+	case approval.Rejected:
+		log.Warn("Transfer rejected by operator, not voting", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+		w.acknowledge(m)
+		return false
+	}
+	return true
+}
+
+// watchHeldMessages periodically resolves the messages an operator released from the quarantine, approved or
+// rejected
+func (w *writer) watchHeldMessages() {
+	for {
+		select {
+		case <-w.conn.stop:
+			return
+		case <-time.After(HeldMessagePollInterval):
+		}
+
+		var msgs []msg.Message
+		if w.rateLimit != nil {
+			released, err := w.rateLimit.Released()
+			if err != nil {
+				w.log.Error("Failed to read quarantine", "err", err)
+			}
+			msgs = append(msgs, released...)
+		}
+		if w.approvals != nil {
+			decided, err := w.approvals.Decided()
+			if err != nil {
+				w.log.Error("Failed to read approvals", "err", err)
+			}
+			msgs = append(msgs, decided...)
+		}
+
+		for _, m := range msgs {
+			chains.TransferLogger(w.log, m).Info("Resolving held message", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+			w.ResolveMessage(m)
+		}
 	}
+}
+
+// createProposal constructs the proposal for the message, retrying on errors that are not caused by the message
+func (w *writer) createProposal(m msg.Message) (*proposal, error) {
+	var prop *proposal
+	var err error
+	for i := 0; i < BlockRetryLimit; i++ {
+		switch m.Type {
+		case msg.FungibleTransfer:
+			prop, err = w.createFungibleProposal(m)
+		case msg.NonFungibleTransfer:
+			prop, err = w.createNonFungibleProposal(m)
+		case msg.GenericTransfer:
+			prop, err = w.createGenericProposal(m)
+		default:
+			return nil, &invalidProposal{reason: fmt.Sprintf("unrecognized message type %s", m.Type)}
+		}
+
+		var invalid *invalidProposal
+		if err == nil || errors.As(err, &invalid) {
+			return prop, err
+		}
+		chains.TransferLogger(w.log, m).Error("Failed to construct proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		if !w.conn.ensureConnected() {
+			time.Sleep(BlockRetryInterval)
+		}
+	}
+	return nil, err
+}
+
+// rejectProposal records the rejection of an invalid proposal. If the proposal could be constructed, a
+// reject_proposal vote is cast for it.
+func (w *writer) rejectProposal(m msg.Message, invalid *invalidProposal) bool {
+	log := chains.TransferLogger(w.log, m)
+	log.Warn("Rejecting proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "resource", fmt.Sprintf("%x", m.ResourceId), "reason", invalid.reason)
+
+	if w.rejections != nil {
+		err := w.rejections.add(Rejection{
+			Source:       m.Source,
+			Destination:  m.Destination,
+			DepositNonce: m.DepositNonce,
+			ResourceId:   fmt.Sprintf("%x", m.ResourceId),
+			Reason:       invalid.reason,
+			Voted:        invalid.prop != nil,
+			Time:         time.Now(),
+		})
+		if err != nil {
+			log.Error("Failed to record rejected proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		}
+	}
+
+	if invalid.prop == nil {
+		w.acknowledge(m)
+		return true
+	}
+	return w.vote(m, invalid.prop, RejectProposal)
+}
+
+// vote submits an acknowledge_proposal or reject_proposal vote, unless the proposal is complete or this relayer
+// has already voted. Returns false if the relayer is shutting down, or the message is held by the rate limit.
+func (w *writer) vote(m msg.Message, prop *proposal, method utils.Method) bool {
+	log := chains.TransferLogger(w.log, m)
 
 	for i := 0; i < BlockRetryLimit; i++ {
 		// Ensure we only submit a vote if the proposal hasn't completed
 		valid, reason, err := w.proposalValid(prop)
 		if err != nil {
-			w.log.Error("Failed to assert proposal state", "err", err)
-			time.Sleep(BlockRetryInterval)
+			log.Error("Failed to assert proposal state", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+			if !w.conn.ensureConnected() {
+				time.Sleep(BlockRetryInterval)
+			}
 			continue
 		}
 
 		// If active submit call, otherwise skip it. Retry on failure.
 		if valid {
-			w.log.Info("Acknowledging proposal on chain", "nonce", prop.depositNonce, "source", prop.sourceId, "resource", fmt.Sprintf("%x", prop.resourceId), "method", prop.method)
+			if method == AcknowledgeProposal && !w.withinRateLimit(m) {
+				return false
+			}
+
+			log.Info("Voting on proposal", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "resource", fmt.Sprintf("%x", prop.resourceId), "method", prop.method, "vote", method)
 
-			err = w.conn.SubmitTx(AcknowledgeProposal, prop.depositNonce, prop.sourceId, prop.resourceId, prop.call)
+			err = w.conn.SubmitTx(method, prop.depositNonce, prop.sourceId, prop.resourceId, prop.call)
 			if err != nil && err.Error() == TerminatedError.Error() {
 				return false
-			} else if err != nil {
-				w.log.Error("Failed to execute extrinsic", "err", err)
+			} else if err == ErrExtrinsicRetracted {
+				// The vote may have been included again, check the proposal before resubmitting
+				log.Warn("Vote retracted, resubmitting", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+				continue
+			} else if balance.IsInsufficientFunds(err) {
+				log.Error("Relayer account cannot pay the fees of the vote, top up the account", "account", w.conn.key.Address, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+				if w.balanceMonitor != nil {
+					go w.checkBalance()
+				}
 				time.Sleep(BlockRetryInterval)
 				continue
+			} else if err != nil {
+				log.Error("Failed to execute extrinsic", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+				if !w.conn.ensureConnected() {
+					time.Sleep(BlockRetryInterval)
+				}
+				continue
 			}
 			if w.metrics != nil {
 				w.metrics.VotesSubmitted.Inc()
 			}
+			t := w.track(m, transfers.Vote(""))
+			if w.writerMetrics != nil {
+				w.writerMetrics.Voted(t)
+			}
+			w.checkExecuted(m, prop)
+			w.acknowledge(m)
 			return true
 		} else {
-			w.log.Info("Ignoring proposal", "reason", reason, "nonce", prop.depositNonce, "source", prop.sourceId, "resource", prop.resourceId)
+			log.Info("Ignoring proposal", "reason", reason, "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "resource", fmt.Sprintf("%x", prop.resourceId))
+			if reason == proposalApproved {
+				// Approved proposals are executed by the pallet
+				w.track(m, transfers.Execute(""))
+			}
+			if w.writerMetrics != nil {
+				if reason == alreadyVoted {
+					w.writerMetrics.Skipped(m.ResourceId, writermetrics.SkippedVoted)
+				} else {
+					w.writerMetrics.Skipped(m.ResourceId, writermetrics.SkippedComplete)
+				}
+			}
+			w.acknowledge(m)
+			w.resolveRateLimit(m)
 			return true
 		}
 	}
+	log.Error("Failed to vote on proposal, retries exceeded", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce)
+	w.track(m, transfers.Fail())
+	if w.writerMetrics != nil {
+		w.writerMetrics.Failed(m.ResourceId)
+	}
 	return true
 }
 
+// checkExecuted records the execution of a proposal that was approved by the vote of this relayer. The pallet
+// executes a proposal as part of the vote that approves it.
+func (w *writer) checkExecuted(m msg.Message, prop *proposal) {
+	if w.transfers == nil && w.writerMetrics == nil {
+		return
+	}
+
+	_, reason, err := w.proposalValid(prop)
+	if err != nil {
+		chains.TransferLogger(w.log, m).Warn("Failed to check proposal state after vote", "src", m.Source, "dst", m.Destination, "nonce", m.DepositNonce, "err", err)
+		return
+	}
+	if reason != proposalApproved {
+		return
+	}
+
+	t := w.track(m, transfers.Execute(""))
+	if w.writerMetrics != nil {
+		w.writerMetrics.Executed(m.ResourceId, t)
+	}
+}
+
 func (w *writer) resolveResourceId(id [32]byte) (string, error) {
 	var res []byte
 	exists, err := w.conn.queryStorage(utils.BridgeStoragePrefix, "Resources", id[:], nil, &res)
@@ -103,11 +471,17 @@ func (w *writer) resolveResourceId(id [32]byte) (string, error) {
 		return "", err
 	}
 	if !exists {
-		return "", fmt.Errorf("resource %x not found on chain", id)
+		return "", fmt.Errorf("%w: %x", errResourceNotFound, id)
 	}
 	return string(res), nil
 }
 
+// Reasons given by proposalValid for not voting
+const (
+	alreadyVoted     = "already voted"
+	proposalApproved = "proposal approved"
+)
+
 // proposalValid asserts the state of a proposal. If the proposal is active and this relayer
 // has not voted, it will return true. Otherwise, it will return false with a reason string.
 func (w *writer) proposalValid(prop *proposal) (bool, string, error) {
@@ -130,10 +504,12 @@ func (w *writer) proposalValid(prop *proposal) (bool, string, error) {
 	} else if voteRes.Status.IsActive {
 		if containsVote(voteRes.VotesFor, types.NewAccountID(w.conn.key.PublicKey)) ||
 			containsVote(voteRes.VotesAgainst, types.NewAccountID(w.conn.key.PublicKey)) {
-			return false, "already voted", nil
+			return false, alreadyVoted, nil
 		} else {
 			return true, "", nil
 		}
+	} else if voteRes.Status.IsApproved {
+		return false, proposalApproved, nil
 	} else {
 		return false, "proposal complete", nil
 	}
@@ -4,7 +4,9 @@
 package substrate
 
 import (
+	"io/ioutil"
 	"math/big"
+	"os"
 	"reflect"
 	"testing"
 
@@ -275,3 +277,72 @@ func TestWriter_ResolveMessage_Duplicate(t *testing.T) {
 	}
 
 }
+
+func TestWriter_ResolveMessage_RejectInvalidRecipient(t *testing.T) {
+	var rId [32]byte
+	subtest.QueryConst(t, context.client, "Example", "NativeTokenId", &rId)
+	// An ethereum address can't be used as a recipient
+	amount := big.NewInt(10000000)
+	context.latestInNonce++
+	m := message.NewFungibleTransfer(ForeignChain, ThisChain, context.latestInNonce, amount, rId, make([]byte, 20))
+
+	_, err := context.writerAlice.createFungibleProposal(m)
+	invalid, ok := err.(*invalidProposal)
+	if !ok || invalid.prop == nil {
+		t.Fatalf("expected rejectable proposal, got: %v", err)
+	}
+
+	ok = context.writerAlice.ResolveMessage(m)
+	if !ok {
+		t.Fatal("Alice failed to resolve the message")
+	}
+
+	rejectedState := &voteState{
+		VotesAgainst: []types.AccountID{types.NewAccountID(context.writerAlice.conn.key.PublicKey)},
+		Status:       voteStatus{IsActive: true},
+	}
+	assertProposalState(t, context.writerAlice.conn, invalid.prop, rejectedState, true)
+
+	select {
+	case err = <-context.wSysErr:
+		t.Fatal(err)
+	default:
+	}
+}
+
+func TestWriter_ResolveMessage_UnknownResource(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "rejections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	report, err := newRejectionReport(dir, ThisChain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	context.writerAlice.setRejectionReport(report)
+	defer context.writerAlice.setRejectionReport(nil)
+
+	rId := [32]byte{0xff}
+	context.latestInNonce++
+	m := message.NewFungibleTransfer(ForeignChain, ThisChain, context.latestInNonce, big.NewInt(1), rId, context.writerBob.conn.key.PublicKey)
+
+	ok := context.writerAlice.ResolveMessage(m)
+	if !ok {
+		t.Fatal("Alice failed to resolve the message")
+	}
+
+	rejections := report.list(t)
+	if len(rejections) != 1 {
+		t.Fatalf("expected 1 rejection, got %d", len(rejections))
+	}
+	if rejections[0].DepositNonce != m.DepositNonce || rejections[0].Voted {
+		t.Fatalf("unexpected rejection: %+v", rejections[0])
+	}
+
+	select {
+	case err = <-context.wSysErr:
+		t.Fatal(err)
+	default:
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+/*
+The transfers package keeps an index of every message seen by the relayer, from its deposit to its execution.
+
+Listeners add a transfer when they observe a deposit, and writers update it as the relayer votes on and executes the
+proposal on the destination chain. The index is served by the metrics HTTP server, so operators can follow a transfer
+without searching the logs of both chains.
+
+The index is stored as an append-only log of JSON records, each holding the latest state of a transfer. The log is
+compacted when it is opened, so that only one record per transfer is carried over.
+*/
+package transfers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains/msglog"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+const FileName = "transfers.log"
+
+// Status is the state of a transfer
+type Status string
+
+const (
+	Deposited Status = "deposited" // Deposit observed on the source chain
+	Voted     Status = "voted"     // Vote submitted on the destination chain
+	Executed  Status = "executed"  // Proposal executed on the destination chain
+	Cancelled Status = "cancelled" // Deposit orphaned by a reorg, or proposal cancelled on chain
+	Failed    Status = "failed"    // Submission of the vote or execution failed
+)
+
+// Pending matches the transfers that are neither executed nor cancelled when listing transfers
+const Pending Status = "pending"
+
+// Transfer is the state of a message in the index
+type Transfer struct {
+	Source       msg.ChainId      `json:"src"`
+	Destination  msg.ChainId      `json:"dst"`
+	Nonce        msg.Nonce        `json:"nonce"`
+	Type         msg.TransferType `json:"type"`
+	ResourceId   string           `json:"resourceId"`
+	Status       Status           `json:"status"`
+	DepositBlock uint64           `json:"depositBlock,omitempty"`
+	DepositTx    string           `json:"depositTx,omitempty"`
+	VoteTx       string           `json:"voteTx,omitempty"`
+	ExecutionTx  string           `json:"executionTx,omitempty"`
+	DepositedAt  *time.Time       `json:"depositedAt,omitempty"`
+	VotedAt      *time.Time       `json:"votedAt,omitempty"`
+	ExecutedAt   *time.Time       `json:"executedAt,omitempty"`
+	UpdatedAt    time.Time        `json:"updatedAt"`
+}
+
+// IsPending returns true if the transfer is neither executed nor cancelled
+func (t Transfer) IsPending() bool {
+	return t.Status != Executed && t.Status != Cancelled
+}
+
+func (t Transfer) key() outbox.Key {
+	return outbox.Key{Source: t.Source, Destination: t.Destination, Nonce: t.Nonce}
+}
+
+// Update changes the state of a transfer
+type Update func(t *Transfer, now time.Time)
+
+// Deposit records the block and transaction of the deposit. A transfer cancelled by a reorg is deposited again
+// if the deposit is included in the canonical chain.
+func Deposit(block uint64, tx string) Update {
+	return func(t *Transfer, now time.Time) {
+		t.DepositBlock = block
+		t.DepositTx = tx
+		t.DepositedAt = &now
+		if t.Status == "" || t.Status == Cancelled {
+			t.Status = Deposited
+		}
+	}
+}
+
+// Vote records the vote transaction of this relayer, tx may be empty if it is not known
+func Vote(tx string) Update {
+	return func(t *Transfer, now time.Time) {
+		if tx != "" {
+			t.VoteTx = tx
+		}
+		t.VotedAt = &now
+		if t.Status == "" || t.Status == Deposited || t.Status == Failed {
+			t.Status = Voted
+		}
+	}
+}
+
+// Execute records the execution of the proposal, tx is empty if it was executed by another relayer
+func Execute(tx string) Update {
+	return func(t *Transfer, now time.Time) {
+		if tx != "" {
+			t.ExecutionTx = tx
+		}
+		if t.ExecutedAt == nil {
+			t.ExecutedAt = &now
+		}
+		t.Status = Executed
+	}
+}
+
+// Cancel marks a transfer that was not executed as cancelled
+func Cancel() Update {
+	return func(t *Transfer, now time.Time) {
+		if t.Status != Executed {
+			t.Status = Cancelled
+		}
+	}
+}
+
+// Fail marks a transfer that was neither executed nor cancelled as failed
+func Fail() Update {
+	return func(t *Transfer, now time.Time) {
+		if t.IsPending() {
+			t.Status = Failed
+		}
+	}
+}
+
+// Tracker is the index of transfers
+type Tracker struct {
+	path      string
+	file      *os.File
+	transfers map[outbox.Key]*Transfer
+	lock      sync.Mutex
+}
+
+// Open loads the index stored in dir, creating it if it does not exist
+func Open(dir string) (*Tracker, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tracker{
+		path:      filepath.Join(dir, FileName),
+		transfers: make(map[outbox.Key]*Transfer),
+	}
+
+	err = t.load()
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.compact()
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// load replays the log into the index, later records replace earlier ones
+func (t *Tracker) load() error {
+	return msglog.Replay(t.path, func(line []byte) error {
+		var tr Transfer
+		err := json.Unmarshal(line, &tr)
+		if err != nil {
+			return err
+		}
+		t.transfers[tr.key()] = &tr
+		return nil
+	})
+}
+
+// compact rewrites the log to contain a single record per transfer and opens it for appending
+func (t *Tracker) compact() error {
+	tmp := t.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, tr := range t.sorted(nil) {
+		err = writeTransfer(w, tr)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	err = w.Flush()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, t.path)
+	if err != nil {
+		return err
+	}
+
+	t.file, err = os.OpenFile(t.path, os.O_APPEND|os.O_WRONLY, 0600)
+	return err
+}
+
+// Update applies the update to the transfer of the message, adding it to the index if it is not known. Returns the
+// updated transfer.
+func (t *Tracker) Update(m msg.Message, update Update) (Transfer, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.file == nil {
+		return Transfer{}, fmt.Errorf("transfer index %s is closed", t.path)
+	}
+
+	key := outbox.KeyOf(m)
+	tr := Transfer{
+		Source:      m.Source,
+		Destination: m.Destination,
+		Nonce:       m.DepositNonce,
+	}
+	if existing, ok := t.transfers[key]; ok {
+		tr = *existing
+	}
+	tr.Type = m.Type
+	tr.ResourceId = m.ResourceId.Hex()
+
+	now := time.Now()
+	update(&tr, now)
+	tr.UpdatedAt = now
+
+	err := writeTransfer(t.file, tr)
+	if err != nil {
+		return Transfer{}, err
+	}
+	t.transfers[key] = &tr
+	return tr, nil
+}
+
+// Get returns the transfers from src with the nonce, for every destination
+func (t *Tracker) Get(src msg.ChainId, nonce msg.Nonce) []Transfer {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.sorted(func(tr *Transfer) bool { return tr.Source == src && tr.Nonce == nonce })
+}
+
+// List returns the transfers with the status, or all transfers if status is empty. The Pending status matches all
+// transfers that are neither executed nor cancelled.
+func (t *Tracker) List(status Status) []Transfer {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.sorted(func(tr *Transfer) bool {
+		if status == Pending {
+			return tr.IsPending()
+		}
+		return status == "" || tr.Status == status
+	})
+}
+
+// Close closes the underlying log file
+func (t *Tracker) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
+
+// sorted returns copies of the transfers matching filter, ordered by source, destination and nonce
+func (t *Tracker) sorted(filter func(*Transfer) bool) []Transfer {
+	transfers := []Transfer{}
+	for _, tr := range t.transfers {
+		if filter == nil || filter(tr) {
+			transfers = append(transfers, *tr)
+		}
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].Source != transfers[j].Source {
+			return transfers[i].Source < transfers[j].Source
+		}
+		if transfers[i].Destination != transfers[j].Destination {
+			return transfers[i].Destination < transfers[j].Destination
+		}
+		return transfers[i].Nonce < transfers[j].Nonce
+	})
+	return transfers
+}
+
+func writeTransfer(w io.Writer, tr Transfer) error {
+	bz, err := json.Marshal(tr)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(bz, '\n'))
+	return err
+}
+
+// Handler serves the index as JSON. /transfers lists all transfers, optionally filtered with ?status=, and
+// /transfers/{src}/{nonce} returns the transfers from src with the nonce.
+func Handler(t *Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/transfers"), "/")
+
+		var transfers []Transfer
+		if path == "" {
+			status := Status(r.URL.Query().Get("status"))
+			switch status {
+			case "", Pending, Deposited, Voted, Executed, Cancelled, Failed:
+			default:
+				writeError(w, http.StatusBadRequest, fmt.Errorf("unknown status %q", status))
+				return
+			}
+			transfers = t.List(status)
+		} else {
+			parts := strings.Split(path, "/")
+			if len(parts) != 2 {
+				writeError(w, http.StatusNotFound, fmt.Errorf("expected /transfers/{src}/{nonce}"))
+				return
+			}
+			src, err := strconv.ParseUint(parts[0], 10, 8)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid source chain %q", parts[0]))
+				return
+			}
+			nonce, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid nonce %q", parts[1]))
+				return
+			}
+			transfers = t.Get(msg.ChainId(src), msg.Nonce(nonce))
+			if len(transfers) == 0 {
+				writeError(w, http.StatusNotFound, fmt.Errorf("no transfer from chain %d with nonce %d", src, nonce))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(transfers)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package transfers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ChainSafe/chainbridge-utils/msg"
+)
+
+func openTestTracker(t *testing.T) (*Tracker, string) {
+	dir, err := ioutil.TempDir(os.TempDir(), "transfers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr, dir
+}
+
+func update(t *testing.T, tr *Tracker, m msg.Message, u Update) {
+	_, err := tr.Update(m, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr, dir := openTestTracker(t)
+	defer os.RemoveAll(dir)
+
+	rId := msg.ResourceId{1}
+	m := msg.NewFungibleTransfer(1, 2, 10, big.NewInt(100), rId, nil)
+	other := msg.NewFungibleTransfer(1, 3, 10, big.NewInt(100), rId, nil)
+
+	update(t, tr, m, Deposit(50, "0xdeposit"))
+	update(t, tr, other, Deposit(51, "0xother"))
+	update(t, tr, m, Vote("0xvote"))
+	update(t, tr, m, Execute("0xexecute"))
+	// A late vote confirmation must not regress the status
+	update(t, tr, m, Vote(""))
+	update(t, tr, other, Cancel())
+
+	res := tr.Get(1, 10)
+	if len(res) != 2 {
+		t.Fatalf("expected 2 transfers, got %d", len(res))
+	}
+	got := res[0]
+	if got.Destination != 2 || got.Status != Executed || got.DepositBlock != 50 || got.DepositTx != "0xdeposit" ||
+		got.VoteTx != "0xvote" || got.ExecutionTx != "0xexecute" || got.ResourceId != rId.Hex() {
+		t.Fatalf("unexpected transfer %+v", got)
+	}
+	if got.DepositedAt == nil || got.VotedAt == nil || got.ExecutedAt == nil {
+		t.Fatalf("expected timestamps to be set, got %+v", got)
+	}
+	if res[1].Status != Cancelled {
+		t.Fatalf("expected transfer to be cancelled, got %s", res[1].Status)
+	}
+
+	// A deposit included again after a reorg is pending
+	update(t, tr, other, Deposit(52, "0xother"))
+	if pending := tr.List(Pending); len(pending) != 1 || pending[0].Destination != 3 || pending[0].DepositBlock != 52 {
+		t.Fatalf("unexpected pending transfers %+v", pending)
+	}
+
+	// The index is restored on open
+	err := tr.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	if all := tr.List(""); len(all) != 2 || all[0].Status != Executed || all[1].Status != Deposited {
+		t.Fatalf("unexpected transfers after reopening %+v", all)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tr, dir := openTestTracker(t)
+	defer os.RemoveAll(dir)
+	defer tr.Close()
+
+	update(t, tr, msg.NewFungibleTransfer(1, 2, 10, big.NewInt(100), msg.ResourceId{1}, nil), Execute("0xexecute"))
+	update(t, tr, msg.NewFungibleTransfer(1, 2, 11, big.NewInt(100), msg.ResourceId{1}, nil), Deposit(60, "0xdeposit"))
+
+	testCases := []struct {
+		path   string
+		code   int
+		nonces []msg.Nonce
+	}{
+		{"/transfers", http.StatusOK, []msg.Nonce{10, 11}},
+		{"/transfers?status=pending", http.StatusOK, []msg.Nonce{11}},
+		{"/transfers?status=executed", http.StatusOK, []msg.Nonce{10}},
+		{"/transfers?status=unknown", http.StatusBadRequest, nil},
+		{"/transfers/1/10", http.StatusOK, []msg.Nonce{10}},
+		{"/transfers/1/12", http.StatusNotFound, nil},
+		{"/transfers/1/x", http.StatusBadRequest, nil},
+		{"/transfers/1", http.StatusNotFound, nil},
+	}
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		Handler(tr)(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", tc.path, tc.code, rec.Code)
+			continue
+		}
+		if tc.code != http.StatusOK {
+			continue
+		}
+
+		var res []Transfer
+		err := json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res) != len(tc.nonces) {
+			t.Errorf("%s: expected %d transfers, got %d", tc.path, len(tc.nonces), len(res))
+			continue
+		}
+		for i, n := range tc.nonces {
+			if res[i].Nonce != n {
+				t.Errorf("%s: expected nonce %d, got %d", tc.path, n, res[i].Nonce)
+			}
+		}
+	}
+}
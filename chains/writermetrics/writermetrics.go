@@ -0,0 +1,163 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+/*
+The writermetrics package provides the Prometheus metrics reported by the writers of all chain types.
+
+The latency of a transfer is measured from the times recorded in the transfer index, so it is only reported for
+transfers this relayer observed the deposit of, and only for the vote and execution of this relayer.
+*/
+package writermetrics
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ChainSafe/ChainBridge/chains/balance"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons a proposal is skipped
+const (
+	SkippedVoted    = "voted"    // This relayer already voted
+	SkippedComplete = "complete" // The proposal passed or was finalized without the vote of this relayer
+)
+
+// Kinds of transactions
+const (
+	TxVote    = "vote"
+	TxExecute = "execute"
+)
+
+// Latency buckets in seconds, from a few blocks up to several hours
+var latencyBuckets = []float64{5, 15, 30, 60, 120, 300, 600, 1800, 3600, 4 * 3600}
+
+// Metrics report the progress of proposals on the writer's chain and the state of the relayer account
+type Metrics struct {
+	depositToVote     prometheus.Histogram
+	voteToExecution   prometheus.Histogram
+	proposalsExecuted *prometheus.CounterVec
+	proposalsSkipped  *prometheus.CounterVec
+	proposalsFailed   *prometheus.CounterVec
+	gasUsed           *prometheus.HistogramVec
+	balance           prometheus.Gauge
+	balanceLevel      prometheus.Gauge
+	votingPaused      prometheus.Gauge
+	forwarderNonce    prometheus.Gauge
+}
+
+// New creates and registers the writer metrics of the chain
+func New(chain string) *Metrics {
+	m := &Metrics{
+		depositToVote: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    fmt.Sprintf("%s_deposit_to_vote_seconds", chain),
+			Help:    "Time from observing a deposit to the vote of the relayer on this chain",
+			Buckets: latencyBuckets,
+		}),
+		voteToExecution: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    fmt.Sprintf("%s_vote_to_execution_seconds", chain),
+			Help:    "Time from the vote of the relayer to the execution of the proposal on this chain",
+			Buckets: latencyBuckets,
+		}),
+		proposalsExecuted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_proposals_executed", chain),
+			Help: "Number of proposals executed by the relayer, by resource ID",
+		}, []string{"resource"}),
+		proposalsSkipped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_proposals_skipped", chain),
+			Help: "Number of proposals not voted on because the relayer already voted or the proposal is complete, by resource ID",
+		}, []string{"resource", "reason"}),
+		proposalsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_proposals_failed", chain),
+			Help: "Number of proposals the relayer failed to vote on or execute, by resource ID",
+		}, []string{"resource"}),
+		gasUsed: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    fmt.Sprintf("%s_tx_gas_used", chain),
+			Help:    "Gas used by the transactions of the relayer, by kind of transaction",
+			Buckets: prometheus.ExponentialBuckets(25000, 2, 10),
+		}, []string{"tx"}),
+		balance: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_relayer_balance", chain),
+			Help: "Balance of the relayer account, in the base unit of the native token",
+		}),
+		balanceLevel: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_relayer_balance_level", chain),
+			Help: "Level of the relayer balance: 0 above the thresholds, 1 below the warning and 2 below the critical threshold",
+		}),
+		votingPaused: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_voting_paused", chain),
+			Help: "1 if voting is paused until the relayer account is topped up",
+		}),
+		forwarderNonce: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_forwarder_nonce", chain),
+			Help: "Nonce of the relayer in the forwarder contract",
+		}),
+	}
+
+	prometheus.MustRegister(m.depositToVote)
+	prometheus.MustRegister(m.voteToExecution)
+	prometheus.MustRegister(m.proposalsExecuted)
+	prometheus.MustRegister(m.proposalsSkipped)
+	prometheus.MustRegister(m.proposalsFailed)
+	prometheus.MustRegister(m.gasUsed)
+	prometheus.MustRegister(m.balance)
+	prometheus.MustRegister(m.balanceLevel)
+	prometheus.MustRegister(m.votingPaused)
+	prometheus.MustRegister(m.forwarderNonce)
+
+	return m
+}
+
+// Voted reports the deposit-to-vote latency of the transfer, if its deposit was observed
+func (m *Metrics) Voted(t *transfers.Transfer) {
+	if t != nil && t.DepositedAt != nil && t.VotedAt != nil {
+		m.depositToVote.Observe(t.VotedAt.Sub(*t.DepositedAt).Seconds())
+	}
+}
+
+// Executed counts the execution of the proposal and reports its vote-to-execution latency, if this relayer voted
+func (m *Metrics) Executed(rId msg.ResourceId, t *transfers.Transfer) {
+	m.proposalsExecuted.WithLabelValues(rId.Hex()).Inc()
+	if t != nil && t.VotedAt != nil && t.ExecutedAt != nil {
+		m.voteToExecution.Observe(t.ExecutedAt.Sub(*t.VotedAt).Seconds())
+	}
+}
+
+// Skipped counts a proposal that was not voted on for the reason
+func (m *Metrics) Skipped(rId msg.ResourceId, reason string) {
+	m.proposalsSkipped.WithLabelValues(rId.Hex(), reason).Inc()
+}
+
+// Failed counts a proposal that could not be voted on or executed
+func (m *Metrics) Failed(rId msg.ResourceId) {
+	m.proposalsFailed.WithLabelValues(rId.Hex()).Inc()
+}
+
+// GasUsed reports the gas used by a transaction of the kind
+func (m *Metrics) GasUsed(kind string, gas uint64) {
+	m.gasUsed.WithLabelValues(kind).Observe(float64(gas))
+}
+
+// SetBalance reports the balance of the relayer account
+func (m *Metrics) SetBalance(balance *big.Int) {
+	f, _ := new(big.Float).SetInt(balance).Float64()
+	m.balance.Set(f)
+}
+
+// SetBalanceLevel reports the level of the relayer balance and whether voting is paused
+func (m *Metrics) SetBalanceLevel(level balance.Level, paused bool) {
+	m.balanceLevel.Set(float64(level))
+	if paused {
+		m.votingPaused.Set(1)
+	} else {
+		m.votingPaused.Set(0)
+	}
+}
+
+// SetForwarderNonce reports the nonce of the relayer in the forwarder contract
+func (m *Metrics) SetForwarderNonce(nonce *big.Int) {
+	f, _ := new(big.Float).SetInt(nonce).Float64()
+	m.forwarderNonce.Set(f)
+}
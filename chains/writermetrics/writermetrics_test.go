@@ -0,0 +1,80 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package writermetrics
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/chains/balance"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New("test")
+	rId := msg.ResourceId{1}
+
+	deposited := time.Unix(1000, 0)
+	voted := deposited.Add(20 * time.Second)
+	executed := voted.Add(40 * time.Second)
+
+	m.Voted(&transfers.Transfer{DepositedAt: &deposited, VotedAt: &voted})
+	// Transfers without a recorded deposit or vote are not observed
+	m.Voted(&transfers.Transfer{VotedAt: &voted})
+	m.Voted(nil)
+	m.Executed(rId, &transfers.Transfer{VotedAt: &voted, ExecutedAt: &executed})
+	m.Executed(rId, nil)
+	m.Skipped(rId, SkippedVoted)
+	m.Failed(rId)
+	m.SetBalance(big.NewInt(1e18))
+	m.SetBalanceLevel(balance.Critical, true)
+
+	expected := `
+# HELP test_deposit_to_vote_seconds Time from observing a deposit to the vote of the relayer on this chain
+# TYPE test_deposit_to_vote_seconds histogram
+test_deposit_to_vote_seconds_bucket{le="5"} 0
+test_deposit_to_vote_seconds_bucket{le="15"} 0
+test_deposit_to_vote_seconds_bucket{le="30"} 1
+test_deposit_to_vote_seconds_bucket{le="60"} 1
+test_deposit_to_vote_seconds_bucket{le="120"} 1
+test_deposit_to_vote_seconds_bucket{le="300"} 1
+test_deposit_to_vote_seconds_bucket{le="600"} 1
+test_deposit_to_vote_seconds_bucket{le="1800"} 1
+test_deposit_to_vote_seconds_bucket{le="3600"} 1
+test_deposit_to_vote_seconds_bucket{le="14400"} 1
+test_deposit_to_vote_seconds_bucket{le="+Inf"} 1
+test_deposit_to_vote_seconds_sum 20
+test_deposit_to_vote_seconds_count 1
+`
+	err := testutil.CollectAndCompare(m.depositToVote, strings.NewReader(expected))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := testutil.CollectAndCount(m.voteToExecution); count != 1 {
+		t.Fatalf("expected 1 vote to execution series, got %d", count)
+	}
+	if v := testutil.ToFloat64(m.proposalsExecuted.WithLabelValues(rId.Hex())); v != 2 {
+		t.Fatalf("expected 2 executed proposals, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.proposalsSkipped.WithLabelValues(rId.Hex(), SkippedVoted)); v != 1 {
+		t.Fatalf("expected 1 skipped proposal, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.proposalsFailed.WithLabelValues(rId.Hex())); v != 1 {
+		t.Fatalf("expected 1 failed proposal, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.balance); v != 1e18 {
+		t.Fatalf("expected balance of 1e18, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.balanceLevel); v != 2 {
+		t.Fatalf("expected critical balance level, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.votingPaused); v != 1 {
+		t.Fatalf("expected voting to be paused, got %v", v)
+	}
+}
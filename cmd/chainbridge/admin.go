@@ -0,0 +1,490 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ChainSafe/ChainBridge/bindings/Bridge"
+	erc20Handler "github.com/ChainSafe/ChainBridge/bindings/ERC20Handler"
+	erc721Handler "github.com/ChainSafe/ChainBridge/bindings/ERC721Handler"
+	"github.com/ChainSafe/ChainBridge/bindings/GenericHandler"
+	"github.com/ChainSafe/ChainBridge/config"
+	utils "github.com/ChainSafe/ChainBridge/shared/ethereum"
+	"github.com/ChainSafe/chainbridge-utils/crypto"
+	"github.com/ChainSafe/chainbridge-utils/crypto/secp256k1"
+	"github.com/ChainSafe/chainbridge-utils/keystore"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/urfave/cli/v2"
+)
+
+// Time to wait for an admin transaction to be mined
+var AdminTxTimeout = time.Minute * 5
+
+// adminBackend is the connection admin transactions are built, sent and confirmed with
+type adminBackend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+}
+
+// adminTx describes a transaction of an admin command. With --dry-run it is built and checked against the
+// current state of the chain, but not sent.
+type adminTx struct {
+	Method   string          `json:"method"`
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to,omitempty"`
+	Contract *common.Address `json:"contract,omitempty"` // Address of the deployed contract
+	Nonce    uint64          `json:"nonce"`
+	Gas      uint64          `json:"gas"`
+	GasPrice *big.Int        `json:"gasPrice"`
+	Data     hexutil.Bytes   `json:"data"`
+	Hash     common.Hash     `json:"hash"`
+	Sent     bool            `json:"sent"`
+}
+
+// bridgeAdmin sends the transactions of the admin commands with the admin key
+type bridgeAdmin struct {
+	backend adminBackend
+	opts    *bind.TransactOpts
+	dryRun  bool
+	txs     []adminTx
+}
+
+// newBridgeAdmin creates an admin sending transactions signed by kp. The gas limit is estimated if it is 0, and the
+// gas price is suggested by the node if it is nil.
+func newBridgeAdmin(backend adminBackend, kp *secp256k1.Keypair, chainId *big.Int, gasLimit uint64, gasPrice *big.Int, dryRun bool) (*bridgeAdmin, error) {
+	opts, err := bind.NewKeyedTransactorWithChainID(kp.PrivateKey(), chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := backend.PendingNonceAt(context.Background(), opts.From)
+	if err != nil {
+		return nil, err
+	}
+	if gasPrice == nil {
+		gasPrice, err = backend.SuggestGasPrice(context.Background())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts.Nonce = new(big.Int).SetUint64(nonce)
+	opts.Value = big.NewInt(0)
+	opts.GasLimit = gasLimit
+	opts.GasPrice = gasPrice
+	opts.NoSend = dryRun
+	opts.Context = context.Background()
+
+	return &bridgeAdmin{
+		backend: backend,
+		opts:    opts,
+		dryRun:  dryRun,
+	}, nil
+}
+
+// transact sends the transaction built by send and waits for it to be mined. With --dry-run the transaction is only
+// built, the gas estimation fails if it would revert. The nonce is incremented so following transactions of the same
+// command are built on top of it.
+func (a *bridgeAdmin) transact(method string, send func(opts *bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	tx, err := send(a.opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", method, err)
+	}
+	a.opts.Nonce = new(big.Int).Add(a.opts.Nonce, big.NewInt(1))
+	a.txs = append(a.txs, adminTx{
+		Method:   method,
+		From:     a.opts.From,
+		To:       tx.To(),
+		Nonce:    tx.Nonce(),
+		Gas:      tx.Gas(),
+		GasPrice: tx.GasPrice(),
+		Data:     tx.Data(),
+		Hash:     tx.Hash(),
+		Sent:     !a.dryRun,
+	})
+	if a.dryRun {
+		return tx, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), AdminTxTimeout)
+	defer cancel()
+	receipt, err := bind.WaitMined(ctx, a.backend, tx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed waiting for transaction %s: %w", method, tx.Hash().Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("%s: transaction %s reverted", method, tx.Hash().Hex())
+	}
+	return tx, nil
+}
+
+// deploy sends the deployment of a contract, and returns its address. With --dry-run the address is the one the
+// contract would be deployed at.
+func (a *bridgeAdmin) deploy(method string, deploy func(opts *bind.TransactOpts) (common.Address, *types.Transaction, error)) (common.Address, error) {
+	var addr common.Address
+	_, err := a.transact(method, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		var tx *types.Transaction
+		var err error
+		addr, tx, err = deploy(opts)
+		return tx, err
+	})
+	if err != nil {
+		return addr, err
+	}
+	a.txs[len(a.txs)-1].Contract = &addr
+	return addr, nil
+}
+
+// deployBridge deploys the Bridge contract and a handler of each type registered with it
+func (a *bridgeAdmin) deployBridge(chainId uint8, relayers []common.Address, threshold, fee, expiry *big.Int) (*utils.DeployedContracts, error) {
+	bridgeAddr, err := a.deploy("deployBridge", func(opts *bind.TransactOpts) (common.Address, *types.Transaction, error) {
+		addr, tx, _, err := Bridge.DeployBridge(opts, a.backend, chainId, relayers, threshold, fee, expiry)
+		return addr, tx, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	erc20Addr, err := a.deploy("deployERC20Handler", func(opts *bind.TransactOpts) (common.Address, *types.Transaction, error) {
+		addr, tx, _, err := erc20Handler.DeployERC20Handler(opts, a.backend, bridgeAddr, [][32]byte{}, []common.Address{}, []common.Address{})
+		return addr, tx, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	erc721Addr, err := a.deploy("deployERC721Handler", func(opts *bind.TransactOpts) (common.Address, *types.Transaction, error) {
+		addr, tx, _, err := erc721Handler.DeployERC721Handler(opts, a.backend, bridgeAddr, [][32]byte{}, []common.Address{}, []common.Address{})
+		return addr, tx, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	genericAddr, err := a.deploy("deployGenericHandler", func(opts *bind.TransactOpts) (common.Address, *types.Transaction, error) {
+		addr, tx, _, err := GenericHandler.DeployGenericHandler(opts, a.backend, bridgeAddr, [][32]byte{}, []common.Address{}, [][4]byte{}, [][4]byte{})
+		return addr, tx, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.DeployedContracts{
+		BridgeAddress:         bridgeAddr,
+		ERC20HandlerAddress:   erc20Addr,
+		ERC721HandlerAddress:  erc721Addr,
+		GenericHandlerAddress: genericAddr,
+	}, nil
+}
+
+// print writes the transactions of the command, as JSON if asJson is set
+func (a *bridgeAdmin) print(w io.Writer, asJson bool) error {
+	if asJson {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			DryRun       bool      `json:"dryRun"`
+			Transactions []adminTx `json:"transactions"`
+		}{a.dryRun, a.txs})
+	}
+
+	for _, tx := range a.txs {
+		status := "sent"
+		if !tx.Sent {
+			status = "dry run, not sent"
+		}
+		fmt.Fprintf(w, "%s (%s): hash: %s nonce: %d gas: %d gasPrice: %s", tx.Method, status, tx.Hash.Hex(), tx.Nonce, tx.Gas, tx.GasPrice)
+		if tx.To != nil {
+			fmt.Fprintf(w, " to: %s", tx.To.Hex())
+		}
+		if tx.Contract != nil {
+			fmt.Fprintf(w, " contract: %s", tx.Contract.Hex())
+		}
+		if !tx.Sent {
+			fmt.Fprintf(w, " data: %s", tx.Data)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// adminKeypair loads the key passed with --from from the keystore, or the test key passed with --testkey
+func adminKeypair(ctx *cli.Context) (*secp256k1.Keypair, error) {
+	kp, err := loadAdminKeypair(ctx, keystore.EthChain)
+	if err != nil {
+		return nil, err
+	}
+	return kp.(*secp256k1.Keypair), nil
+}
+
+// loadAdminKeypair loads the key of the chain type passed with --from or --testkey
+func loadAdminKeypair(ctx *cli.Context, chainType string) (crypto.Keypair, error) {
+	path := ctx.String(config.KeystorePathFlag.Name)
+	from := ctx.String(config.FromFlag.Name)
+	insecure := false
+	if key := ctx.String(config.TestKeyFlag.Name); key != "" {
+		path = key
+		insecure = true
+	} else if from == "" {
+		return nil, errors.New("--from is required unless --testkey is set")
+	}
+
+	return keystore.KeypairFromAddress(from, chainType, path, insecure)
+}
+
+// openBridgeAdmin connects to the node passed with --url and loads the admin key
+func openBridgeAdmin(ctx *cli.Context) (*bridgeAdmin, error) {
+	kp, err := adminKeypair(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ethclient.Dial(ctx.String(config.UrlFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	chainId, err := client.ChainID(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var gasPrice *big.Int
+	if ctx.String(config.GasPriceFlag.Name) != "" {
+		gasPrice, err = parseAmount(ctx, config.GasPriceFlag)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newBridgeAdmin(client, kp, chainId, ctx.Uint64(config.GasLimitFlag.Name), gasPrice, ctx.Bool(config.DryRunFlag.Name))
+}
+
+// runAdmin runs the command with an admin opened from the flags, and prints the transactions sent, including
+// those sent before an error
+func runAdmin(ctx *cli.Context, run func(a *bridgeAdmin) error) error {
+	a, err := openBridgeAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = run(a)
+	if printErr := a.print(os.Stdout, ctx.Bool(config.JsonFlag.Name)); printErr != nil && err == nil {
+		err = printErr
+	}
+	return err
+}
+
+// runBridgeMethod sends a transaction to the Bridge contract passed with --bridge
+func runBridgeMethod(ctx *cli.Context, method string, send func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error)) error {
+	bridgeAddr, err := parseAddress(ctx, config.BridgeAddressFlag)
+	if err != nil {
+		return err
+	}
+
+	return runAdmin(ctx, func(a *bridgeAdmin) error {
+		bridge, err := Bridge.NewBridgeTransactor(bridgeAddr, a.backend)
+		if err != nil {
+			return err
+		}
+		_, err = a.transact(method, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return send(bridge, opts)
+		})
+		return err
+	})
+}
+
+// parseAddress parses the hex address passed with the flag
+func parseAddress(ctx *cli.Context, flag *cli.StringFlag) (common.Address, error) {
+	s := ctx.String(flag.Name)
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("invalid --%s address %s", flag.Name, s)
+	}
+	return common.HexToAddress(s), nil
+}
+
+// parseAmount parses the decimal amount passed with the flag
+func parseAmount(ctx *cli.Context, flag *cli.StringFlag) (*big.Int, error) {
+	s := ctx.String(flag.Name)
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid --%s amount %s", flag.Name, s)
+	}
+	return amount, nil
+}
+
+// parseResourceId parses the hex resource ID passed with --resourceId
+func parseResourceId(ctx *cli.Context) ([32]byte, error) {
+	var rId [32]byte
+	bz, err := hexutil.Decode(ctx.String(config.ResourceIdFlag.Name))
+	if err != nil || len(bz) != 32 {
+		return rId, fmt.Errorf("invalid --%s %s", config.ResourceIdFlag.Name, ctx.String(config.ResourceIdFlag.Name))
+	}
+	copy(rId[:], bz)
+	return rId, nil
+}
+
+// functionSignature returns the selector of the function signature passed with the flag, empty if it is not set
+func functionSignature(ctx *cli.Context, flag *cli.StringFlag) [4]byte {
+	if sig := ctx.String(flag.Name); sig != "" {
+		return utils.CreateFunctionSignature(sig)
+	}
+	return [4]byte{}
+}
+
+// handleAdminDeployCmd deploys the Bridge contract and its handlers
+func handleAdminDeployCmd(ctx *cli.Context) error {
+	var relayers []common.Address
+	for _, r := range ctx.StringSlice(config.RelayersFlag.Name) {
+		if !common.IsHexAddress(r) {
+			return fmt.Errorf("invalid --%s address %s", config.RelayersFlag.Name, r)
+		}
+		relayers = append(relayers, common.HexToAddress(r))
+	}
+	chainId := ctx.Uint(config.DeployChainIdFlag.Name)
+	if chainId > 255 {
+		return fmt.Errorf("invalid --%s %d, must fit in a uint8", config.DeployChainIdFlag.Name, chainId)
+	}
+	fee, err := parseAmount(ctx, config.InitialFeeFlag)
+	if err != nil {
+		return err
+	}
+	threshold := new(big.Int).SetUint64(ctx.Uint64(config.InitialThresholdFlag.Name))
+	expiry := new(big.Int).SetUint64(ctx.Uint64(config.ExpiryFlag.Name))
+
+	return runAdmin(ctx, func(a *bridgeAdmin) error {
+		_, err := a.deployBridge(uint8(chainId), relayers, threshold, fee, expiry)
+		return err
+	})
+}
+
+// handleAdminAddRelayerCmd grants the relayer role to an address
+func handleAdminAddRelayerCmd(ctx *cli.Context) error {
+	relayer, err := parseAddress(ctx, config.RelayerFlag)
+	if err != nil {
+		return err
+	}
+	return runBridgeMethod(ctx, "adminAddRelayer", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return b.AdminAddRelayer(opts, relayer)
+	})
+}
+
+// handleAdminRemoveRelayerCmd revokes the relayer role of an address
+func handleAdminRemoveRelayerCmd(ctx *cli.Context) error {
+	relayer, err := parseAddress(ctx, config.RelayerFlag)
+	if err != nil {
+		return err
+	}
+	return runBridgeMethod(ctx, "adminRemoveRelayer", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return b.AdminRemoveRelayer(opts, relayer)
+	})
+}
+
+// handleAdminSetThresholdCmd changes the number of votes required for a proposal to pass
+func handleAdminSetThresholdCmd(ctx *cli.Context) error {
+	threshold := new(big.Int).SetUint64(ctx.Uint64(config.ThresholdFlag.Name))
+	return runBridgeMethod(ctx, "adminChangeRelayerThreshold", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return b.AdminChangeRelayerThreshold(opts, threshold)
+	})
+}
+
+// handleAdminRegisterResourceCmd maps a resource ID to a token or contract in a handler
+func handleAdminRegisterResourceCmd(ctx *cli.Context) error {
+	handler, err := parseAddress(ctx, config.HandlerFlag)
+	if err != nil {
+		return err
+	}
+	target, err := parseAddress(ctx, config.TargetFlag)
+	if err != nil {
+		return err
+	}
+	rId, err := parseResourceId(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !ctx.Bool(config.GenericFlag.Name) {
+		return runBridgeMethod(ctx, "adminSetResource", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+			return b.AdminSetResource(opts, handler, rId, target)
+		})
+	}
+	depositSig := functionSignature(ctx, config.DepositSigFlag)
+	executeSig := functionSignature(ctx, config.ExecuteSigFlag)
+	return runBridgeMethod(ctx, "adminSetGenericResource", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return b.AdminSetGenericResource(opts, handler, rId, target, depositSig, executeSig)
+	})
+}
+
+// handleAdminSetBurnableCmd marks a token as burnable in a handler, so deposits burn it and executions mint it
+func handleAdminSetBurnableCmd(ctx *cli.Context) error {
+	handler, err := parseAddress(ctx, config.HandlerFlag)
+	if err != nil {
+		return err
+	}
+	token, err := parseAddress(ctx, config.TokenFlag)
+	if err != nil {
+		return err
+	}
+	return runBridgeMethod(ctx, "adminSetBurnable", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return b.AdminSetBurnable(opts, handler, token)
+	})
+}
+
+// handleAdminPauseCmd pauses deposits and proposals on the bridge
+func handleAdminPauseCmd(ctx *cli.Context) error {
+	return runBridgeMethod(ctx, "adminPauseTransfers", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return b.AdminPauseTransfers(opts)
+	})
+}
+
+// handleAdminUnpauseCmd resumes deposits and proposals on the bridge
+func handleAdminUnpauseCmd(ctx *cli.Context) error {
+	return runBridgeMethod(ctx, "adminUnpauseTransfers", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return b.AdminUnpauseTransfers(opts)
+	})
+}
+
+// handleAdminSetFeeCmd changes the fee of deposits
+func handleAdminSetFeeCmd(ctx *cli.Context) error {
+	fee, err := parseAmount(ctx, config.FeeFlag)
+	if err != nil {
+		return err
+	}
+	return runBridgeMethod(ctx, "adminChangeFee", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return b.AdminChangeFee(opts, fee)
+	})
+}
+
+// handleAdminWithdrawCmd withdraws tokens held by a handler
+func handleAdminWithdrawCmd(ctx *cli.Context) error {
+	handler, err := parseAddress(ctx, config.HandlerFlag)
+	if err != nil {
+		return err
+	}
+	token, err := parseAddress(ctx, config.TokenFlag)
+	if err != nil {
+		return err
+	}
+	recipient, err := parseAddress(ctx, config.RecipientFlag)
+	if err != nil {
+		return err
+	}
+	amount, err := parseAmount(ctx, config.AmountFlag)
+	if err != nil {
+		return err
+	}
+	return runBridgeMethod(ctx, "adminWithdraw", func(b *Bridge.BridgeTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return b.AdminWithdraw(opts, handler, token, recipient, amount)
+	})
+}
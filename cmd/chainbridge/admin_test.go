@@ -0,0 +1,134 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ChainSafe/ChainBridge/bindings/Bridge"
+	"github.com/ChainSafe/chainbridge-utils/crypto/secp256k1"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// minedBackend mines a block with every transaction sent
+type minedBackend struct {
+	*backends.SimulatedBackend
+}
+
+func (b minedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	err := b.SimulatedBackend.SendTransaction(ctx, tx)
+	if err == nil {
+		b.Commit()
+	}
+	return err
+}
+
+func newTestAdmin(t *testing.T, backend adminBackend, kp *secp256k1.Keypair, dryRun bool) *bridgeAdmin {
+	a, err := newBridgeAdmin(backend, kp, big.NewInt(1337), 0, big.NewInt(params.GWei), dryRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestBridgeAdmin(t *testing.T) {
+	admin, err := secp256k1.GenerateKeypair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := secp256k1.GenerateKeypair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	funds := new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{
+		admin.CommonAddress(): {Balance: funds},
+		other.CommonAddress(): {Balance: funds},
+	}, 30000000)
+	defer sim.Close()
+	backend := minedBackend{sim}
+
+	relayer := common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	// A dry run predicts the addresses of the contracts without deploying them
+	dry := newTestAdmin(t, backend, admin, true)
+	predicted, err := dry.deployBridge(1, []common.Address{relayer}, big.NewInt(1), big.NewInt(0), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dry.txs) != 4 || dry.txs[0].Sent || dry.txs[0].Gas == 0 {
+		t.Fatalf("unexpected dry run transactions %+v", dry.txs)
+	}
+	code, err := sim.CodeAt(context.Background(), predicted.BridgeAddress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 0 {
+		t.Fatal("expected dry run not to deploy the bridge")
+	}
+
+	a := newTestAdmin(t, backend, admin, false)
+	deployed, err := a.deployBridge(1, []common.Address{relayer}, big.NewInt(1), big.NewInt(0), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployed != *predicted {
+		t.Fatalf("expected contracts at %+v, got %+v", predicted, deployed)
+	}
+
+	bridge, err := Bridge.NewBridge(deployed.BridgeAddress, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	_, err = a.transact("adminAddRelayer", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return bridge.AdminAddRelayer(opts, added)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	isRelayer, err := bridge.IsRelayer(&bind.CallOpts{}, added)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isRelayer {
+		t.Fatal("expected relayer to be added")
+	}
+
+	// Transactions that would revert fail the dry run
+	dry = newTestAdmin(t, backend, other, true)
+	_, err = dry.transact("adminPauseTransfers", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return bridge.AdminPauseTransfers(opts)
+	})
+	if err == nil {
+		t.Fatal("expected dry run by a key without the admin role to fail")
+	}
+
+	var buf bytes.Buffer
+	err = a.print(&buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		DryRun       bool      `json:"dryRun"`
+		Transactions []adminTx `json:"transactions"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DryRun || len(res.Transactions) != 5 || res.Transactions[4].Method != "adminAddRelayer" ||
+		*res.Transactions[4].To != deployed.BridgeAddress || res.Transactions[0].Contract == nil {
+		t.Fatalf("unexpected output %s", buf.String())
+	}
+}
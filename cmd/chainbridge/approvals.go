@@ -0,0 +1,91 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ChainSafe/ChainBridge/chains/approval"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
+	"github.com/ChainSafe/ChainBridge/config"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	log "github.com/ChainSafe/log15"
+	"github.com/urfave/cli/v2"
+)
+
+// openApprovals opens the approval store of the chain passed with --chain, stored alongside the blockstore
+func openApprovals(ctx *cli.Context) (*approval.Store, error) {
+	dir, err := getOutboxPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return approval.Open(dir, msg.ChainId(ctx.Uint(config.ChainIdFlag.Name)), nil)
+}
+
+// transferKey returns the key of the transfer passed with --chain, --source and --nonce
+func transferKey(ctx *cli.Context) outbox.Key {
+	return outbox.Key{
+		Source:      msg.ChainId(ctx.Uint(config.SourceIdFlag.Name)),
+		Destination: msg.ChainId(ctx.Uint(config.ChainIdFlag.Name)),
+		Nonce:       msg.Nonce(ctx.Uint64(config.NonceFlag.Name)),
+	}
+}
+
+// handleApprovalsListCmd prints the transfers that require approval
+func handleApprovalsListCmd(ctx *cli.Context) error {
+	s, err := openApprovals(ctx)
+	if err != nil {
+		return err
+	}
+
+	requests, err := s.List()
+	if err != nil {
+		return err
+	}
+	if len(requests) == 0 {
+		fmt.Println("No transfers require approval")
+		return nil
+	}
+	for _, r := range requests {
+		status := string(r.Status)
+		if r.Resolved {
+			status += ", resolved"
+		}
+		fmt.Printf("src: %d nonce: %d resourceId: %s amount: %s status: %s requested: %s\n",
+			r.Source, r.Nonce, r.Resource, r.Amount, status, r.Requested.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+// handleApproveCmd approves a pending transfer, it is voted on by the running relayer
+func handleApproveCmd(ctx *cli.Context) error {
+	s, err := openApprovals(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := transferKey(ctx)
+	err = s.Approve(key)
+	if err != nil {
+		return err
+	}
+	log.Info("Approved transfer", "src", key.Source, "dst", key.Destination, "nonce", key.Nonce)
+	return nil
+}
+
+// handleRejectCmd rejects a pending transfer, the running relayer will not vote on it
+func handleRejectCmd(ctx *cli.Context) error {
+	s, err := openApprovals(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := transferKey(ctx)
+	err = s.Reject(key)
+	if err != nil {
+		return err
+	}
+	log.Info("Rejected transfer", "src", key.Source, "dst", key.Destination, "nonce", key.Nonce)
+	return nil
+}
@@ -12,12 +12,18 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 
 	"strconv"
 
+	"github.com/ChainSafe/ChainBridge/chains/approval"
+	"github.com/ChainSafe/ChainBridge/chains/balance"
 	"github.com/ChainSafe/ChainBridge/chains/ethereum"
+	"github.com/ChainSafe/ChainBridge/chains/outbox"
 	"github.com/ChainSafe/ChainBridge/chains/substrate"
+	"github.com/ChainSafe/ChainBridge/chains/transfers"
 	"github.com/ChainSafe/ChainBridge/config"
+	"github.com/ChainSafe/chainbridge-utils/blockstore"
 	"github.com/ChainSafe/chainbridge-utils/core"
 	"github.com/ChainSafe/chainbridge-utils/metrics/health"
 	metrics "github.com/ChainSafe/chainbridge-utils/metrics/types"
@@ -32,6 +38,7 @@ var app = cli.NewApp()
 var cliFlags = []cli.Flag{
 	config.ConfigFileFlag,
 	config.VerbosityFlag,
+	config.LogFormatFlag,
 	config.KeystorePathFlag,
 	config.BlockstorePathFlag,
 	config.FreshStartFlag,
@@ -97,6 +104,224 @@ var accountCommand = cli.Command{
 	},
 }
 
+var quarantineCommand = cli.Command{
+	Name:  "quarantine",
+	Usage: "manage messages held by rate limits",
+	Description: "The quarantine command is used to manage messages that exceeded a rate limit.\n" +
+		"\tTo list held messages: chainbridge quarantine list --chain <id>\n" +
+		"\tTo release a message: chainbridge quarantine release --chain <id> --source <id> --nonce <nonce>",
+	Subcommands: []*cli.Command{
+		{
+			Action:      handleQuarantineListCmd,
+			Name:        "list",
+			Usage:       "list quarantined messages",
+			Flags:       []cli.Flag{config.BlockstorePathFlag, config.ChainIdFlag},
+			Description: "The list subcommand lists the messages to a chain held in the quarantine.",
+		},
+		{
+			Action: handleQuarantineReleaseCmd,
+			Name:   "release",
+			Usage:  "release a quarantined message",
+			Flags:  []cli.Flag{config.BlockstorePathFlag, config.ChainIdFlag, config.SourceIdFlag, config.NonceFlag},
+			Description: "The release subcommand releases a message held in the quarantine.\n" +
+				"\tThe running relayer resolves it regardless of the rate limit.",
+		},
+	},
+}
+
+var approvalsCommand = cli.Command{
+	Name:  "approvals",
+	Usage: "manage transfers awaiting approval",
+	Description: "The approvals command is used to manage transfers above the approval threshold of their resource.\n" +
+		"\tTo list transfers: chainbridge approvals list --chain <id>\n" +
+		"\tTo approve a transfer: chainbridge approvals approve --chain <id> --source <id> --nonce <nonce>\n" +
+		"\tTo reject a transfer: chainbridge approvals reject --chain <id> --source <id> --nonce <nonce>",
+	Subcommands: []*cli.Command{
+		{
+			Action:      handleApprovalsListCmd,
+			Name:        "list",
+			Usage:       "list transfers awaiting approval",
+			Flags:       []cli.Flag{config.BlockstorePathFlag, config.ChainIdFlag},
+			Description: "The list subcommand lists the transfers to a chain that require approval, and the decisions taken.",
+		},
+		{
+			Action:      handleApproveCmd,
+			Name:        "approve",
+			Usage:       "approve a transfer",
+			Flags:       []cli.Flag{config.BlockstorePathFlag, config.ChainIdFlag, config.SourceIdFlag, config.NonceFlag},
+			Description: "The approve subcommand approves a pending transfer, the running relayer votes on it.",
+		},
+		{
+			Action:      handleRejectCmd,
+			Name:        "reject",
+			Usage:       "reject a transfer",
+			Flags:       []cli.Flag{config.BlockstorePathFlag, config.ChainIdFlag, config.SourceIdFlag, config.NonceFlag},
+			Description: "The reject subcommand rejects a pending transfer, the running relayer will not vote on it.",
+		},
+	},
+}
+
+// adminFlags returns the flags of an admin subcommand, followed by the flags of the subcommand
+func adminFlags(flags ...cli.Flag) []cli.Flag {
+	return append([]cli.Flag{
+		config.UrlFlag,
+		config.FromFlag,
+		config.KeystorePathFlag,
+		config.TestKeyFlag,
+		config.GasLimitFlag,
+		config.GasPriceFlag,
+		config.DryRunFlag,
+		config.JsonFlag,
+	}, flags...)
+}
+
+var adminCommand = cli.Command{
+	Name:  "admin",
+	Usage: "manage the Bridge contract of an ethereum chain",
+	Description: "The admin command is used to deploy and configure the Bridge contract with a key from the keystore.\n" +
+		"\tTo deploy the bridge: chainbridge admin deploy --url <url> --from <address> --chainId <id> --relayers <address>\n" +
+		"\tTo add a relayer: chainbridge admin add-relayer --url <url> --from <address> --bridge <address> --relayer <address>\n" +
+		"\tUse --dry-run to check transactions without sending them, and --json to print them as JSON.",
+	Subcommands: []*cli.Command{
+		{
+			Action: handleAdminDeployCmd,
+			Name:   "deploy",
+			Usage:  "deploy the bridge and its handlers",
+			Flags:  adminFlags(config.DeployChainIdFlag, config.RelayersFlag, config.InitialThresholdFlag, config.InitialFeeFlag, config.ExpiryFlag),
+			Description: "The deploy subcommand deploys the Bridge contract, and an ERC20, ERC721 and generic handler for it.\n" +
+				"\tThe deploying key is the admin of the bridge.",
+		},
+		{
+			Action:      handleAdminAddRelayerCmd,
+			Name:        "add-relayer",
+			Usage:       "add a relayer",
+			Flags:       adminFlags(config.BridgeAddressFlag, config.RelayerFlag),
+			Description: "The add-relayer subcommand grants the relayer role to an address.",
+		},
+		{
+			Action:      handleAdminRemoveRelayerCmd,
+			Name:        "remove-relayer",
+			Usage:       "remove a relayer",
+			Flags:       adminFlags(config.BridgeAddressFlag, config.RelayerFlag),
+			Description: "The remove-relayer subcommand revokes the relayer role of an address.",
+		},
+		{
+			Action:      handleAdminSetThresholdCmd,
+			Name:        "set-threshold",
+			Usage:       "set the relayer threshold",
+			Flags:       adminFlags(config.BridgeAddressFlag, config.ThresholdFlag),
+			Description: "The set-threshold subcommand changes the number of relayer votes required for a proposal to pass.",
+		},
+		{
+			Action: handleAdminRegisterResourceCmd,
+			Name:   "register-resource",
+			Usage:  "register a resource ID",
+			Flags:  adminFlags(config.BridgeAddressFlag, config.HandlerFlag, config.ResourceIdFlag, config.TargetFlag, config.GenericFlag, config.DepositSigFlag, config.ExecuteSigFlag),
+			Description: "The register-resource subcommand maps a resource ID to a token or contract in a handler.\n" +
+				"\tUse --generic with --depositSig and --executeSig to register it with a generic handler.",
+		},
+		{
+			Action:      handleAdminSetBurnableCmd,
+			Name:        "set-burnable",
+			Usage:       "set a token as burnable",
+			Flags:       adminFlags(config.BridgeAddressFlag, config.HandlerFlag, config.TokenFlag),
+			Description: "The set-burnable subcommand makes a handler burn deposited tokens and mint executed ones.",
+		},
+		{
+			Action:      handleAdminPauseCmd,
+			Name:        "pause",
+			Usage:       "pause the bridge",
+			Flags:       adminFlags(config.BridgeAddressFlag),
+			Description: "The pause subcommand pauses deposits and proposals on the bridge.",
+		},
+		{
+			Action:      handleAdminUnpauseCmd,
+			Name:        "unpause",
+			Usage:       "unpause the bridge",
+			Flags:       adminFlags(config.BridgeAddressFlag),
+			Description: "The unpause subcommand resumes deposits and proposals on the bridge.",
+		},
+		{
+			Action:      handleAdminSetFeeCmd,
+			Name:        "set-fee",
+			Usage:       "set the deposit fee",
+			Flags:       adminFlags(config.BridgeAddressFlag, config.FeeFlag),
+			Description: "The set-fee subcommand changes the fee, in wei, paid with each deposit.",
+		},
+		{
+			Action:      handleAdminWithdrawCmd,
+			Name:        "withdraw",
+			Usage:       "withdraw tokens from a handler",
+			Flags:       adminFlags(config.BridgeAddressFlag, config.HandlerFlag, config.TokenFlag, config.RecipientFlag, config.AmountFlag),
+			Description: "The withdraw subcommand sends tokens held by a handler to a recipient.",
+		},
+	},
+}
+
+// substrateAdminFlags returns the flags of a substrate-admin subcommand, followed by the flags of the subcommand
+func substrateAdminFlags(flags ...cli.Flag) []cli.Flag {
+	return append([]cli.Flag{
+		config.UrlFlag,
+		config.FromFlag,
+		config.KeystorePathFlag,
+		config.TestKeyFlag,
+		config.ModeFlag,
+		config.SignatoriesFlag,
+		config.MultisigThresholdFlag,
+		config.TimepointFlag,
+		config.MaxWeightFlag,
+		config.PrintCallFlag,
+		config.JsonFlag,
+	}, flags...)
+}
+
+var substrateAdminCommand = cli.Command{
+	Name:  "substrate-admin",
+	Usage: "manage the bridge pallet of a substrate chain",
+	Description: "The substrate-admin command is used to configure the bridge pallet with an sr25519 key from the keystore.\n" +
+		"\tTo add a relayer with sudo: chainbridge substrate-admin add-relayer --url <url> --from <address> --relayer <address>\n" +
+		"\tTo approve it with a multisig: chainbridge substrate-admin add-relayer --mode multisig --signatories <address> --multisigThreshold 2 ...\n" +
+		"\tUse --print-call to print the encoded call for external signing without submitting it, and --json to print it as JSON.",
+	Subcommands: []*cli.Command{
+		{
+			Action:      handleSubstrateAddRelayerCmd,
+			Name:        "add-relayer",
+			Usage:       "add a relayer",
+			Flags:       substrateAdminFlags(config.SubstrateRelayerFlag),
+			Description: "The add-relayer subcommand adds an account to the relayers of the bridge pallet.",
+		},
+		{
+			Action:      handleSubstrateSetThresholdCmd,
+			Name:        "set-threshold",
+			Usage:       "set the relayer threshold",
+			Flags:       substrateAdminFlags(config.ThresholdFlag),
+			Description: "The set-threshold subcommand changes the number of relayer votes required for a proposal to pass.",
+		},
+		{
+			Action:      handleSubstrateWhitelistChainCmd,
+			Name:        "whitelist-chain",
+			Usage:       "whitelist a chain",
+			Flags:       substrateAdminFlags(config.WhitelistChainIdFlag),
+			Description: "The whitelist-chain subcommand allows transfers to and from a chain.",
+		},
+		{
+			Action:      handleSubstrateRegisterResourceCmd,
+			Name:        "register-resource",
+			Usage:       "register a resource ID",
+			Flags:       substrateAdminFlags(config.ResourceIdFlag, config.ResourceMethodFlag),
+			Description: "The register-resource subcommand maps a resource ID to the call executing its transfers.",
+		},
+		{
+			Action: handleSubstrateInitializeCmd,
+			Name:   "initialize",
+			Usage:  "initialize the bridge pallet",
+			Flags:  substrateAdminFlags(config.SubstrateRelayersFlag, config.ChainsFlag, config.ResourcesFlag, config.InitialThresholdFlag),
+			Description: "The initialize subcommand adds the relayers, whitelists the chains, registers the resources and sets the threshold\n" +
+				"\twith a single Utility.batch call.",
+		},
+	},
+}
+
 var (
 	Version = "0.0.1"
 )
@@ -112,6 +337,10 @@ func init() {
 	app.EnableBashCompletion = true
 	app.Commands = []*cli.Command{
 		&accountCommand,
+		&quarantineCommand,
+		&approvalsCommand,
+		&adminCommand,
+		&substrateAdminCommand,
 	}
 
 	app.Flags = append(app.Flags, cliFlags...)
@@ -130,6 +359,14 @@ func startLogger(ctx *cli.Context) error {
 	handler := logger.GetHandler()
 	var lvl log.Lvl
 
+	switch format := ctx.String(config.LogFormatFlag.Name); format {
+	case "text":
+	case "json":
+		handler = log.StreamHandler(os.Stdout, log.JsonFormat())
+	default:
+		return fmt.Errorf("unknown log format %q, expected text or json", format)
+	}
+
 	if lvlToInt, err := strconv.Atoi(ctx.String(config.VerbosityFlag.Name)); err == nil {
 		lvl = log.Lvl(lvlToInt)
 	} else if lvl, err = log.LvlFromString(ctx.String(config.VerbosityFlag.Name)); err != nil {
@@ -165,6 +402,28 @@ func run(ctx *cli.Context) error {
 		ks = cfg.KeystorePath
 	}
 
+	// Messages are persisted alongside the blockstore so they can be replayed after a restart
+	outboxPath, err := getOutboxPath(ctx)
+	if err != nil {
+		return err
+	}
+	ob, err := outbox.Open(outboxPath)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox: %w", err)
+	}
+	defer ob.Close()
+
+	tr, err := transfers.Open(outboxPath)
+	if err != nil {
+		return fmt.Errorf("failed to open transfer index: %w", err)
+	}
+	defer tr.Close()
+
+	// Every chain's store is served, transfers are only added to those with approval thresholds
+	var approvals []*approval.Store
+	// Chains with a critical relayer balance fail the health check
+	var monitors []*balance.Monitor
+
 	// Used to signal core shutdown due to fatal error
 	sysErr := make(chan error)
 	c := core.NewCore(sysErr)
@@ -196,9 +455,9 @@ func run(ctx *cli.Context) error {
 		}
 
 		if chain.Type == "ethereum" {
-			newChain, err = ethereum.InitializeChain(chainConfig, logger, sysErr, m)
+			newChain, err = ethereum.InitializeChain(chainConfig, logger, sysErr, m, ob, tr)
 		} else if chain.Type == "substrate" {
-			newChain, err = substrate.InitializeChain(chainConfig, logger, sysErr, m)
+			newChain, err = substrate.InitializeChain(chainConfig, logger, sysErr, m, ob, tr)
 		} else {
 			return errors.New("unrecognized Chain Type")
 		}
@@ -207,6 +466,15 @@ func run(ctx *cli.Context) error {
 			return err
 		}
 		c.AddChain(newChain)
+		if mc, ok := newChain.(balance.Monitored); ok && mc.BalanceMonitor() != nil {
+			monitors = append(monitors, mc.BalanceMonitor())
+		}
+
+		store, err := approval.Open(outboxPath, chainConfig.Id, nil)
+		if err != nil {
+			return err
+		}
+		approvals = append(approvals, store)
 
 	}
 
@@ -225,7 +493,10 @@ func run(ctx *cli.Context) error {
 
 		go func() {
 			http.Handle("/metrics", promhttp.Handler())
-			http.HandleFunc("/health", h.HealthStatus)
+			http.HandleFunc("/health", balance.HealthHandler(monitors, h.HealthStatus))
+			http.HandleFunc("/approvals", approval.Handler(approvals))
+			http.HandleFunc("/transfers", transfers.Handler(tr))
+			http.HandleFunc("/transfers/", transfers.Handler(tr))
 			err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
 			if errors.Is(err, http.ErrServerClosed) {
 				log.Info("Health status server is shutting down", err)
@@ -239,3 +510,17 @@ func run(ctx *cli.Context) error {
 
 	return nil
 }
+
+// getOutboxPath returns the directory the outbox is stored in. This is the blockstore path if provided, otherwise
+// the default blockstore location in the home directory.
+func getOutboxPath(ctx *cli.Context) (string, error) {
+	if path := ctx.String(config.BlockstorePathFlag.Name); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, blockstore.PathPostfix), nil
+}
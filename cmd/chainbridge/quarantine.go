@@ -0,0 +1,62 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ChainSafe/ChainBridge/chains/ratelimit"
+	"github.com/ChainSafe/ChainBridge/config"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	log "github.com/ChainSafe/log15"
+	"github.com/urfave/cli/v2"
+)
+
+// openQuarantine opens the quarantine of the chain passed with --chain, stored alongside the blockstore
+func openQuarantine(ctx *cli.Context) (*ratelimit.Quarantine, error) {
+	dir, err := getOutboxPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ratelimit.OpenQuarantine(dir, msg.ChainId(ctx.Uint(config.ChainIdFlag.Name)))
+}
+
+// handleQuarantineListCmd prints the messages held in the quarantine
+func handleQuarantineListCmd(ctx *cli.Context) error {
+	q, err := openQuarantine(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := q.List()
+	if len(entries) == 0 {
+		fmt.Println("No quarantined messages")
+		return nil
+	}
+	for _, e := range entries {
+		status := "held"
+		if e.Released {
+			status = "released"
+		}
+		fmt.Printf("src: %d nonce: %d resourceId: %x status: %s held: %s reason: %s\n",
+			e.Message.Source, e.Message.DepositNonce, e.Message.ResourceId, status, e.HeldAt.Format("2006-01-02 15:04:05"), e.Reason)
+	}
+	return nil
+}
+
+// handleQuarantineReleaseCmd releases a message from the quarantine, it is resolved by the running relayer
+func handleQuarantineReleaseCmd(ctx *cli.Context) error {
+	q, err := openQuarantine(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := transferKey(ctx)
+	err = q.Release(key)
+	if err != nil {
+		return err
+	}
+	log.Info("Released message from quarantine", "src", key.Source, "dst", key.Destination, "nonce", key.Nonce)
+	return nil
+}
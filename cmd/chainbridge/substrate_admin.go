@@ -0,0 +1,345 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ChainSafe/ChainBridge/config"
+	utils "github.com/ChainSafe/ChainBridge/shared/substrate"
+	"github.com/ChainSafe/chainbridge-utils/crypto/sr25519"
+	"github.com/ChainSafe/chainbridge-utils/keystore"
+	"github.com/ChainSafe/chainbridge-utils/msg"
+	"github.com/centrifuge/go-substrate-rpc-client/signature"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/urfave/cli/v2"
+)
+
+// Origins the admin calls of the bridge pallet can be dispatched with
+const (
+	SudoMode     = "sudo"
+	MultisigMode = "multisig"
+)
+
+// multisigParams are the parameters of a multisig approval, other is the signatories other than the signer
+type multisigParams struct {
+	threshold uint16
+	other     []types.AccountID
+	timepoint *utils.Timepoint
+	maxWeight uint64
+}
+
+// substrateAdminCall describes an admin call of the bridge pallet, and the call dispatching it with the origin of
+// the mode. With --print-call it is not submitted.
+type substrateAdminCall struct {
+	Method      string `json:"method"`
+	Call        string `json:"call"`
+	CallHash    string `json:"callHash"`
+	Mode        string `json:"mode"`
+	Dispatch    string `json:"dispatch"`
+	DispatchHex string `json:"dispatchCall"`
+	Block       string `json:"block,omitempty"`
+	Submitted   bool   `json:"submitted"`
+}
+
+// newSubstrateAdminCall encodes the admin call, and wraps it in a call dispatching it with the origin of the mode
+func newSubstrateAdminCall(client *utils.Client, method utils.Method, call types.Call, mode string, multisig *multisigParams) (*substrateAdminCall, types.Call, error) {
+	var dispatch types.Call
+	var dispatchMethod utils.Method
+	var err error
+	switch mode {
+	case SudoMode:
+		dispatchMethod = utils.SudoMethod
+		dispatch, err = client.NewSudoCall(call)
+	case MultisigMode:
+		dispatchMethod = utils.MultisigAsMultiMethod
+		dispatch, err = client.NewMultisigCall(multisig.threshold, multisig.other, multisig.timepoint, call, multisig.maxWeight)
+	default:
+		err = fmt.Errorf("unknown mode %s", mode)
+	}
+	if err != nil {
+		return nil, types.Call{}, err
+	}
+
+	encoded, err := types.EncodeToHexString(call)
+	if err != nil {
+		return nil, types.Call{}, err
+	}
+	hash, err := types.GetHash(call)
+	if err != nil {
+		return nil, types.Call{}, err
+	}
+	encodedDispatch, err := types.EncodeToHexString(dispatch)
+	if err != nil {
+		return nil, types.Call{}, err
+	}
+
+	return &substrateAdminCall{
+		Method:      string(method),
+		Call:        encoded,
+		CallHash:    hash.Hex(),
+		Mode:        mode,
+		Dispatch:    string(dispatchMethod),
+		DispatchHex: encodedDispatch,
+	}, dispatch, nil
+}
+
+// print writes the call, as JSON if asJson is set
+func (c *substrateAdminCall) print(w io.Writer, asJson bool) error {
+	if asJson {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	}
+
+	fmt.Fprintf(w, "%s\n", c.Method)
+	fmt.Fprintf(w, "  call: %s\n", c.Call)
+	fmt.Fprintf(w, "  call hash: %s\n", c.CallHash)
+	fmt.Fprintf(w, "  %s call: %s\n", c.Dispatch, c.DispatchHex)
+	if c.Submitted {
+		fmt.Fprintf(w, "  in block: %s\n", c.Block)
+	} else {
+		fmt.Fprintln(w, "  not submitted")
+	}
+	return nil
+}
+
+// substrateAdminKeypair loads the sr25519 key passed with --from from the keystore, or the test key passed with
+// --testkey
+func substrateAdminKeypair(ctx *cli.Context) (*signature.KeyringPair, error) {
+	kp, err := loadAdminKeypair(ctx, keystore.SubChain)
+	if err != nil {
+		return nil, err
+	}
+	return kp.(*sr25519.Keypair).AsKeyringPair(), nil
+}
+
+// parseMultisigParams parses the multisig flags. The signer must not be one of the other signatories.
+func parseMultisigParams(ctx *cli.Context, signer *signature.KeyringPair) (*multisigParams, error) {
+	other, err := parseAccountIds(ctx, config.SignatoriesFlag)
+	if err != nil {
+		return nil, err
+	}
+	if len(other) == 0 {
+		return nil, fmt.Errorf("--%s is required with --%s %s", config.SignatoriesFlag.Name, config.ModeFlag.Name, MultisigMode)
+	}
+	if signer != nil {
+		for _, acc := range other {
+			if acc == types.NewAccountID(signer.PublicKey) {
+				return nil, fmt.Errorf("--%s must not include the signer %s", config.SignatoriesFlag.Name, signer.Address)
+			}
+		}
+	}
+
+	threshold := ctx.Uint(config.MultisigThresholdFlag.Name)
+	if threshold < 2 || threshold > uint(len(other))+1 {
+		return nil, fmt.Errorf("invalid --%s %d, must be between 2 and the number of signatories", config.MultisigThresholdFlag.Name, threshold)
+	}
+
+	var timepoint *utils.Timepoint
+	if s := ctx.String(config.TimepointFlag.Name); s != "" {
+		timepoint, err = parseTimepoint(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &multisigParams{
+		threshold: uint16(threshold),
+		other:     other,
+		timepoint: timepoint,
+		maxWeight: ctx.Uint64(config.MaxWeightFlag.Name),
+	}, nil
+}
+
+// parseTimepoint parses a timepoint formatted as <height>:<index>
+func parseTimepoint(s string) (*utils.Timepoint, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid --%s %s, must be <height>:<index>", config.TimepointFlag.Name, s)
+	}
+	height, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s height %s", config.TimepointFlag.Name, parts[0])
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s index %s", config.TimepointFlag.Name, parts[1])
+	}
+	return &utils.Timepoint{Height: types.U32(height), Index: types.U32(index)}, nil
+}
+
+// parseAccountIds parses the accounts passed with the flag
+func parseAccountIds(ctx *cli.Context, flag *cli.StringSliceFlag) ([]types.AccountID, error) {
+	var accounts []types.AccountID
+	for _, s := range ctx.StringSlice(flag.Name) {
+		acc, err := utils.ParseAccountId(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", flag.Name, err)
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, nil
+}
+
+// parseChainId parses a chain ID, which must fit in a uint8
+func parseChainId(flag cli.Flag, id int) (msg.ChainId, error) {
+	if id < 0 || id > 255 {
+		return 0, fmt.Errorf("invalid --%s %d, must fit in a uint8", flag.Names()[0], id)
+	}
+	return msg.ChainId(id), nil
+}
+
+// parseSubstrateResourceId parses a hex resource ID
+func parseSubstrateResourceId(s string) (msg.ResourceId, error) {
+	bz, err := types.HexDecodeString(s)
+	if err != nil || len(bz) != 32 {
+		return msg.ResourceId{}, fmt.Errorf("invalid resource ID %s", s)
+	}
+	return msg.ResourceIdFromSlice(bz), nil
+}
+
+// runSubstrateAdmin builds the admin call with a client connected to --url, and submits it with the origin of --mode.
+// With --print-call the call is printed without being submitted, and no key is required.
+func runSubstrateAdmin(ctx *cli.Context, method utils.Method, build func(c *utils.Client) (types.Call, error)) error {
+	printOnly := ctx.Bool(config.PrintCallFlag.Name)
+	mode := ctx.String(config.ModeFlag.Name)
+	if mode != SudoMode && mode != MultisigMode {
+		return fmt.Errorf("invalid --%s %s, must be %s or %s", config.ModeFlag.Name, mode, SudoMode, MultisigMode)
+	}
+
+	var kp *signature.KeyringPair
+	var err error
+	if !printOnly {
+		kp, err = substrateAdminKeypair(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	var multisig *multisigParams
+	if mode == MultisigMode {
+		multisig, err = parseMultisigParams(ctx, kp)
+		if err != nil {
+			return err
+		}
+	}
+
+	client, err := utils.CreateClient(kp, ctx.String(config.UrlFlag.Name))
+	if err != nil {
+		return err
+	}
+	call, err := build(client)
+	if err != nil {
+		return err
+	}
+	res, dispatch, err := newSubstrateAdminCall(client, method, call, mode, multisig)
+	if err != nil {
+		return err
+	}
+
+	if !printOnly {
+		block, err := utils.SubmitCall(client, dispatch)
+		if err != nil {
+			return fmt.Errorf("%s failed: %w", res.Dispatch, err)
+		}
+		res.Block = block.Hex()
+		res.Submitted = true
+	}
+	return res.print(os.Stdout, ctx.Bool(config.JsonFlag.Name))
+}
+
+// handleSubstrateAddRelayerCmd adds a relayer to the bridge pallet
+func handleSubstrateAddRelayerCmd(ctx *cli.Context) error {
+	relayer, err := utils.ParseAccountId(ctx.String(config.SubstrateRelayerFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid --%s: %w", config.SubstrateRelayerFlag.Name, err)
+	}
+	return runSubstrateAdmin(ctx, utils.AddRelayerMethod, func(c *utils.Client) (types.Call, error) {
+		return c.NewAddRelayerAdminCall(relayer)
+	})
+}
+
+// handleSubstrateSetThresholdCmd changes the number of relayer votes required for a proposal to pass
+func handleSubstrateSetThresholdCmd(ctx *cli.Context) error {
+	threshold := ctx.Uint64(config.ThresholdFlag.Name)
+	if threshold > uint64(^uint32(0)) {
+		return fmt.Errorf("invalid --%s %d, must fit in a uint32", config.ThresholdFlag.Name, threshold)
+	}
+	return runSubstrateAdmin(ctx, utils.SetThresholdMethod, func(c *utils.Client) (types.Call, error) {
+		return c.NewSetRelayerThresholdAdminCall(types.U32(threshold))
+	})
+}
+
+// handleSubstrateWhitelistChainCmd allows transfers to and from a chain
+func handleSubstrateWhitelistChainCmd(ctx *cli.Context) error {
+	id, err := parseChainId(config.WhitelistChainIdFlag, int(ctx.Uint(config.WhitelistChainIdFlag.Name)))
+	if err != nil {
+		return err
+	}
+	return runSubstrateAdmin(ctx, utils.WhitelistChainMethod, func(c *utils.Client) (types.Call, error) {
+		return c.NewWhitelistChainAdminCall(id)
+	})
+}
+
+// handleSubstrateRegisterResourceCmd maps a resource ID to the call executing its transfers
+func handleSubstrateRegisterResourceCmd(ctx *cli.Context) error {
+	rId, err := parseSubstrateResourceId(ctx.String(config.ResourceIdFlag.Name))
+	if err != nil {
+		return err
+	}
+	method := ctx.String(config.ResourceMethodFlag.Name)
+	return runSubstrateAdmin(ctx, utils.SetResourceMethod, func(c *utils.Client) (types.Call, error) {
+		return c.NewRegisterResourceAdminCall(rId, method)
+	})
+}
+
+// handleSubstrateInitializeCmd adds the relayers, whitelists the chains, registers the resources and sets the
+// threshold with a single batch call
+func handleSubstrateInitializeCmd(ctx *cli.Context) error {
+	relayers, err := parseAccountIds(ctx, config.SubstrateRelayersFlag)
+	if err != nil {
+		return err
+	}
+	var chains []msg.ChainId
+	for _, c := range ctx.IntSlice(config.ChainsFlag.Name) {
+		id, err := parseChainId(config.ChainsFlag, c)
+		if err != nil {
+			return err
+		}
+		chains = append(chains, id)
+	}
+	resources := make(map[msg.ResourceId]utils.Method)
+	for _, r := range ctx.StringSlice(config.ResourcesFlag.Name) {
+		parts := strings.SplitN(r, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("invalid --%s %s, must be <resourceId>=<method>", config.ResourcesFlag.Name, r)
+		}
+		rId, err := parseSubstrateResourceId(parts[0])
+		if err != nil {
+			return err
+		}
+		resources[rId] = utils.Method(parts[1])
+	}
+	threshold := ctx.Uint64(config.InitialThresholdFlag.Name)
+	if threshold > uint64(^uint32(0)) {
+		return fmt.Errorf("invalid --%s %d, must fit in a uint32", config.InitialThresholdFlag.Name, threshold)
+	}
+	if len(relayers) == 0 && len(chains) == 0 && len(resources) == 0 {
+		return errors.New("nothing to initialize, set --relayers, --chains or --resources")
+	}
+
+	return runSubstrateAdmin(ctx, utils.UtilityBatchMethod, func(c *utils.Client) (types.Call, error) {
+		calls, err := utils.NewInitializeChainCalls(c, relayers, chains, resources, uint32(threshold))
+		if err != nil {
+			return types.Call{}, err
+		}
+		return c.NewBatchCall(calls)
+	})
+}
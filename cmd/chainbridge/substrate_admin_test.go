@@ -0,0 +1,74 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	utils "github.com/ChainSafe/ChainBridge/shared/substrate"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+func TestSubstrateAdminCall(t *testing.T) {
+	client := &utils.Client{Meta: types.ExamplaryMetadataV11Substrate}
+	call, err := types.NewCall(client.Meta, "System.remark", []byte{0xab})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, dispatch, err := newSubstrateAdminCall(client, "System.remark", call, SudoMode, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sudo, err := client.NewSudoCall(call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !types.Eq(dispatch, sudo) || res.Dispatch != string(utils.SudoMethod) {
+		t.Fatalf("expected sudo call, got %s", res.Dispatch)
+	}
+
+	other := types.NewAccountID(bytes.Repeat([]byte{1}, 32))
+	multisig := &multisigParams{threshold: 2, other: []types.AccountID{other}, maxWeight: 1000}
+	res, _, err = newSubstrateAdminCall(client, "System.remark", call, MultisigMode, multisig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = res.print(&buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var printed substrateAdminCall
+	err = json.Unmarshal(buf.Bytes(), &printed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := types.GetHash(call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if printed.Call != "0x000104ab" || printed.CallHash != hash.Hex() || printed.Dispatch != string(utils.MultisigAsMultiMethod) || printed.Submitted {
+		t.Fatalf("unexpected output %s", buf.String())
+	}
+}
+
+func TestParseTimepoint(t *testing.T) {
+	tp, err := parseTimepoint("120:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp.Height != 120 || tp.Index != 2 {
+		t.Fatalf("unexpected timepoint %+v", tp)
+	}
+
+	for _, s := range []string{"120", "120:", "a:2", "120:2:1"} {
+		if _, err := parseTimepoint(s); err == nil {
+			t.Errorf("expected error for %s", s)
+		}
+	}
+}
@@ -25,6 +25,12 @@ var (
 		Value: log.LvlInfo.String(),
 	}
 
+	LogFormatFlag = &cli.StringFlag{
+		Name:  "log-format",
+		Usage: "Log output format, text or json",
+		Value: "text",
+	}
+
 	KeystorePathFlag = &cli.StringFlag{
 		Name:  "keystore",
 		Usage: "Path to keystore directory",
@@ -94,6 +100,197 @@ var (
 	}
 )
 
+// Quarantine subcommand flags
+var (
+	ChainIdFlag = &cli.UintFlag{
+		Name:     "chain",
+		Usage:    "ID of the destination chain the quarantine belongs to",
+		Required: true,
+	}
+	SourceIdFlag = &cli.UintFlag{
+		Name:     "source",
+		Usage:    "ID of the chain the deposit was made on",
+		Required: true,
+	}
+	NonceFlag = &cli.Uint64Flag{
+		Name:     "nonce",
+		Usage:    "Deposit nonce of the message",
+		Required: true,
+	}
+)
+
+// Admin subcommand flags
+var (
+	UrlFlag = &cli.StringFlag{
+		Name:     "url",
+		Usage:    "URL of the node to send transactions to",
+		Required: true,
+	}
+	FromFlag = &cli.StringFlag{
+		Name:  "from",
+		Usage: "Address of the key in the keystore to sign transactions with",
+	}
+	DryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Build and check transactions without sending them",
+	}
+	JsonFlag = &cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the result as JSON",
+	}
+	BridgeAddressFlag = &cli.StringFlag{
+		Name:     "bridge",
+		Usage:    "Address of the Bridge contract",
+		Required: true,
+	}
+	GasLimitFlag = &cli.Uint64Flag{
+		Name:  "gasLimit",
+		Usage: "Gas limit of transactions, estimated by the node if not set",
+	}
+	GasPriceFlag = &cli.StringFlag{
+		Name:  "gasPrice",
+		Usage: "Gas price of transactions in wei, suggested by the node if not set",
+	}
+	DeployChainIdFlag = &cli.UintFlag{
+		Name:     "chainId",
+		Usage:    "ID of the chain in the bridge",
+		Required: true,
+	}
+	RelayersFlag = &cli.StringSliceFlag{
+		Name:  "relayers",
+		Usage: "Addresses of the initial relayers",
+	}
+	InitialThresholdFlag = &cli.Uint64Flag{
+		Name:  "threshold",
+		Usage: "Number of relayer votes required for a proposal to pass",
+		Value: 1,
+	}
+	InitialFeeFlag = &cli.StringFlag{
+		Name:  "fee",
+		Usage: "Fee of deposits in wei",
+		Value: "0",
+	}
+	ExpiryFlag = &cli.Uint64Flag{
+		Name:  "expiry",
+		Usage: "Number of blocks after which an active proposal can be cancelled",
+		Value: 100,
+	}
+	RelayerFlag = &cli.StringFlag{
+		Name:     "relayer",
+		Usage:    "Address of the relayer",
+		Required: true,
+	}
+	ThresholdFlag = &cli.Uint64Flag{
+		Name:     "threshold",
+		Usage:    "Number of relayer votes required for a proposal to pass",
+		Required: true,
+	}
+	FeeFlag = &cli.StringFlag{
+		Name:     "fee",
+		Usage:    "Fee of deposits in wei",
+		Required: true,
+	}
+	HandlerFlag = &cli.StringFlag{
+		Name:     "handler",
+		Usage:    "Address of the handler contract",
+		Required: true,
+	}
+	ResourceIdFlag = &cli.StringFlag{
+		Name:     "resourceId",
+		Usage:    "Hex encoded resource ID",
+		Required: true,
+	}
+	TargetFlag = &cli.StringFlag{
+		Name:     "target",
+		Usage:    "Address of the token or contract the resource ID is mapped to",
+		Required: true,
+	}
+	GenericFlag = &cli.BoolFlag{
+		Name:  "generic",
+		Usage: "Register the resource ID with a generic handler",
+	}
+	DepositSigFlag = &cli.StringFlag{
+		Name:  "depositSig",
+		Usage: "Signature of the function the generic handler calls on deposits, e.g. store(bytes32)",
+	}
+	ExecuteSigFlag = &cli.StringFlag{
+		Name:  "executeSig",
+		Usage: "Signature of the function the generic handler calls on executions, e.g. store(bytes32)",
+	}
+	TokenFlag = &cli.StringFlag{
+		Name:     "token",
+		Usage:    "Address of the token contract",
+		Required: true,
+	}
+	RecipientFlag = &cli.StringFlag{
+		Name:     "recipient",
+		Usage:    "Address the tokens are sent to",
+		Required: true,
+	}
+	AmountFlag = &cli.StringFlag{
+		Name:     "amount",
+		Usage:    "Amount in the token's base unit, or the ID of an ERC721 token",
+		Required: true,
+	}
+)
+
+// Substrate admin subcommand flags
+var (
+	ModeFlag = &cli.StringFlag{
+		Name:  "mode",
+		Usage: "Origin admin calls are dispatched with: sudo or multisig",
+		Value: "sudo",
+	}
+	PrintCallFlag = &cli.BoolFlag{
+		Name:  "print-call",
+		Usage: "Print the encoded call to sign it externally, without submitting it",
+	}
+	SignatoriesFlag = &cli.StringSliceFlag{
+		Name:  "signatories",
+		Usage: "Other signatories of the multisig account, as hex public keys or SS58 addresses",
+	}
+	MultisigThresholdFlag = &cli.UintFlag{
+		Name:  "multisigThreshold",
+		Usage: "Number of approvals required to dispatch a multisig call",
+	}
+	TimepointFlag = &cli.StringFlag{
+		Name:  "timepoint",
+		Usage: "Block height and extrinsic index of the first approval of a multisig call, as <height>:<index>",
+	}
+	MaxWeightFlag = &cli.Uint64Flag{
+		Name:  "maxWeight",
+		Usage: "Maximum weight of the call dispatched by the final multisig approval",
+		Value: 1000000000,
+	}
+	SubstrateRelayerFlag = &cli.StringFlag{
+		Name:     "relayer",
+		Usage:    "Account of the relayer, as a hex public key or SS58 address",
+		Required: true,
+	}
+	SubstrateRelayersFlag = &cli.StringSliceFlag{
+		Name:  "relayers",
+		Usage: "Accounts of the initial relayers, as hex public keys or SS58 addresses",
+	}
+	WhitelistChainIdFlag = &cli.UintFlag{
+		Name:     "chainId",
+		Usage:    "ID of the chain to whitelist",
+		Required: true,
+	}
+	ResourceMethodFlag = &cli.StringFlag{
+		Name:     "method",
+		Usage:    "Call executing transfers of the resource, e.g. Example.transfer",
+		Required: true,
+	}
+	ChainsFlag = &cli.IntSliceFlag{
+		Name:  "chains",
+		Usage: "IDs of the chains to whitelist",
+	}
+	ResourcesFlag = &cli.StringSliceFlag{
+		Name:  "resources",
+		Usage: "Resources to register, as <resourceId>=<method>",
+	}
+)
+
 // Test Setting Flags
 var (
 	TestKeyFlag = &cli.StringFlag{
@@ -11,20 +11,25 @@ import (
 	"sync"
 	"time"
 
-	"github.com/ChainSafe/ChainBridge/connections/ethereum/egs"
 	"github.com/ChainSafe/chainbridge-utils/crypto/secp256k1"
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	ethcrypto "github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var BlockRetryInterval = time.Second * 5
 
+// Minimum fee increase, in percent, of a replacement transaction. Nodes require at least 10%.
+const TxReplacementBump = 12
+
+var ErrGasPriceCapReached = errors.New("transaction fees already at max gas price")
+
 type Connection struct {
-	endpoint      string
+	endpoint      string // Comma separated list of RPC endpoints
 	itxEndpoint   *string
 	http          bool
 	kp            *secp256k1.Keypair
@@ -33,12 +38,19 @@ type Connection struct {
 	gasMultiplier *big.Float
 	egsApiKey     string
 	egsSpeed      string
-	conn          *ethclient.Client
+	gasPricerCfg  GasPricerConfig
+	gasPricer     GasPricer
+	gasSource     GasPriceSource
+	gasMetrics    *GasPriceMetrics
+	finality      Finality
+	readQuorum    int
+	conn          *failoverClient
 	itxRpc        *rpc.Client
 	itxSchedule   string
 	opts          *bind.TransactOpts
 	callOpts      *bind.CallOpts
-	nonce         uint64
+	chainId       *big.Int
+	nonces        *nonceManager
 	optsLock      sync.Mutex
 	log           log15.Logger
 	stop          chan int // All routines should exit when this channel is closed
@@ -57,27 +69,60 @@ func NewConnection(endpoint string, itxEndpoint *string, itxSchedule string, htt
 		gasMultiplier: gasMultiplier,
 		egsApiKey:     gsnApiKey,
 		egsSpeed:      gsnSpeed,
+		finality:      ConfirmationsFinality,
+		readQuorum:    1,
 		log:           log,
 		stop:          make(chan int),
 	}
 }
 
+// SetGasPricer selects the strategy used to price transactions, must be called before Connection.Connect().
+// The node strategy is used by default.
+func (c *Connection) SetGasPricer(cfg GasPricerConfig) {
+	c.gasPricerCfg = cfg
+}
+
+// SetGasPriceMetrics enables reporting of the selected gas prices
+func (c *Connection) SetGasPriceMetrics(m *GasPriceMetrics) {
+	c.gasMetrics = m
+}
+
+// SetFinality selects which blocks are considered final, must be called before Connection.Connect().
+// Confirmations finality is used by default.
+func (c *Connection) SetFinality(f Finality) {
+	c.finality = f
+}
+
+// SetReadQuorum sets the number of endpoints that must agree on block numbers and contract calls,
+// must be called before Connection.Connect(). Defaults to 1.
+func (c *Connection) SetReadQuorum(quorum int) {
+	c.readQuorum = quorum
+}
+
 // Connect starts the ethereum WS connection
 func (c *Connection) Connect() error {
 	c.log.Info("Connecting to ethereum chain...", "url", c.endpoint)
-	var rpcClient *rpc.Client
 	var err error
-	// Start http or ws client
-	if c.http {
-		rpcClient, err = rpc.DialHTTP(c.endpoint)
-	} else {
-		rpcClient, err = rpc.DialContext(context.Background(), c.endpoint)
+	// Start http or ws clients
+	urls := splitEndpoints(c.endpoint)
+	c.conn, err = dialEndpoints(urls, c.http, c.readQuorum, c.log)
+	if err != nil {
+		return err
+	}
+	if len(urls) > 1 {
+		_, err = c.conn.checkChainId(context.Background())
+		if err != nil {
+			return err
+		}
+		go c.conn.healthCheck(c.stop)
 	}
+
+	c.gasSource = &clientGasPriceSource{c.conn}
+	c.gasPricer, err = NewGasPricer(c.gasPricerCfg, c.gasSource, c.maxGasPrice, c.gasMultiplier, c.egsApiKey, c.egsSpeed, c.log)
 	if err != nil {
 		return err
 	}
 
-	c.conn = ethclient.NewClient(rpcClient)
 	var itxRpc *rpc.Client
 	if c.itxEndpoint != nil {
 		c.log.Info("Connecting to ITX...", "url", *c.itxEndpoint)
@@ -96,8 +141,20 @@ func (c *Connection) Connect() error {
 		return err
 	}
 	c.opts = opts
-	c.nonce = 0
+	c.nonces = newNonceManager(c.conn, opts.From)
+	c.chainId, err = c.conn.ChainID(context.Background())
+	if err != nil {
+		return err
+	}
 	c.callOpts = &bind.CallOpts{From: c.kp.CommonAddress()}
+
+	// Fail early if the node does not support the block tag
+	if c.finality != ConfirmationsFinality {
+		_, err = c.ProcessableBlock(nil)
+		if err != nil {
+			return fmt.Errorf("unable to query %s block: %w", c.finality, err)
+		}
+	}
 	return nil
 }
 
@@ -142,7 +199,8 @@ func (c *Connection) ItxSchedule() string {
 	return c.itxSchedule
 }
 
-func (c *Connection) Client() *ethclient.Client {
+// Client returns the client for the chain, which fails over between the configured endpoints
+func (c *Connection) Client() Client {
 	return c.conn
 }
 
@@ -154,71 +212,25 @@ func (c *Connection) CallOpts() *bind.CallOpts {
 	return c.callOpts
 }
 
+// SafeEstimateGas returns the legacy gas price suggested by EGS or the node, capped at the max gas price
 func (c *Connection) SafeEstimateGas(ctx context.Context) (*big.Int, error) {
-
-	var suggestedGasPrice *big.Int
-
-	// First attempt to use EGS for the gas price if the api key is supplied
-	if c.egsApiKey != "" {
-		price, err := egs.FetchGasPrice(c.egsApiKey, c.egsSpeed)
-		if err != nil {
-			c.log.Error("Couldn't fetch gasPrice from GSN", "err", err)
-		} else {
-			suggestedGasPrice = price
-		}
-	}
-
-	// Fallback to the node rpc method for the gas price if GSN did not provide a price
-	if suggestedGasPrice == nil {
-		c.log.Debug("Fetching gasPrice from node")
-		nodePriceEstimate, err := c.conn.SuggestGasPrice(context.TODO())
-		if err != nil {
-			return nil, err
-		} else {
-			suggestedGasPrice = nodePriceEstimate
-		}
-	}
-
-	gasPrice := multiplyGasPrice(suggestedGasPrice, c.gasMultiplier)
-
-	// Check we aren't exceeding our limit
-	if gasPrice.Cmp(c.maxGasPrice) == 1 {
-		return c.maxGasPrice, nil
-	} else {
-		return gasPrice, nil
-	}
+	return c.nodeGasPricer().legacyGasPrice(ctx)
 }
 
+// EstimateGasLondon returns the tip and fee cap for an EIP-1559 transaction, capped at the max gas price
 func (c *Connection) EstimateGasLondon(ctx context.Context, baseFee *big.Int) (*big.Int, *big.Int, error) {
-	var maxPriorityFeePerGas *big.Int
-	var maxFeePerGas *big.Int
-
-	if c.maxGasPrice.Cmp(baseFee) < 0 {
-		maxPriorityFeePerGas = big.NewInt(1)
-		maxFeePerGas = new(big.Int).Add(baseFee, maxPriorityFeePerGas)
-		return maxPriorityFeePerGas, maxFeePerGas, nil
-	}
-
-	maxPriorityFeePerGas, err := c.conn.SuggestGasTipCap(context.TODO())
-	if err != nil {
-		return nil, nil, err
-	}
-
-	maxFeePerGas = new(big.Int).Add(
-		maxPriorityFeePerGas,
-		new(big.Int).Mul(baseFee, big.NewInt(2)),
-	)
-
-	if maxFeePerGas.Cmp(maxPriorityFeePerGas) < 0 {
-		return nil, nil, fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", maxFeePerGas, maxPriorityFeePerGas)
-	}
+	return c.nodeGasPricer().londonGasPrice(ctx, baseFee)
+}
 
-	// Check we aren't exceeding our limit
-	if maxFeePerGas.Cmp(c.maxGasPrice) == 1 {
-		maxPriorityFeePerGas.Sub(c.maxGasPrice, baseFee)
-		maxFeePerGas = c.maxGasPrice
+func (c *Connection) nodeGasPricer() *nodeGasPricer {
+	return &nodeGasPricer{
+		source:        c.gasSource,
+		maxGasPrice:   c.maxGasPrice,
+		gasMultiplier: c.gasMultiplier,
+		egsApiKey:     c.egsApiKey,
+		egsSpeed:      c.egsSpeed,
+		log:           c.log,
 	}
-	return maxPriorityFeePerGas, maxFeePerGas, nil
 }
 
 func multiplyGasPrice(gasEstimate *big.Int, gasMultiplier *big.Float) *big.Int {
@@ -235,7 +247,8 @@ func multiplyGasPrice(gasEstimate *big.Int, gasMultiplier *big.Float) *big.Int {
 }
 
 // LockAndUpdateOpts acquires a lock on the opts before updating the nonce
-// and gas price.
+// and gas price. The nonce is handed out locally, the lock must be released with
+// UnlockOptsAfterSend once the transaction has been submitted.
 func (c *Connection) LockAndUpdateOpts() error {
 	c.optsLock.Lock()
 
@@ -245,45 +258,180 @@ func (c *Connection) LockAndUpdateOpts() error {
 		return err
 	}
 
-	if head.BaseFee != nil {
-		c.opts.GasTipCap, c.opts.GasFeeCap, err = c.EstimateGasLondon(context.TODO(), head.BaseFee)
-		if err != nil {
-			c.UnlockOpts()
-			return err
-		}
+	nonce, err := c.nonces.reserve(context.Background())
+	if err != nil {
+		c.UnlockOpts()
+		return err
+	}
+
+	price, err := c.gasPricer.GasPrice(context.TODO(), head, nonce)
+	if err != nil {
+		// No other nonce can have been reserved while the opts are locked, so this never leaves a gap
+		c.nonces.unused(nonce)
+		c.UnlockOpts()
+		return err
+	}
 
+	if price.IsLondon() {
+		c.opts.GasTipCap, c.opts.GasFeeCap = price.GasTipCap, price.GasFeeCap
 		// Both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) cannot be specified: https://github.com/ethereum/go-ethereum/blob/95bbd46eabc5d95d9fb2108ec232dd62df2f44ab/accounts/abi/bind/base.go#L254
 		c.opts.GasPrice = nil
 	} else {
-		var gasPrice *big.Int
-		gasPrice, err = c.SafeEstimateGas(context.TODO())
-		if err != nil {
-			c.UnlockOpts()
-			return err
+		c.opts.GasPrice = price.GasPrice
+		c.opts.GasTipCap, c.opts.GasFeeCap = nil, nil
+	}
+	if c.gasMetrics != nil {
+		c.gasMetrics.report(price)
+	}
+
+	c.opts.Nonce.SetUint64(nonce)
+	return nil
+}
+
+func (c *Connection) UnlockOpts() {
+	c.optsLock.Unlock()
+}
+
+// UnlockOptsAfterSend releases the lock on the opts after submitting a transaction with them. If the
+// transaction was not sent, or rejected by the node, the nonce is handed out again. Only if the send
+// failed after the node may have received the transaction is the nonce filled with a self-transfer, so
+// that later transactions are not blocked.
+func (c *Connection) UnlockOptsAfterSend(err error) {
+	defer c.optsLock.Unlock()
+
+	nonce := c.opts.Nonce.Uint64()
+	if !c.nonces.result(nonce, err) {
+		return
+	}
+
+	c.log.Debug("Transaction submission failed, filling nonce gap", "nonce", nonce, "err", err)
+	err = c.fillNonceGap(nonce)
+	if err != nil && !isNonceDivergence(err) {
+		c.log.Warn("Failed to fill nonce gap, resyncing nonce", "nonce", nonce, "err", err)
+		c.nonces.resync()
+	}
+}
+
+// fillNonceGap sends a zero value transfer to our own account with the nonce, using the current fees.
+// Must be called with the opts locked.
+func (c *Connection) fillNonceGap(nonce uint64) error {
+	var tx types.TxData
+	if c.opts.GasFeeCap != nil {
+		tx = &types.DynamicFeeTx{
+			ChainID:   c.chainId,
+			Nonce:     nonce,
+			GasTipCap: c.opts.GasTipCap,
+			GasFeeCap: c.opts.GasFeeCap,
+			Gas:       params.TxGas,
+			To:        &c.opts.From,
+			Value:     big.NewInt(0),
+		}
+	} else {
+		tx = &types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: c.opts.GasPrice,
+			Gas:      params.TxGas,
+			To:       &c.opts.From,
+			Value:    big.NewInt(0),
 		}
-		c.opts.GasPrice = gasPrice
 	}
 
-	nonce, err := c.conn.PendingNonceAt(context.Background(), c.opts.From)
+	signed, err := c.opts.Signer(c.opts.From, types.NewTx(tx))
 	if err != nil {
-		c.optsLock.Unlock()
 		return err
 	}
-	c.opts.Nonce.SetUint64(nonce)
+
+	err = c.conn.SendTransaction(context.TODO(), signed)
+	if err != nil {
+		return err
+	}
+	c.log.Info("Filled nonce gap with self-transfer", "nonce", nonce, "tx", signed.Hash())
 	return nil
 }
 
-func (c *Connection) UnlockOpts() {
-	c.optsLock.Unlock()
+// ReplaceTransaction signs and broadcasts a transaction with the same nonce, destination and data as tx.
+// The fees are the greater of the current gas price and the fees of tx increased by TxReplacementBump
+// percent, and never exceed the max gas price.
+func (c *Connection) ReplaceTransaction(tx *types.Transaction) (*types.Transaction, error) {
+	c.optsLock.Lock()
+	defer c.optsLock.Unlock()
+
+	head, err := c.conn.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := c.gasPricer.GasPrice(context.TODO(), head, tx.Nonce())
+	if err != nil {
+		return nil, err
+	}
+
+	var replacement types.TxData
+	if tx.Type() == types.DynamicFeeTxType {
+		tipCap := c.replacementFee(tx.GasTipCap(), price.GasTipCap)
+		feeCap := c.replacementFee(tx.GasFeeCap(), price.GasFeeCap)
+		if feeCap.Cmp(tx.GasFeeCap()) <= 0 {
+			return nil, ErrGasPriceCapReached
+		}
+		if tipCap.Cmp(feeCap) == 1 {
+			tipCap = new(big.Int).Set(feeCap)
+		}
+		replacement = &types.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasTipCap:  tipCap,
+			GasFeeCap:  feeCap,
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}
+	} else {
+		gasPrice := c.replacementFee(tx.GasPrice(), price.GasPrice)
+		if gasPrice.Cmp(tx.GasPrice()) <= 0 {
+			return nil, ErrGasPriceCapReached
+		}
+		replacement = &types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: gasPrice,
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		}
+	}
+
+	signed, err := c.opts.Signer(c.opts.From, types.NewTx(replacement))
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.conn.SendTransaction(context.TODO(), signed)
+	if err != nil {
+		return nil, err
+	}
+	return signed, nil
 }
 
-// LatestBlock returns the latest block from the current chain
+// replacementFee returns the greater of the bumped previous fee and the current fee, capped at the max gas price
+func (c *Connection) replacementFee(previous, current *big.Int) *big.Int {
+	fee := new(big.Int).Mul(previous, big.NewInt(100+TxReplacementBump))
+	fee.Div(fee, big.NewInt(100))
+	if current != nil && current.Cmp(fee) == 1 {
+		fee = current
+	}
+	return capGasPrice(fee, c.maxGasPrice)
+}
+
+// LatestBlock returns the latest block from the current chain. With a read quorum this is the
+// latest block reached by at least that many endpoints.
 func (c *Connection) LatestBlock() (*big.Int, error) {
-	header, err := c.conn.HeaderByNumber(context.Background(), nil)
+	head, err := c.conn.BlockNumber(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	return header.Number, nil
+	return new(big.Int).SetUint64(head), nil
 }
 
 // EnsureHasBytecode asserts if contract code exists at the specified address
@@ -300,22 +448,19 @@ func (c *Connection) EnsureHasBytecode(addr ethcommon.Address) error {
 }
 
 // WaitForBlock will poll for the block number until the current block is equal or greater.
-// If delay is provided it will wait until currBlock - delay = targetBlock
+// If delay is provided it will wait until currBlock - delay = targetBlock. With safe or finalized
+// finality the delay is ignored, and it waits until the tagged block is equal or greater.
 func (c *Connection) WaitForBlock(targetBlock *big.Int, delay *big.Int) error {
 	for {
 		select {
 		case <-c.stop:
 			return errors.New("connection terminated")
 		default:
-			currBlock, err := c.LatestBlock()
+			currBlock, err := c.ProcessableBlock(delay)
 			if err != nil {
 				return err
 			}
 
-			if delay != nil {
-				currBlock.Sub(currBlock, delay)
-			}
-
 			// Equal or greater than target
 			if currBlock.Cmp(targetBlock) >= 0 {
 				return nil
@@ -0,0 +1,610 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Interval between health checks of the RPC endpoints
+var EndpointHealthCheckInterval = time.Second * 15
+
+// Maximum duration of a request to an endpoint, after which the next endpoint is tried
+var EndpointRequestTimeout = time.Second * 30
+
+// Number of blocks an endpoint may fall behind the others before it is considered unhealthy
+const EndpointMaxLag = 5
+
+// Number of consecutive failed requests after which the health check closes the connection to an endpoint and
+// dials it again
+const EndpointReconnectFailures = 3
+
+// JSON-RPC error code used by providers when rate limiting requests
+const rateLimitErrorCode = -32005
+
+var ErrNoEndpoints = errors.New("no RPC endpoint available")
+
+// Client provides access to the chain through one or more RPC endpoints
+type Client interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	ChainID(ctx context.Context) (*big.Int, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (eth.Subscription, error)
+	Close()
+}
+
+var _ Client = &failoverClient{}
+
+// isEndpointFailure returns true if the error indicates a problem with the endpoint rather than the request.
+// A JSON-RPC error means the node processed the request, and another node would respond the same way.
+func isEndpointFailure(err error) bool {
+	if err == nil || err == eth.NotFound || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == rateLimitErrorCode
+	}
+	return true
+}
+
+// sendError is an error returned by an endpoint while sending a transaction
+type sendError struct {
+	err error
+}
+
+func (e *sendError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sendError) Unwrap() error {
+	return e.err
+}
+
+// splitEndpoints parses a comma separated list of endpoints
+func splitEndpoints(endpoints string) []string {
+	var urls []string
+	for _, url := range strings.Split(endpoints, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// endpoint is a single RPC provider and its health
+type endpoint struct {
+	url      string
+	client   *ethclient.Client
+	rpc      *rpc.Client
+	failures int    // Consecutive failed requests
+	head     uint64 // Latest block reported by the endpoint
+}
+
+// conn is an endpoint along with the clients it was connected with when it was ranked. Requests use these clients
+// rather than those of the endpoint, which are replaced when it is reconnected.
+type conn struct {
+	*endpoint
+	client *ethclient.Client
+	rpc    *rpc.Client
+}
+
+// failoverClient sends requests to the healthiest endpoint, and retries reads with the next healthiest
+// endpoint if it fails. Endpoints are ranked by their consecutive failures, and whether they have fallen
+// behind the others, ties are broken by their configured order. Transactions are only sent to the
+// healthiest endpoint, so they are never broadcast twice. With a quorum greater than one, block numbers
+// and contract calls must be confirmed by that many endpoints.
+type failoverClient struct {
+	endpoints []*endpoint
+	http      bool
+	quorum    int
+	active    *endpoint // Healthiest endpoint when last ranked, only used to log changes
+	lock      sync.RWMutex
+	log       log15.Logger
+}
+
+// dialEndpoints connects to each of the urls. Endpoints that can't be reached are retried by the health checks,
+// but at least one must be available.
+func dialEndpoints(urls []string, http bool, quorum int, log log15.Logger) (*failoverClient, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	if quorum < 1 || quorum > len(urls) {
+		return nil, fmt.Errorf("read quorum of %d is not possible with %d endpoints", quorum, len(urls))
+	}
+
+	f := &failoverClient{http: http, quorum: quorum, log: log}
+	var connected int
+	for _, url := range urls {
+		e := &endpoint{url: url}
+		err := f.dial(e)
+		if err != nil {
+			log.Warn("Failed to connect to RPC endpoint", "url", url, "err", err)
+			e.failures++
+		} else {
+			connected++
+		}
+		f.endpoints = append(f.endpoints, e)
+	}
+	if connected == 0 {
+		return nil, ErrNoEndpoints
+	}
+	return f, nil
+}
+
+func (f *failoverClient) dial(e *endpoint) error {
+	var rpcClient *rpc.Client
+	var err error
+	if f.http {
+		rpcClient, err = rpc.DialHTTP(e.url)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), EndpointRequestTimeout)
+		defer cancel()
+		rpcClient, err = rpc.DialContext(ctx, e.url)
+	}
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	e.client, e.rpc = ethclient.NewClient(rpcClient), rpcClient
+	f.lock.Unlock()
+	return nil
+}
+
+// ranked returns the connected endpoints, healthiest first
+func (f *failoverClient) ranked() []*conn {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var best uint64
+	var ranked []*conn
+	for _, e := range f.endpoints {
+		if e.client == nil {
+			continue
+		}
+		ranked = append(ranked, &conn{endpoint: e, client: e.client, rpc: e.rpc})
+		if e.head > best {
+			best = e.head
+		}
+	}
+
+	score := func(e *conn) int {
+		if e.head+EndpointMaxLag < best {
+			return e.failures + 1
+		}
+		return e.failures
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return score(ranked[i]) < score(ranked[j])
+	})
+
+	if len(ranked) > 0 && ranked[0].endpoint != f.active {
+		if f.active != nil {
+			f.log.Warn("Switching RPC endpoint", "from", f.active.url, "to", ranked[0].url, "failures", f.active.failures)
+		}
+		f.active = ranked[0].endpoint
+	}
+	return ranked
+}
+
+// record updates the health of the endpoint with the result of a request
+func (f *failoverClient) record(e *endpoint, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if isEndpointFailure(err) {
+		e.failures++
+		f.log.Debug("RPC endpoint request failed", "url", e.url, "failures", e.failures, "err", err)
+	} else {
+		e.failures = 0
+	}
+}
+
+// recordHead updates the latest block known to the endpoint
+func (f *failoverClient) recordHead(e *endpoint, head uint64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if head > e.head {
+		e.head = head
+	}
+}
+
+// do calls fn with each endpoint, healthiest first, until it succeeds or fails for a reason unrelated to the endpoint
+func (f *failoverClient) do(ctx context.Context, fn func(ctx context.Context, e *conn) error) error {
+	err := ErrNoEndpoints
+	for _, e := range f.ranked() {
+		reqCtx, cancel := context.WithTimeout(ctx, EndpointRequestTimeout)
+		err = fn(reqCtx, e)
+		cancel()
+
+		f.record(e.endpoint, err)
+		if !isEndpointFailure(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// send calls fn with the healthiest endpoint only
+func (f *failoverClient) send(fn func(e *conn) error) error {
+	ranked := f.ranked()
+	if len(ranked) == 0 {
+		return ErrNoEndpoints
+	}
+	err := fn(ranked[0])
+	f.record(ranked[0].endpoint, err)
+	return err
+}
+
+// healthCheck periodically queries the latest block of every endpoint, and reconnects to unreachable endpoints and
+// endpoints that keep failing
+func (f *failoverClient) healthCheck(stop <-chan int) {
+	ticker := time.NewTicker(EndpointHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, e := range f.endpoints {
+				f.checkEndpoint(e)
+			}
+		}
+	}
+}
+
+func (f *failoverClient) checkEndpoint(e *endpoint) {
+	f.lock.RLock()
+	client := e.client
+	f.lock.RUnlock()
+
+	if client == nil {
+		err := f.dial(e)
+		if err != nil {
+			f.log.Debug("Failed to reconnect to RPC endpoint", "url", e.url, "err", err)
+			return
+		}
+		f.log.Info("Reconnected to RPC endpoint", "url", e.url)
+		f.lock.RLock()
+		client = e.client
+		f.lock.RUnlock()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), EndpointRequestTimeout)
+	defer cancel()
+	head, err := client.BlockNumber(ctx)
+	f.record(e, err)
+	if err == nil {
+		f.recordHead(e, head)
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	// The connection may have been dropped, it is dialed again by the next health check
+	if e.failures >= EndpointReconnectFailures && e.client == client {
+		f.log.Warn("Disconnecting failing RPC endpoint", "url", e.url, "failures", e.failures, "err", err)
+		e.rpc.Close()
+		e.client, e.rpc = nil, nil
+	}
+}
+
+// checkChainId ensures every connected endpoint serves the same chain
+func (f *failoverClient) checkChainId(ctx context.Context) (*big.Int, error) {
+	var chainId *big.Int
+	for _, e := range f.ranked() {
+		id, err := e.client.ChainID(ctx)
+		f.record(e.endpoint, err)
+		if err != nil {
+			f.log.Warn("Failed to query chain ID of RPC endpoint", "url", e.url, "err", err)
+			continue
+		}
+		if chainId == nil {
+			chainId = id
+		} else if chainId.Cmp(id) != 0 {
+			return nil, fmt.Errorf("RPC endpoint %s serves chain %s, expected %s", e.url, id, chainId)
+		}
+	}
+	if chainId == nil {
+		return nil, ErrNoEndpoints
+	}
+	return chainId, nil
+}
+
+// quorumBlockNumber returns the highest block that at least quorum endpoints have reached
+func (f *failoverClient) quorumBlockNumber(ctx context.Context) (uint64, error) {
+	return f.quorumBlock(ctx, "latest block", func(ctx context.Context, e *conn) (uint64, error) {
+		head, err := e.client.BlockNumber(ctx)
+		if err == nil {
+			f.recordHead(e.endpoint, head)
+		}
+		return head, err
+	})
+}
+
+// quorumBlock returns the highest block number returned by fn for at least quorum endpoints
+func (f *failoverClient) quorumBlock(ctx context.Context, name string, fn func(ctx context.Context, e *conn) (uint64, error)) (uint64, error) {
+	var blocks []uint64
+	for _, e := range f.ranked() {
+		reqCtx, cancel := context.WithTimeout(ctx, EndpointRequestTimeout)
+		block, err := fn(reqCtx, e)
+		cancel()
+
+		f.record(e.endpoint, err)
+		if err != nil {
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) < f.quorum {
+		return 0, fmt.Errorf("%s reported by %d endpoints, read quorum is %d", name, len(blocks), f.quorum)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] > blocks[j] })
+	return blocks[f.quorum-1], nil
+}
+
+// quorumCall returns the result of the call once quorum endpoints returned the same result. Unless a block is
+// specified, the call is made at the latest block confirmed by the quorum so that the results are comparable.
+func (f *failoverClient) quorumCall(ctx context.Context, call eth.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if blockNumber == nil {
+		head, err := f.quorumBlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		blockNumber = new(big.Int).SetUint64(head)
+	}
+
+	votes := make(map[string]int)
+	var lastErr error
+	for _, e := range f.ranked() {
+		reqCtx, cancel := context.WithTimeout(ctx, EndpointRequestTimeout)
+		res, err := e.client.CallContract(reqCtx, call, blockNumber)
+		cancel()
+
+		f.record(e.endpoint, err)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		votes[string(res)]++
+		if votes[string(res)] == f.quorum {
+			return res, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("call results did not reach read quorum of %d: %w", f.quorum, lastErr)
+	}
+	return nil, fmt.Errorf("call results did not reach read quorum of %d", f.quorum)
+}
+
+func (f *failoverClient) BlockNumber(ctx context.Context) (uint64, error) {
+	if f.quorum > 1 {
+		return f.quorumBlockNumber(ctx)
+	}
+
+	var head uint64
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		head, err = e.client.BlockNumber(ctx)
+		if err == nil {
+			f.recordHead(e.endpoint, head)
+		}
+		return err
+	})
+	return head, err
+}
+
+func (f *failoverClient) CallContract(ctx context.Context, call eth.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if f.quorum > 1 {
+		return f.quorumCall(ctx, call, blockNumber)
+	}
+
+	var res []byte
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		res, err = e.client.CallContract(ctx, call, blockNumber)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverClient) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	var code []byte
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		code, err = e.client.CodeAt(ctx, contract, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+func (f *failoverClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	var balance *big.Int
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		balance, err = e.client.BalanceAt(ctx, account, blockNumber)
+		return err
+	})
+	return balance, err
+}
+
+func (f *failoverClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		header, err = e.client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+func (f *failoverClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	var code []byte
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		code, err = e.client.PendingCodeAt(ctx, account)
+		return err
+	})
+	return code, err
+}
+
+func (f *failoverClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var nonce uint64
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		nonce, err = e.client.PendingNonceAt(ctx, account)
+		return err
+	})
+	return nonce, err
+}
+
+func (f *failoverClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var price *big.Int
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		price, err = e.client.SuggestGasPrice(ctx)
+		return err
+	})
+	return price, err
+}
+
+func (f *failoverClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	var tip *big.Int
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		tip, err = e.client.SuggestGasTipCap(ctx)
+		return err
+	})
+	return tip, err
+}
+
+func (f *failoverClient) EstimateGas(ctx context.Context, call eth.CallMsg) (uint64, error) {
+	var gas uint64
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		gas, err = e.client.EstimateGas(ctx, call)
+		return err
+	})
+	return gas, err
+}
+
+// SendTransaction sends the transaction to the healthiest endpoint. An error returned after the transaction was
+// serialized and handed to the endpoint is a *sendError, as the node may have received the transaction.
+func (f *failoverClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return f.send(func(e *conn) error {
+		err := e.client.SendTransaction(ctx, tx)
+		if err != nil {
+			return &sendError{err: err}
+		}
+		return nil
+	})
+}
+
+func (f *failoverClient) FilterLogs(ctx context.Context, query eth.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		logs, err = e.client.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
+}
+
+func (f *failoverClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		receipt, err = e.client.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return receipt, err
+}
+
+func (f *failoverClient) ChainID(ctx context.Context) (*big.Int, error) {
+	var id *big.Int
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		id, err = e.client.ChainID(ctx)
+		return err
+	})
+	return id, err
+}
+
+// SubscribeFilterLogs subscribes using the healthiest endpoint, the subscription is not moved if the endpoint fails
+func (f *failoverClient) SubscribeFilterLogs(ctx context.Context, query eth.FilterQuery, ch chan<- types.Log) (eth.Subscription, error) {
+	var sub eth.Subscription
+	err := f.send(func(e *conn) (err error) {
+		sub, err = e.client.SubscribeFilterLogs(ctx, query, ch)
+		return err
+	})
+	return sub, err
+}
+
+// SubscribeNewHead subscribes using the healthiest endpoint, the subscription is not moved if the endpoint fails
+func (f *failoverClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (eth.Subscription, error) {
+	var sub eth.Subscription
+	err := f.send(func(e *conn) (err error) {
+		sub, err = e.client.SubscribeNewHead(ctx, ch)
+		return err
+	})
+	return sub, err
+}
+
+// TaggedBlockNumber returns the number of the block with the tag, such as safe or finalized. With a read quorum
+// this is the highest tagged block reached by at least that many endpoints.
+func (f *failoverClient) TaggedBlockNumber(ctx context.Context, tag string) (uint64, error) {
+	taggedBlock := func(ctx context.Context, e *conn) (uint64, error) {
+		var header *types.Header
+		err := e.rpc.CallContext(ctx, &header, "eth_getBlockByNumber", tag, false)
+		if err != nil {
+			return 0, err
+		}
+		if header == nil {
+			return 0, fmt.Errorf("no block tagged %s", tag)
+		}
+		return header.Number.Uint64(), nil
+	}
+
+	if f.quorum > 1 {
+		return f.quorumBlock(ctx, "block tagged "+tag, taggedBlock)
+	}
+
+	var block uint64
+	err := f.do(ctx, func(ctx context.Context, e *conn) (err error) {
+		block, err = taggedBlock(ctx, e)
+		return err
+	})
+	return block, err
+}
+
+// CallContext performs a raw JSON-RPC call, for methods not provided by ethclient
+func (f *failoverClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return f.do(ctx, func(ctx context.Context, e *conn) error {
+		return e.rpc.CallContext(ctx, result, method, args...)
+	})
+}
+
+// Close closes the connections to all endpoints
+func (f *failoverClient) Close() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, e := range f.endpoints {
+		if e.client != nil {
+			e.client.Close()
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ChainSafe/log15"
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// rateLimitedError mimics the error returned by providers when rate limiting requests
+type rateLimitedError struct{}
+
+func (rateLimitedError) Error() string  { return "limit exceeded" }
+func (rateLimitedError) ErrorCode() int { return rateLimitErrorCode }
+
+// fakeEthService stands in for a node's eth namespace. The finalized block advances each time it is queried.
+type fakeEthService struct {
+	latest     int64
+	safe       int64
+	finalized  int64
+	noTags     bool
+	chainId    int64
+	callResult []byte
+	callErr    error
+	callBlocks []string
+	sent       int
+}
+
+func (s *fakeEthService) GetBlockByNumber(_ context.Context, tag string, _ bool) (*types.Header, error) {
+	if s.noTags && tag != "latest" {
+		return nil, nil
+	}
+
+	var number int64
+	switch tag {
+	case "latest":
+		number = s.latest
+	case "safe":
+		number = s.safe
+	case "finalized":
+		number = s.finalized
+		s.finalized++
+	default:
+		return nil, nil
+	}
+	return &types.Header{Number: big.NewInt(number), Difficulty: big.NewInt(0)}, nil
+}
+
+func (s *fakeEthService) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(s.latest)
+}
+
+func (s *fakeEthService) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(s.chainId))
+}
+
+func (s *fakeEthService) Call(_ map[string]interface{}, block string) (hexutil.Bytes, error) {
+	s.callBlocks = append(s.callBlocks, block)
+	return s.callResult, s.callErr
+}
+
+func (s *fakeEthService) SendRawTransaction(_ hexutil.Bytes) common.Hash {
+	s.sent++
+	return common.Hash{}
+}
+
+func dialFakeEthService(t *testing.T, service *fakeEthService) *rpc.Client {
+	server := rpc.NewServer()
+	err := server.RegisterName("eth", service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	return client
+}
+
+// newTestFailoverClient returns a client for the services, a nil service is an unreachable endpoint
+func newTestFailoverClient(t *testing.T, quorum int, services ...*fakeEthService) *failoverClient {
+	f := &failoverClient{quorum: quorum, log: log15.Root()}
+	for i, service := range services {
+		var client *rpc.Client
+		if service == nil {
+			client = dialFakeEthService(t, &fakeEthService{})
+			client.Close()
+		} else {
+			client = dialFakeEthService(t, service)
+		}
+		f.endpoints = append(f.endpoints, &endpoint{url: string(rune('a' + i)), client: ethclient.NewClient(client), rpc: client})
+	}
+	return f
+}
+
+func assertActiveEndpoint(t *testing.T, f *failoverClient, expected string) {
+	if active := f.ranked()[0].url; active != expected {
+		t.Fatalf("expected endpoint %s to be active, got %s", expected, active)
+	}
+}
+
+func TestFailover_Unreachable(t *testing.T) {
+	f := newTestFailoverClient(t, 1, nil, &fakeEthService{latest: 100})
+
+	head, err := f.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != 100 {
+		t.Fatalf("expected block 100, got %d", head)
+	}
+	assertActiveEndpoint(t, f, "b")
+}
+
+func TestFailover_RpcError(t *testing.T) {
+	a := &fakeEthService{callErr: errors.New("execution reverted")}
+	b := &fakeEthService{}
+	f := newTestFailoverClient(t, 1, a, b)
+
+	// The node processed the request, so the error is returned rather than trying the next endpoint
+	_, err := f.CallContract(context.Background(), eth.CallMsg{}, nil)
+	if err == nil {
+		t.Fatal("expected call to fail")
+	}
+	if len(b.callBlocks) != 0 {
+		t.Fatal("expected call not to be sent to the next endpoint")
+	}
+	assertActiveEndpoint(t, f, "a")
+}
+
+func TestFailover_RateLimited(t *testing.T) {
+	a := &fakeEthService{callErr: rateLimitedError{}}
+	b := &fakeEthService{callResult: []byte{1}}
+	f := newTestFailoverClient(t, 1, a, b)
+
+	res, err := f.CallContract(context.Background(), eth.CallMsg{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0] != 1 {
+		t.Fatalf("unexpected call result %x", res)
+	}
+	assertActiveEndpoint(t, f, "b")
+}
+
+func TestFailover_Lagging(t *testing.T) {
+	f := newTestFailoverClient(t, 1, &fakeEthService{latest: 100}, &fakeEthService{latest: 100 + EndpointMaxLag + 1})
+
+	for _, e := range f.endpoints {
+		f.checkEndpoint(e)
+	}
+	assertActiveEndpoint(t, f, "b")
+}
+
+func TestFailover_Reconnect(t *testing.T) {
+	server := rpc.NewServer()
+	err := server.RegisterName("eth", &fakeEthService{latest: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := httptest.NewServer(server)
+	t.Cleanup(node.Close)
+
+	// The endpoint is reachable, but its connection was dropped
+	dropped := dialFakeEthService(t, &fakeEthService{})
+	dropped.Close()
+	e := &endpoint{url: node.URL, client: ethclient.NewClient(dropped), rpc: dropped}
+	f := &failoverClient{endpoints: []*endpoint{e}, http: true, quorum: 1, log: log15.Root()}
+
+	for i := 0; i < EndpointReconnectFailures; i++ {
+		f.checkEndpoint(e)
+	}
+	if e.client != nil {
+		t.Fatal("expected failing endpoint to be disconnected")
+	}
+	if _, err = f.BlockNumber(context.Background()); err != ErrNoEndpoints {
+		t.Fatalf("expected no endpoints while disconnected, got %v", err)
+	}
+
+	f.checkEndpoint(e)
+	if e.client == nil {
+		t.Fatal("expected endpoint to be reconnected")
+	}
+	head, err := f.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != 100 || e.failures != 0 {
+		t.Fatalf("expected block 100 and no failures, got block %d and %d failures", head, e.failures)
+	}
+}
+
+func TestFailover_SendHealthiestOnly(t *testing.T) {
+	b := &fakeEthService{}
+	f := newTestFailoverClient(t, 1, nil, b)
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+
+	// The transaction is not broadcast through another endpoint
+	err := f.SendTransaction(context.Background(), tx)
+	if err == nil {
+		t.Fatal("expected send through unreachable endpoint to fail")
+	}
+	if b.sent != 0 {
+		t.Fatal("expected transaction not to be sent to the next endpoint")
+	}
+
+	// The next transaction is sent to the healthiest endpoint
+	err = f.SendTransaction(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.sent != 1 {
+		t.Fatalf("expected 1 transaction to be sent, got %d", b.sent)
+	}
+}
+
+func TestQuorum_BlockNumber(t *testing.T) {
+	f := newTestFailoverClient(t, 2, &fakeEthService{latest: 110}, &fakeEthService{latest: 100}, &fakeEthService{latest: 105})
+
+	head, err := f.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != 105 {
+		t.Fatalf("expected block 105, got %d", head)
+	}
+
+	f = newTestFailoverClient(t, 2, &fakeEthService{latest: 110}, nil)
+	_, err = f.BlockNumber(context.Background())
+	if err == nil {
+		t.Fatal("expected error without quorum")
+	}
+}
+
+func TestQuorum_Call(t *testing.T) {
+	a := &fakeEthService{latest: 110, callResult: []byte{1}}
+	b := &fakeEthService{latest: 100, callResult: []byte{2}}
+	c := &fakeEthService{latest: 105, callResult: []byte{1}}
+	f := newTestFailoverClient(t, 2, a, b, c)
+
+	res, err := f.CallContract(context.Background(), eth.CallMsg{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0] != 1 {
+		t.Fatalf("unexpected call result %x", res)
+	}
+	// The call is made at the latest block confirmed by the quorum
+	if len(a.callBlocks) != 1 || a.callBlocks[0] != hexutil.EncodeUint64(105) {
+		t.Fatalf("expected call at block 105, got %v", a.callBlocks)
+	}
+
+	c.callResult = []byte{3}
+	_, err = f.CallContract(context.Background(), eth.CallMsg{}, nil)
+	if err == nil {
+		t.Fatal("expected error without quorum")
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"math/big"
+)
+
+// Finality determines which blocks are considered final, and may be processed
+type Finality string
+
+const (
+	ConfirmationsFinality Finality = "confirmations" // Blocks with a fixed number of confirmations, default
+	SafeFinality          Finality = "safe"          // Blocks up to the block tagged `safe` by the node
+	FinalizedFinality     Finality = "finalized"     // Blocks up to the block tagged `finalized` by the node
+)
+
+// ProcessableBlock returns the latest block that is considered final. With confirmations finality this is
+// the latest block minus confirmations, otherwise it is the block with the matching tag and confirmations
+// are ignored. With a read quorum the tagged block must have been reached by that many endpoints.
+func (c *Connection) ProcessableBlock(confirmations *big.Int) (*big.Int, error) {
+	if c.finality == ConfirmationsFinality {
+		latest, err := c.LatestBlock()
+		if err != nil {
+			return nil, err
+		}
+		if confirmations != nil {
+			latest.Sub(latest, confirmations)
+		}
+		return latest, nil
+	}
+
+	block, err := c.conn.TaggedBlockNumber(context.Background(), string(c.finality))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(block), nil
+}
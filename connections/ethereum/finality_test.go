@@ -0,0 +1,89 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/log15"
+)
+
+func newFakeRpcConnection(t *testing.T, service *fakeEthService, finality Finality) *Connection {
+	return &Connection{
+		conn:     newTestFailoverClient(t, 1, service),
+		finality: finality,
+		log:      log15.Root(),
+		stop:     make(chan int),
+	}
+}
+
+func assertProcessableBlock(t *testing.T, conn *Connection, confirmations *big.Int, expected int64) {
+	block, err := conn.ProcessableBlock(confirmations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Int64() != expected {
+		t.Fatalf("expected processable block %d, got %s", expected, block)
+	}
+}
+
+func TestFinality_Confirmations(t *testing.T) {
+	conn := newFakeRpcConnection(t, &fakeEthService{latest: 100, safe: 95, finalized: 80}, ConfirmationsFinality)
+
+	assertProcessableBlock(t, conn, big.NewInt(10), 90)
+	assertProcessableBlock(t, conn, nil, 100)
+}
+
+func TestFinality_Safe(t *testing.T) {
+	conn := newFakeRpcConnection(t, &fakeEthService{latest: 100, safe: 95, finalized: 80}, SafeFinality)
+
+	assertProcessableBlock(t, conn, big.NewInt(10), 95)
+}
+
+func TestFinality_Finalized(t *testing.T) {
+	conn := newFakeRpcConnection(t, &fakeEthService{latest: 100, safe: 95, finalized: 80}, FinalizedFinality)
+
+	assertProcessableBlock(t, conn, big.NewInt(10), 80)
+}
+
+func TestFinality_Quorum(t *testing.T) {
+	conn := &Connection{
+		conn:     newTestFailoverClient(t, 2, &fakeEthService{latest: 100, safe: 95}, &fakeEthService{latest: 100, safe: 90}, nil),
+		finality: SafeFinality,
+		log:      log15.Root(),
+		stop:     make(chan int),
+	}
+
+	// The highest safe block reached by two endpoints
+	assertProcessableBlock(t, conn, nil, 90)
+}
+
+func TestFinality_UnsupportedTag(t *testing.T) {
+	conn := newFakeRpcConnection(t, &fakeEthService{latest: 100, noTags: true}, FinalizedFinality)
+
+	_, err := conn.ProcessableBlock(nil)
+	if err == nil {
+		t.Fatal("expected error for unsupported block tag")
+	}
+}
+
+func TestFinality_WaitForBlock(t *testing.T) {
+	interval := BlockRetryInterval
+	BlockRetryInterval = time.Millisecond
+	defer func() { BlockRetryInterval = interval }()
+
+	service := &fakeEthService{latest: 100, finalized: 80}
+	conn := newFakeRpcConnection(t, service, FinalizedFinality)
+
+	// The head is well past the target, but only the finalized block counts
+	err := conn.WaitForBlock(big.NewInt(85), big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.finalized != 86 {
+		t.Fatalf("expected to wait until block 85 was finalized, finalized block queried up to %d", service.finalized-1)
+	}
+}
@@ -0,0 +1,411 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ChainSafe/ChainBridge/connections/ethereum/egs"
+	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type GasStrategy string
+
+const (
+	NodeGasStrategy       GasStrategy = "node"       // Node (or EGS) suggested price, default
+	StaticGasStrategy     GasStrategy = "static"     // Always use the max gas price
+	PercentileGasStrategy GasStrategy = "percentile" // Percentile of the tips paid in recent blocks
+	EscalatingGasStrategy GasStrategy = "escalating" // Node suggested price, bumped each time the same nonce is priced again
+)
+
+// GasPricerConfig selects and configures the strategy used to price transactions
+type GasPricerConfig struct {
+	Strategy          GasStrategy
+	Percentile        float64 // Reward percentile used by the percentile strategy
+	PercentileBlocks  uint64  // Number of blocks sampled by the percentile strategy
+	EscalationPercent uint64  // Increase applied for each repeated attempt by the escalating strategy
+}
+
+// GasPrice holds the fee parameters for a transaction. Legacy transactions only set GasPrice,
+// EIP-1559 transactions set GasTipCap and GasFeeCap.
+type GasPrice struct {
+	GasPrice  *big.Int
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// IsLondon returns true if the price is for an EIP-1559 transaction
+func (p *GasPrice) IsLondon() bool {
+	return p.GasFeeCap != nil
+}
+
+// GasPricer determines the fee parameters for the next transaction. The head is the latest known header,
+// a non-nil BaseFee indicates the chain supports EIP-1559. The nonce is that of the transaction being priced.
+type GasPricer interface {
+	GasPrice(ctx context.Context, head *types.Header, nonce uint64) (*GasPrice, error)
+}
+
+// FeeHistory is the result of eth_feeHistory
+type FeeHistory struct {
+	OldestBlock  *big.Int
+	Reward       [][]*big.Int
+	BaseFee      []*big.Int
+	GasUsedRatio []float64
+}
+
+// GasPriceSource provides the chain data required by the gas pricers
+type GasPriceSource interface {
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*FeeHistory, error)
+}
+
+var _ GasPriceSource = &clientGasPriceSource{}
+
+// clientGasPriceSource implements GasPriceSource using the connection's clients
+type clientGasPriceSource struct {
+	*failoverClient
+}
+
+// FeeHistory queries eth_feeHistory, which is not yet provided by ethclient
+func (s *clientGasPriceSource) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*FeeHistory, error) {
+	var res struct {
+		OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+		Reward       [][]*hexutil.Big `json:"reward"`
+		BaseFee      []*hexutil.Big   `json:"baseFeePerGas"`
+		GasUsedRatio []float64        `json:"gasUsedRatio"`
+	}
+	block := "latest"
+	if lastBlock != nil {
+		block = hexutil.EncodeBig(lastBlock)
+	}
+	err := s.CallContext(ctx, &res, "eth_feeHistory", hexutil.Uint64(blockCount), block, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+
+	hist := &FeeHistory{
+		OldestBlock:  (*big.Int)(res.OldestBlock),
+		Reward:       make([][]*big.Int, len(res.Reward)),
+		BaseFee:      make([]*big.Int, len(res.BaseFee)),
+		GasUsedRatio: res.GasUsedRatio,
+	}
+	for i, rewards := range res.Reward {
+		hist.Reward[i] = make([]*big.Int, len(rewards))
+		for j, r := range rewards {
+			hist.Reward[i][j] = (*big.Int)(r)
+		}
+	}
+	for i, fee := range res.BaseFee {
+		hist.BaseFee[i] = (*big.Int)(fee)
+	}
+	return hist, nil
+}
+
+// GasPriceMetrics reports the fees selected by the gas pricer
+type GasPriceMetrics struct {
+	GasPrice  prometheus.Gauge
+	GasTipCap prometheus.Gauge
+}
+
+// NewGasPriceMetrics creates and registers the gas price gauges for the chain
+func NewGasPriceMetrics(chain string) *GasPriceMetrics {
+	m := &GasPriceMetrics{
+		GasPrice: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_gas_price", chain),
+			Help: "Gas price (or max fee per gas for EIP-1559 transactions) of the latest transaction, in wei",
+		}),
+		GasTipCap: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_gas_tip_cap", chain),
+			Help: "Max priority fee per gas of the latest EIP-1559 transaction, in wei",
+		}),
+	}
+
+	prometheus.MustRegister(m.GasPrice)
+	prometheus.MustRegister(m.GasTipCap)
+
+	return m
+}
+
+func (m *GasPriceMetrics) report(price *GasPrice) {
+	if price.IsLondon() {
+		m.GasPrice.Set(toFloat(price.GasFeeCap))
+		m.GasTipCap.Set(toFloat(price.GasTipCap))
+	} else {
+		m.GasPrice.Set(toFloat(price.GasPrice))
+		m.GasTipCap.Set(0)
+	}
+}
+
+func toFloat(i *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(i).Float64()
+	return f
+}
+
+// NewGasPricer constructs the gas pricer selected by cfg. Prices are never higher than maxGasPrice.
+func NewGasPricer(cfg GasPricerConfig, source GasPriceSource, maxGasPrice *big.Int, gasMultiplier *big.Float, egsApiKey, egsSpeed string, log log15.Logger) (GasPricer, error) {
+	node := &nodeGasPricer{
+		source:        source,
+		maxGasPrice:   maxGasPrice,
+		gasMultiplier: gasMultiplier,
+		egsApiKey:     egsApiKey,
+		egsSpeed:      egsSpeed,
+		log:           log,
+	}
+
+	switch cfg.Strategy {
+	case NodeGasStrategy, "":
+		return node, nil
+	case StaticGasStrategy:
+		return &staticGasPricer{gasPrice: maxGasPrice}, nil
+	case PercentileGasStrategy:
+		return &percentileGasPricer{
+			source:        source,
+			maxGasPrice:   maxGasPrice,
+			gasMultiplier: gasMultiplier,
+			percentile:    cfg.Percentile,
+			blocks:        cfg.PercentileBlocks,
+		}, nil
+	case EscalatingGasStrategy:
+		return &escalatingGasPricer{
+			base:        node,
+			maxGasPrice: maxGasPrice,
+			percent:     cfg.EscalationPercent,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown gas strategy %s", cfg.Strategy)
+	}
+}
+
+// capLondonFees ensures the fee cap does not exceed maxGasPrice. If the base fee alone exceeds
+// maxGasPrice, the minimum possible tip is used.
+func capLondonFees(tip, baseFee, maxGasPrice *big.Int) (*big.Int, *big.Int) {
+	if maxGasPrice.Cmp(baseFee) < 0 {
+		tip = big.NewInt(1)
+		return tip, new(big.Int).Add(baseFee, tip)
+	}
+
+	feeCap := new(big.Int).Add(tip, new(big.Int).Mul(baseFee, big.NewInt(2)))
+
+	// Check we aren't exceeding our limit
+	if feeCap.Cmp(maxGasPrice) == 1 {
+		tip = new(big.Int).Sub(maxGasPrice, baseFee)
+		feeCap = new(big.Int).Set(maxGasPrice)
+	}
+	return tip, feeCap
+}
+
+// capGasPrice returns the lower of price and maxGasPrice
+func capGasPrice(price, maxGasPrice *big.Int) *big.Int {
+	if price.Cmp(maxGasPrice) == 1 {
+		return new(big.Int).Set(maxGasPrice)
+	}
+	return price
+}
+
+// nodeGasPricer uses the gas price suggested by EGS (if an api key is provided) or the node, multiplied by
+// the gas multiplier. On EIP-1559 chains the node's suggested tip is used, with a fee cap of tip + 2*baseFee.
+type nodeGasPricer struct {
+	source        GasPriceSource
+	maxGasPrice   *big.Int
+	gasMultiplier *big.Float
+	egsApiKey     string
+	egsSpeed      string
+	log           log15.Logger
+}
+
+func (p *nodeGasPricer) GasPrice(ctx context.Context, head *types.Header, _ uint64) (*GasPrice, error) {
+	if head.BaseFee != nil {
+		tip, feeCap, err := p.londonGasPrice(ctx, head.BaseFee)
+		if err != nil {
+			return nil, err
+		}
+		return &GasPrice{GasTipCap: tip, GasFeeCap: feeCap}, nil
+	}
+
+	price, err := p.legacyGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &GasPrice{GasPrice: price}, nil
+}
+
+func (p *nodeGasPricer) legacyGasPrice(ctx context.Context) (*big.Int, error) {
+	var suggestedGasPrice *big.Int
+
+	// First attempt to use EGS for the gas price if the api key is supplied
+	if p.egsApiKey != "" {
+		price, err := egs.FetchGasPrice(p.egsApiKey, p.egsSpeed)
+		if err != nil {
+			p.log.Error("Couldn't fetch gasPrice from GSN", "err", err)
+		} else {
+			suggestedGasPrice = price
+		}
+	}
+
+	// Fallback to the node rpc method for the gas price if GSN did not provide a price
+	if suggestedGasPrice == nil {
+		p.log.Debug("Fetching gasPrice from node")
+		nodePriceEstimate, err := p.source.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		} else {
+			suggestedGasPrice = nodePriceEstimate
+		}
+	}
+
+	gasPrice := multiplyGasPrice(suggestedGasPrice, p.gasMultiplier)
+
+	// Check we aren't exceeding our limit
+	return capGasPrice(gasPrice, p.maxGasPrice), nil
+}
+
+func (p *nodeGasPricer) londonGasPrice(ctx context.Context, baseFee *big.Int) (*big.Int, *big.Int, error) {
+	if p.maxGasPrice.Cmp(baseFee) < 0 {
+		tip, feeCap := capLondonFees(nil, baseFee, p.maxGasPrice)
+		return tip, feeCap, nil
+	}
+
+	maxPriorityFeePerGas, err := p.source.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tip, feeCap := capLondonFees(maxPriorityFeePerGas, baseFee, p.maxGasPrice)
+	if feeCap.Cmp(tip) < 0 {
+		return nil, nil, fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", feeCap, tip)
+	}
+	return tip, feeCap, nil
+}
+
+// staticGasPricer always uses the same price. On EIP-1559 chains it is used as both the tip and fee cap,
+// so the transaction pays at most that price per gas.
+type staticGasPricer struct {
+	gasPrice *big.Int
+}
+
+func (p *staticGasPricer) GasPrice(_ context.Context, head *types.Header, _ uint64) (*GasPrice, error) {
+	if head.BaseFee != nil {
+		return &GasPrice{GasTipCap: new(big.Int).Set(p.gasPrice), GasFeeCap: new(big.Int).Set(p.gasPrice)}, nil
+	}
+	return &GasPrice{GasPrice: new(big.Int).Set(p.gasPrice)}, nil
+}
+
+// percentileGasPricer uses the median of the rewards paid at the configured percentile over recent
+// blocks, as reported by eth_feeHistory. Blocks with no transactions are ignored. On legacy chains the
+// reward is the full gas price, which is multiplied by the gas multiplier.
+type percentileGasPricer struct {
+	source        GasPriceSource
+	maxGasPrice   *big.Int
+	gasMultiplier *big.Float
+	percentile    float64
+	blocks        uint64
+}
+
+func (p *percentileGasPricer) GasPrice(ctx context.Context, head *types.Header, _ uint64) (*GasPrice, error) {
+	hist, err := p.source.FeeHistory(ctx, p.blocks, head.Number, []float64{p.percentile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch fee history: %w", err)
+	}
+
+	var rewards []*big.Int
+	for i, blockRewards := range hist.Reward {
+		if i < len(hist.GasUsedRatio) && hist.GasUsedRatio[i] == 0 {
+			continue
+		}
+		if len(blockRewards) > 0 && blockRewards[0] != nil {
+			rewards = append(rewards, blockRewards[0])
+		}
+	}
+
+	// Fallback to the node's suggestion if there were no recent transactions
+	var reward *big.Int
+	if len(rewards) > 0 {
+		reward = median(rewards)
+	} else if head.BaseFee != nil {
+		reward, err = p.source.SuggestGasTipCap(ctx)
+	} else {
+		reward, err = p.source.SuggestGasPrice(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if head.BaseFee != nil {
+		tip, feeCap := capLondonFees(reward, head.BaseFee, p.maxGasPrice)
+		return &GasPrice{GasTipCap: tip, GasFeeCap: feeCap}, nil
+	}
+
+	price := multiplyGasPrice(reward, p.gasMultiplier)
+	return &GasPrice{GasPrice: capGasPrice(price, p.maxGasPrice)}, nil
+}
+
+// median returns the median of the values, the values are sorted in place
+func median(values []*big.Int) *big.Int {
+	sort.Slice(values, func(i, j int) bool { return values[i].Cmp(values[j]) < 0 })
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return new(big.Int).Set(values[mid])
+	}
+	sum := new(big.Int).Add(values[mid-1], values[mid])
+	return sum.Div(sum, big.NewInt(2))
+}
+
+// escalatingGasPricer uses the price of the base pricer, increased by percent for every time the
+// same nonce has been priced before. A nonce being priced again means the previous attempt did not
+// land, so each retry pays more until maxGasPrice is reached.
+type escalatingGasPricer struct {
+	base        GasPricer
+	maxGasPrice *big.Int
+	percent     uint64
+	lastNonce   *uint64
+	attempts    uint64
+	lock        sync.Mutex
+}
+
+func (p *escalatingGasPricer) GasPrice(ctx context.Context, head *types.Header, nonce uint64) (*GasPrice, error) {
+	price, err := p.base.GasPrice(ctx, head, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	if p.lastNonce != nil && *p.lastNonce == nonce {
+		p.attempts++
+	} else {
+		p.attempts = 0
+		p.lastNonce = &nonce
+	}
+	attempts := p.attempts
+	p.lock.Unlock()
+
+	if price.IsLondon() {
+		price.GasTipCap = capGasPrice(p.escalate(price.GasTipCap, attempts), p.maxGasPrice)
+		price.GasFeeCap = capGasPrice(p.escalate(price.GasFeeCap, attempts), p.maxGasPrice)
+		// The fee cap can't be capped below the base fee, ensure the tip never exceeds it
+		if price.GasTipCap.Cmp(price.GasFeeCap) == 1 {
+			price.GasTipCap = new(big.Int).Set(price.GasFeeCap)
+		}
+	} else {
+		price.GasPrice = capGasPrice(p.escalate(price.GasPrice, attempts), p.maxGasPrice)
+	}
+	return price, nil
+}
+
+// escalate increases the value by percent, compounded for each attempt
+func (p *escalatingGasPricer) escalate(value *big.Int, attempts uint64) *big.Int {
+	res := new(big.Int).Set(value)
+	for i := uint64(0); i < attempts && res.Cmp(p.maxGasPrice) < 0; i++ {
+		res.Mul(res, big.NewInt(int64(100+p.percent)))
+		res.Div(res, big.NewInt(100))
+	}
+	return res
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeGasPriceSource returns fixed suggestions and fee history
+type fakeGasPriceSource struct {
+	gasPrice *big.Int
+	tipCap   *big.Int
+	history  *FeeHistory
+}
+
+func (s *fakeGasPriceSource) SuggestGasPrice(_ context.Context) (*big.Int, error) {
+	return new(big.Int).Set(s.gasPrice), nil
+}
+
+func (s *fakeGasPriceSource) SuggestGasTipCap(_ context.Context) (*big.Int, error) {
+	return new(big.Int).Set(s.tipCap), nil
+}
+
+func (s *fakeGasPriceSource) FeeHistory(_ context.Context, _ uint64, _ *big.Int, _ []float64) (*FeeHistory, error) {
+	return s.history, nil
+}
+
+func legacyHead() *types.Header {
+	return &types.Header{Number: big.NewInt(100)}
+}
+
+func londonHead(baseFee int64) *types.Header {
+	return &types.Header{Number: big.NewInt(100), BaseFee: big.NewInt(baseFee)}
+}
+
+func newTestGasPricer(t *testing.T, cfg GasPricerConfig, source GasPriceSource, maxGasPrice int64) GasPricer {
+	p, err := NewGasPricer(cfg, source, big.NewInt(maxGasPrice), big.NewFloat(1), "", "", log15.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func assertGasPrice(t *testing.T, price *GasPrice, gasPrice, tipCap, feeCap int64) {
+	if price.IsLondon() {
+		if price.GasTipCap.Int64() != tipCap || price.GasFeeCap.Int64() != feeCap {
+			t.Fatalf("unexpected fees. Expected tip %d cap %d, got tip %d cap %d", tipCap, feeCap, price.GasTipCap, price.GasFeeCap)
+		}
+		return
+	}
+	if price.GasPrice.Int64() != gasPrice {
+		t.Fatalf("unexpected gas price. Expected %d, got %d", gasPrice, price.GasPrice)
+	}
+}
+
+func TestGasPricer_Node(t *testing.T) {
+	source := &fakeGasPriceSource{gasPrice: big.NewInt(50), tipCap: big.NewInt(2)}
+	p := newTestGasPricer(t, GasPricerConfig{Strategy: NodeGasStrategy}, source, 1000)
+
+	price, err := p.GasPrice(context.Background(), legacyHead(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 50, 0, 0)
+
+	price, err = p.GasPrice(context.Background(), londonHead(10), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 0, 2, 22)
+
+	// Fee cap exceeds max gas price
+	price, err = p.GasPrice(context.Background(), londonHead(600), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 0, 400, 1000)
+}
+
+func TestGasPricer_Static(t *testing.T) {
+	p := newTestGasPricer(t, GasPricerConfig{Strategy: StaticGasStrategy}, &fakeGasPriceSource{}, 30)
+
+	price, err := p.GasPrice(context.Background(), legacyHead(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 30, 0, 0)
+
+	price, err = p.GasPrice(context.Background(), londonHead(10), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 0, 30, 30)
+}
+
+func TestGasPricer_Percentile(t *testing.T) {
+	source := &fakeGasPriceSource{
+		gasPrice: big.NewInt(50),
+		tipCap:   big.NewInt(7),
+		history: &FeeHistory{
+			OldestBlock:  big.NewInt(96),
+			Reward:       [][]*big.Int{{big.NewInt(3)}, {big.NewInt(0)}, {big.NewInt(9)}, {big.NewInt(5)}, {big.NewInt(1)}},
+			GasUsedRatio: []float64{0.5, 0, 0.9, 0.3, 0.1},
+		},
+	}
+	p := newTestGasPricer(t, GasPricerConfig{Strategy: PercentileGasStrategy, Percentile: 50, PercentileBlocks: 5}, source, 1000)
+
+	// The empty block is ignored, median of 1, 3, 5 and 9
+	price, err := p.GasPrice(context.Background(), londonHead(10), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 0, 4, 24)
+
+	price, err = p.GasPrice(context.Background(), legacyHead(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 4, 0, 0)
+
+	// Falls back to the node without recent transactions
+	source.history = &FeeHistory{Reward: [][]*big.Int{{big.NewInt(0)}}, GasUsedRatio: []float64{0}}
+	price, err = p.GasPrice(context.Background(), londonHead(10), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 0, 7, 27)
+}
+
+func TestGasPricer_Escalating(t *testing.T) {
+	source := &fakeGasPriceSource{gasPrice: big.NewInt(100), tipCap: big.NewInt(100)}
+	p := newTestGasPricer(t, GasPricerConfig{Strategy: EscalatingGasStrategy, EscalationPercent: 10}, source, 130)
+
+	expected := []int64{100, 110, 121, 130, 130}
+	for i, e := range expected {
+		price, err := p.GasPrice(context.Background(), legacyHead(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price.GasPrice.Int64() != e {
+			t.Fatalf("attempt %d: expected gas price %d, got %d", i, e, price.GasPrice)
+		}
+	}
+
+	// A new nonce resets the escalation
+	price, err := p.GasPrice(context.Background(), legacyHead(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 100, 0, 0)
+
+	// EIP-1559 fees are escalated and capped
+	price, err = p.GasPrice(context.Background(), londonHead(10), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGasPrice(t, price, 0, 110, 130)
+}
+
+func TestGasPricer_UnknownStrategy(t *testing.T) {
+	_, err := NewGasPricer(GasPricerConfig{Strategy: "cheapest"}, &fakeGasPriceSource{}, big.NewInt(1), big.NewFloat(1), "", "", log15.Root())
+	if err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+}
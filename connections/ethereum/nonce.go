@@ -0,0 +1,126 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Errors returned by nodes when a nonce has already been used, indicating our local nonce is behind the chain
+var nonceDivergenceErrors = []string{
+	"nonce too low",
+	"already known",
+	"known transaction",
+	"replacement transaction underpriced",
+}
+
+// isNonceDivergence returns true if the error indicates the nonce of the transaction was already used
+func isNonceDivergence(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, e := range nonceDivergenceErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// NonceSource provides the account's nonce including pending transactions
+type NonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// nonceManager hands out nonces locally, so transactions can be submitted without querying the node
+// each time. It only resyncs with the node when a send reports the nonce was already used.
+type nonceManager struct {
+	source  NonceSource
+	account common.Address
+	next    uint64
+	synced  bool
+	lock    sync.Mutex
+}
+
+func newNonceManager(source NonceSource, account common.Address) *nonceManager {
+	return &nonceManager{source: source, account: account}
+}
+
+// reserve returns the next nonce to use. Every reserved nonce must be passed to unused or result.
+func (n *nonceManager) reserve(ctx context.Context) (uint64, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if !n.synced {
+		next, err := n.source.PendingNonceAt(ctx, n.account)
+		if err != nil {
+			return 0, err
+		}
+		n.next = next
+		n.synced = true
+	}
+
+	nonce := n.next
+	n.next++
+	return nonce, nil
+}
+
+// unused returns a nonce that was not submitted to the node. If later nonces have already been
+// reserved the nonce can't be returned, and true is returned to indicate the gap must be filled.
+func (n *nonceManager) unused(nonce uint64) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	// The node will be queried on the next reserve anyway
+	if !n.synced {
+		return false
+	}
+
+	if nonce+1 != n.next {
+		return true
+	}
+	n.next--
+	return false
+}
+
+// result records the outcome of submitting a transaction with nonce. Returns true if the transaction may
+// have reached the node but the nonce may not have been used, in which case the gap must be filled for
+// later transactions to be mined.
+func (n *nonceManager) result(nonce uint64, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if isNonceDivergence(err) {
+		n.resync()
+		return false
+	}
+
+	// The node rejected the transaction, so the nonce was definitely not used
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return n.unused(nonce)
+	}
+
+	// The transaction was sent but the node did not respond, it may or may not have received it
+	var sendErr *sendError
+	if errors.As(err, &sendErr) {
+		return true
+	}
+
+	// The transaction failed to be built, signed or sent to any endpoint
+	return n.unused(nonce)
+}
+
+// resync causes the next reserve to fetch the nonce from the node
+func (n *nonceManager) resync() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.synced = false
+}
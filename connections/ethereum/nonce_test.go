@@ -0,0 +1,113 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type fakeNonceSource struct {
+	nonce   uint64
+	queries int
+}
+
+func (s *fakeNonceSource) PendingNonceAt(_ context.Context, _ common.Address) (uint64, error) {
+	s.queries++
+	return s.nonce, nil
+}
+
+// rejectedError mimics a JSON-RPC error returned by the node
+type rejectedError struct{}
+
+func (rejectedError) Error() string  { return "insufficient funds for gas * price + value" }
+func (rejectedError) ErrorCode() int { return -32000 }
+
+func reserveNonce(t *testing.T, n *nonceManager, expected uint64) {
+	nonce, err := n.reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != expected {
+		t.Fatalf("expected nonce %d, got %d", expected, nonce)
+	}
+}
+
+func TestNonceManager_Reserve(t *testing.T) {
+	source := &fakeNonceSource{nonce: 5}
+	n := newNonceManager(source, common.Address{})
+
+	for i := uint64(5); i < 10; i++ {
+		reserveNonce(t, n, i)
+		if n.result(i, nil) {
+			t.Fatal("successful send should not leave a gap")
+		}
+	}
+
+	if source.queries != 1 {
+		t.Fatalf("expected node to be queried once, got %d", source.queries)
+	}
+}
+
+func TestNonceManager_Rejected(t *testing.T) {
+	n := newNonceManager(&fakeNonceSource{nonce: 5}, common.Address{})
+
+	reserveNonce(t, n, 5)
+	if n.result(5, rejectedError{}) {
+		t.Fatal("rejected send should not leave a gap")
+	}
+	// The rejected nonce is handed out again
+	reserveNonce(t, n, 5)
+
+	// A rejected nonce followed by a reserved one leaves a gap
+	reserveNonce(t, n, 6)
+	if !n.result(5, rejectedError{}) {
+		t.Fatal("expected gap to be reported")
+	}
+}
+
+func TestNonceManager_Ambiguous(t *testing.T) {
+	n := newNonceManager(&fakeNonceSource{nonce: 5}, common.Address{})
+
+	reserveNonce(t, n, 5)
+	if !n.result(5, &sendError{err: errors.New("connection reset by peer")}) {
+		t.Fatal("expected gap to be reported when the node may have received the transaction")
+	}
+	reserveNonce(t, n, 6)
+}
+
+func TestNonceManager_NotSent(t *testing.T) {
+	n := newNonceManager(&fakeNonceSource{nonce: 5}, common.Address{})
+
+	reserveNonce(t, n, 5)
+	if n.result(5, ErrNoEndpoints) {
+		t.Fatal("unsent transaction should not leave a gap")
+	}
+	reserveNonce(t, n, 5)
+	if n.result(5, errors.New("abi: cannot use string as type uint8")) {
+		t.Fatal("unsent transaction should not leave a gap")
+	}
+	reserveNonce(t, n, 5)
+}
+
+func TestNonceManager_Divergence(t *testing.T) {
+	source := &fakeNonceSource{nonce: 5}
+	n := newNonceManager(source, common.Address{})
+
+	reserveNonce(t, n, 5)
+
+	// Another transaction was sent from the account
+	source.nonce = 8
+	if n.result(5, errors.New("nonce too low")) {
+		t.Fatal("nonce divergence should not leave a gap")
+	}
+	reserveNonce(t, n, 8)
+
+	if source.queries != 2 {
+		t.Fatalf("expected node to be queried twice, got %d", source.queries)
+	}
+}
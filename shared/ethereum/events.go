@@ -18,6 +18,8 @@ const (
 	Deposit       EventSig = "Deposit(uint8,bytes32,uint64)"
 	ProposalEvent EventSig = "ProposalEvent(uint8,uint64,uint8,bytes32,bytes32)"
 	ProposalVote  EventSig = "ProposalVote(uint8,uint64,uint8,bytes32)"
+	RoleGranted   EventSig = "RoleGranted(bytes32,address,address)"
+	RoleRevoked   EventSig = "RoleRevoked(bytes32,address,address)"
 )
 
 type ProposalStatus int
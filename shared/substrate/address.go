@@ -0,0 +1,67 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"golang.org/x/crypto/blake2b"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// ParseAccountId parses a hex encoded public key, or an SS58 address of any network
+func ParseAccountId(s string) (types.AccountID, error) {
+	if strings.HasPrefix(s, "0x") {
+		bz, err := types.HexDecodeString(s)
+		if err != nil || len(bz) != 32 {
+			return types.AccountID{}, fmt.Errorf("invalid public key %s", s)
+		}
+		return types.NewAccountID(bz), nil
+	}
+	return decodeSS58(s)
+}
+
+// decodeSS58 decodes an address made of a one byte network prefix, the public key and a two byte checksum
+func decodeSS58(s string) (types.AccountID, error) {
+	bz, err := decodeBase58(s)
+	if err != nil || len(bz) != 35 {
+		return types.AccountID{}, fmt.Errorf("invalid SS58 address %s", s)
+	}
+
+	h, err := blake2b.New512(nil)
+	if err != nil {
+		return types.AccountID{}, err
+	}
+	h.Write([]byte("SS58PRE"))
+	h.Write(bz[:33])
+	if !bytes.Equal(h.Sum(nil)[:2], bz[33:]) {
+		return types.AccountID{}, fmt.Errorf("invalid checksum of SS58 address %s", s)
+	}
+	return types.NewAccountID(bz[1:33]), nil
+}
+
+func decodeBase58(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		i := strings.IndexRune(base58Alphabet, c)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+
+	// Leading ones encode leading zero bytes
+	var zeros int
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
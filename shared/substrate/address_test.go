@@ -0,0 +1,40 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+func TestParseAccountId(t *testing.T) {
+	alice := types.NewAccountID(types.MustHexDecodeString("0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d"))
+
+	valid := []string{
+		"0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d",
+		"5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY",
+	}
+	for _, s := range valid {
+		acc, err := ParseAccountId(s)
+		if err != nil {
+			t.Fatalf("%s: %s", s, err)
+		}
+		if acc != alice {
+			t.Errorf("%s: expected %x, got %x", s, alice, acc)
+		}
+	}
+
+	invalid := []string{
+		"0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da2",
+		"5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQZ",
+		"5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKut0Y",
+		"",
+	}
+	for _, s := range invalid {
+		if _, err := ParseAccountId(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
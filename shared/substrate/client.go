@@ -4,8 +4,10 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ChainSafe/chainbridge-utils/msg"
 	"github.com/ChainSafe/log15"
@@ -93,8 +95,24 @@ func (c *Client) NewSudoCall(call types.Call) (types.Call, error) {
 	return types.NewCall(c.Meta, string(SudoMethod), call)
 }
 
+// NewMultisigCall creates a call approving the dispatch of call by the multisig account of the signer and
+// otherSignatories. The timepoint of the first approval must be provided by the following ones, the call is dispatched
+// with the approval reaching the threshold.
+func (c *Client) NewMultisigCall(threshold uint16, otherSignatories []types.AccountID, timepoint *Timepoint, call types.Call, maxWeight uint64) (types.Call, error) {
+	encoded, err := types.EncodeToBytes(call)
+	if err != nil {
+		return types.Call{}, err
+	}
+	// The pallet requires the signatories to be sorted
+	others := append([]types.AccountID{}, otherSignatories...)
+	sort.Slice(others, func(i, j int) bool {
+		return bytes.Compare(others[i][:], others[j][:]) < 0
+	})
+	return types.NewCall(c.Meta, string(MultisigAsMultiMethod), types.U16(threshold), others, OptionTimepoint{timepoint}, types.Bytes(encoded), types.NewBool(false), types.U64(maxWeight))
+}
+
 func (c *Client) NewSetRelayerThresholdCall(threshold types.U32) (types.Call, error) {
-	call, err := types.NewCall(c.Meta, string(SetThresholdMethod), threshold)
+	call, err := c.NewSetRelayerThresholdAdminCall(threshold)
 	if err != nil {
 		return types.Call{}, err
 	}
@@ -102,7 +120,7 @@ func (c *Client) NewSetRelayerThresholdCall(threshold types.U32) (types.Call, er
 }
 
 func (c *Client) NewAddRelayerCall(relayer types.AccountID) (types.Call, error) {
-	call, err := types.NewCall(c.Meta, string(AddRelayerMethod), relayer)
+	call, err := c.NewAddRelayerAdminCall(relayer)
 	if err != nil {
 		return types.Call{}, err
 	}
@@ -110,7 +128,7 @@ func (c *Client) NewAddRelayerCall(relayer types.AccountID) (types.Call, error)
 }
 
 func (c *Client) NewWhitelistChainCall(id msg.ChainId) (types.Call, error) {
-	call, err := types.NewCall(c.Meta, string(WhitelistChainMethod), id)
+	call, err := c.NewWhitelistChainAdminCall(id)
 	if err != nil {
 		return types.Call{}, err
 	}
@@ -118,13 +136,36 @@ func (c *Client) NewWhitelistChainCall(id msg.ChainId) (types.Call, error) {
 }
 
 func (c *Client) NewRegisterResourceCall(id msg.ResourceId, method string) (types.Call, error) {
-	call, err := types.NewCall(c.Meta, string(SetResourceMethod), id, method)
+	call, err := c.NewRegisterResourceAdminCall(id, method)
 	if err != nil {
 		return types.Call{}, err
 	}
 	return c.NewSudoCall(call)
 }
 
+// Admin calls of the bridge pallet, they must be dispatched by its admin origin (eg. with sudo or a multisig)
+
+func (c *Client) NewSetRelayerThresholdAdminCall(threshold types.U32) (types.Call, error) {
+	return types.NewCall(c.Meta, string(SetThresholdMethod), threshold)
+}
+
+func (c *Client) NewAddRelayerAdminCall(relayer types.AccountID) (types.Call, error) {
+	return types.NewCall(c.Meta, string(AddRelayerMethod), relayer)
+}
+
+func (c *Client) NewWhitelistChainAdminCall(id msg.ChainId) (types.Call, error) {
+	return types.NewCall(c.Meta, string(WhitelistChainMethod), types.U8(id))
+}
+
+func (c *Client) NewRegisterResourceAdminCall(id msg.ResourceId, method string) (types.Call, error) {
+	return types.NewCall(c.Meta, string(SetResourceMethod), types.NewBytes32(id), []byte(method))
+}
+
+// NewBatchCall creates a call dispatching calls in order, with the origin of the batch
+func (c *Client) NewBatchCall(calls []types.Call) (types.Call, error) {
+	return types.NewCall(c.Meta, string(UtilityBatchMethod), calls)
+}
+
 func (c *Client) NewNativeTransferCall(amount types.U128, recipient []byte, destId msg.ChainId) (types.Call, error) {
 	return types.NewCall(c.Meta, string(ExampleTransferNativeMethod), amount, recipient, types.U8(destId))
 }
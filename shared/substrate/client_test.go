@@ -0,0 +1,61 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+func TestNewMultisigCall(t *testing.T) {
+	client := &Client{Meta: types.ExamplaryMetadataV11Substrate}
+	call, err := types.NewCall(client.Meta, "System.remark", []byte{0xab})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := types.EncodeToBytes(call)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := types.NewAccountID(bytes.Repeat([]byte{1}, 32))
+	b := types.NewAccountID(bytes.Repeat([]byte{2}, 32))
+	index, err := client.Meta.FindCallIndex(string(MultisigAsMultiMethod))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		timepoint *Timepoint
+		option    []byte
+	}{
+		{nil, []byte{0}},
+		{&Timepoint{Height: 5, Index: 1}, []byte{1, 5, 0, 0, 0, 1, 0, 0, 0}},
+	}
+	for _, tc := range testCases {
+		// Signatories are sorted in the call
+		multisig, err := client.NewMultisigCall(2, []types.AccountID{b, a}, tc.timepoint, call, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var expected []byte
+		expected = append(expected, 2, 0, 8)
+		expected = append(expected, a[:]...)
+		expected = append(expected, b[:]...)
+		expected = append(expected, tc.option...)
+		expected = append(expected, byte(len(encoded)<<2))
+		expected = append(expected, encoded...)
+		expected = append(expected, 0, 0xe8, 3, 0, 0, 0, 0, 0, 0)
+
+		if multisig.CallIndex != index {
+			t.Errorf("expected call index %v, got %v", index, multisig.CallIndex)
+		}
+		if !bytes.Equal(multisig.Args, expected) {
+			t.Errorf("expected args %x, got %x", expected, []byte(multisig.Args))
+		}
+	}
+}
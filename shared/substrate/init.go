@@ -4,45 +4,73 @@
 package utils
 
 import (
+	"bytes"
+	"sort"
+
 	"github.com/ChainSafe/chainbridge-utils/msg"
 	"github.com/centrifuge/go-substrate-rpc-client/types"
 )
 
 func InitializeChain(client *Client, relayers []types.AccountID, chains []msg.ChainId, resources map[msg.ResourceId]Method, threshold uint32) error {
+	calls, err := NewInitializeChainCalls(client, relayers, chains, resources, threshold)
+	if err != nil {
+		return err
+	}
+
+	for i, call := range calls {
+		calls[i], err = client.NewSudoCall(call)
+		if err != nil {
+			return err
+		}
+	}
+
+	return BatchSubmit(client, calls)
+}
+
+// NewInitializeChainCalls creates the admin calls adding the relayers, whitelisting the chains, registering the
+// resources and setting the threshold. Resources are registered in the order of their ID.
+func NewInitializeChainCalls(client *Client, relayers []types.AccountID, chains []msg.ChainId, resources map[msg.ResourceId]Method, threshold uint32) ([]types.Call, error) {
 	calls := []types.Call{}
 
 	// Create AddRelayer calls
 	for _, relayer := range relayers {
-		call, err := client.NewAddRelayerCall(relayer)
+		call, err := client.NewAddRelayerAdminCall(relayer)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		calls = append(calls, call)
 	}
 	// Create WhitelistChain calls
 	for _, chain := range chains {
-		call, err := client.NewWhitelistChainCall(chain)
+		call, err := client.NewWhitelistChainAdminCall(chain)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		calls = append(calls, call)
 	}
 
 	// Create SetResource calls
-	for id, method := range resources {
-		call, err := client.NewRegisterResourceCall(id, string(method))
+	ids := make([]msg.ResourceId, 0, len(resources))
+	for id := range resources {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+	for _, id := range ids {
+		call, err := client.NewRegisterResourceAdminCall(id, string(resources[id]))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		calls = append(calls, call)
 	}
 
 	// Create a SetThreshold call
-	call, err := client.NewSetRelayerThresholdCall(types.U32(threshold))
+	call, err := client.NewSetRelayerThresholdAdminCall(types.U32(threshold))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	calls = append(calls, call)
 
-	return BatchSubmit(client, calls)
+	return calls, nil
 }
@@ -18,3 +18,5 @@ var ExampleTransferMethod Method = "Example.transfer"
 var ExampleRemarkMethod Method = "Example.remark"
 var Erc721MintMethod Method = "Erc721.mint"
 var SudoMethod Method = "Sudo.sudo"
+var MultisigAsMultiMethod Method = "Multisig.as_multi"
+var UtilityBatchMethod Method = "Utility.batch"
@@ -22,18 +22,30 @@ func SubmitTx(client *Client, method Method, args ...interface{}) error {
 	if err != nil {
 		return err
 	}
+
+	block, err := SubmitCall(client, call)
+	if err != nil {
+		return err
+	}
+	log15.Info("Extrinsic in block", "block", block.Hex())
+	return nil
+}
+
+// SubmitCall signs an extrinsic of the call with the client key, submits it and waits until it is included in a
+// block. Returns the hash of the block, and an error if the events of the block show that the call failed.
+func SubmitCall(client *Client, call types.Call) (types.Hash, error) {
 	ext := types.NewExtrinsic(call)
 
 	// Get latest runtime version
 	rv, err := client.Api.RPC.State.GetRuntimeVersionLatest()
 	if err != nil {
-		return err
+		return types.Hash{}, err
 	}
 
 	var acct types.AccountInfo
 	_, err = QueryStorage(client, "System", "Account", client.Key.PublicKey, nil, &acct)
 	if err != nil {
-		return err
+		return types.Hash{}, err
 	}
 
 	// Sign the extrinsic
@@ -48,27 +60,106 @@ func SubmitTx(client *Client, method Method, args ...interface{}) error {
 	}
 	err = ext.Sign(*client.Key, o)
 	if err != nil {
-		return err
+		return types.Hash{}, err
 	}
 
 	// Submit and watch the extrinsic
 	sub, err := client.Api.RPC.Author.SubmitAndWatchExtrinsic(ext)
 	if err != nil {
-		return err
+		return types.Hash{}, err
 	}
+	defer sub.Unsubscribe()
 
 	for {
 		status := <-sub.Chan()
 		switch {
 		case status.IsInBlock:
-			log15.Info("Extrinsic in block", "block", status.AsInBlock.Hex())
-			return nil
+			return status.AsInBlock, checkExtrinsic(client, status.AsInBlock, ext)
 		case status.IsDropped:
-			return fmt.Errorf("extrinsic dropped")
+			return types.Hash{}, fmt.Errorf("extrinsic dropped")
 		case status.IsInvalid:
-			return fmt.Errorf("extrinsic invalid")
+			return types.Hash{}, fmt.Errorf("extrinsic invalid")
+		}
+	}
+}
+
+// checkExtrinsic returns an error if the extrinsic included in the block failed, or a call it dispatched failed
+func checkExtrinsic(client *Client, block types.Hash, ext types.Extrinsic) error {
+	signed, err := client.Api.RPC.Chain.GetBlock(block)
+	if err != nil {
+		return err
+	}
+	encoded, err := types.EncodeToHexString(ext)
+	if err != nil {
+		return err
+	}
+	index := -1
+	for i, e := range signed.Block.Extrinsics {
+		enc, err := types.EncodeToHexString(e)
+		if err != nil {
+			return err
 		}
+		if enc == encoded {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("extrinsic not found in block %s", block.Hex())
+	}
+
+	key, err := types.CreateStorageKey(client.Meta, "System", "Events", nil, nil)
+	if err != nil {
+		return err
+	}
+	var records types.EventRecordsRaw
+	_, err = client.Api.RPC.State.GetStorage(key, &records, block)
+	if err != nil {
+		return err
+	}
+	e := Events{}
+	err = records.DecodeEventRecords(client.Meta, &e)
+	if err != nil {
+		return err
+	}
+	return extrinsicError(&e, uint32(index))
+}
+
+// extrinsicError returns an error if the events show that the extrinsic at index failed, or that a sudo, batch or
+// multisig call it dispatched failed
+func extrinsicError(e *Events, index uint32) error {
+	applied := func(phase types.Phase) bool {
+		return phase.IsApplyExtrinsic && phase.AsApplyExtrinsic == index
+	}
+
+	for _, evt := range e.System_ExtrinsicFailed {
+		if applied(evt.Phase) {
+			return fmt.Errorf("extrinsic failed: %s", dispatchErrorString(evt.DispatchError))
+		}
+	}
+	for _, evt := range e.Sudo_Sudid {
+		if applied(evt.Phase) && !evt.Result.Ok {
+			return fmt.Errorf("sudo call failed: %s", dispatchErrorString(evt.Result.Error))
+		}
+	}
+	for _, evt := range e.Utility_BatchInterrupted {
+		if applied(evt.Phase) {
+			return fmt.Errorf("batch interrupted at call %d: %s", evt.Index, dispatchErrorString(evt.DispatchError))
+		}
+	}
+	for _, evt := range e.Multisig_Executed {
+		if applied(evt.Phase) && !evt.Result.Ok {
+			return fmt.Errorf("multisig call failed: %s", dispatchErrorString(evt.Result.Error))
+		}
+	}
+	return nil
+}
+
+func dispatchErrorString(err types.DispatchError) string {
+	if err.HasModule {
+		return fmt.Sprintf("module %d error %d", err.Module, err.Error)
 	}
+	return fmt.Sprintf("dispatch error %d", err.Error)
 }
 
 func SubmitSudoTx(client *Client, method Method, args ...interface{}) error {
@@ -0,0 +1,41 @@
+// Copyright 2020 ChainSafe Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+func TestExtrinsicError(t *testing.T) {
+	phase := func(index uint32) types.Phase {
+		return types.Phase{IsApplyExtrinsic: true, AsApplyExtrinsic: index}
+	}
+	failed := types.DispatchResult{Ok: false, Error: types.DispatchError{HasModule: true, Module: 3, Error: 1}}
+
+	testCases := []struct {
+		name   string
+		events Events
+		fails  bool
+	}{
+		{"no events", Events{}, false},
+		{"extrinsic failed", Events{EventRecords: types.EventRecords{System_ExtrinsicFailed: []types.EventSystemExtrinsicFailed{{Phase: phase(1)}}}}, true},
+		{"other extrinsic failed", Events{EventRecords: types.EventRecords{System_ExtrinsicFailed: []types.EventSystemExtrinsicFailed{{Phase: phase(2)}}}}, false},
+		{"sudo call failed", Events{EventRecords: types.EventRecords{Sudo_Sudid: []types.EventSudoSudid{{Phase: phase(1), Result: failed}}}}, true},
+		{"sudo call succeeded", Events{EventRecords: types.EventRecords{Sudo_Sudid: []types.EventSudoSudid{{Phase: phase(1), Result: types.DispatchResult{Ok: true}}}}}, false},
+		{"batch interrupted", Events{EventRecords: types.EventRecords{Utility_BatchInterrupted: []types.EventUtilityBatchInterrupted{{Phase: phase(1), Index: 2}}}}, true},
+		{"multisig call failed", Events{EventRecords: types.EventRecords{Multisig_Executed: []types.EventMultisigExecuted{{Phase: phase(1), Result: failed}}}}, true},
+		{"multisig call succeeded", Events{EventRecords: types.EventRecords{Multisig_Executed: []types.EventMultisigExecuted{{Phase: phase(1), Result: types.DispatchResult{Ok: true}}}}}, false},
+	}
+
+	for _, tc := range testCases {
+		err := extrinsicError(&tc.events, 1)
+		if tc.fails && err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		} else if !tc.fails && err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+		}
+	}
+}
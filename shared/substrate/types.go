@@ -4,6 +4,7 @@
 package utils
 
 import (
+	"github.com/centrifuge/go-substrate-rpc-client/scale"
 	"github.com/centrifuge/go-substrate-rpc-client/types"
 )
 
@@ -22,3 +23,21 @@ type AssetId struct {
 	RegistryId RegistryId
 	TokenId    TokenId
 }
+
+// Timepoint is the block height and extrinsic index of the first approval of a multisig operation
+type Timepoint struct {
+	Height types.U32
+	Index  types.U32
+}
+
+// OptionTimepoint is an optional timepoint, it is not set if Timepoint is nil
+type OptionTimepoint struct {
+	Timepoint *Timepoint
+}
+
+func (o OptionTimepoint) Encode(encoder scale.Encoder) error {
+	if o.Timepoint == nil {
+		return encoder.EncodeOption(false, nil)
+	}
+	return encoder.EncodeOption(true, *o.Timepoint)
+}